@@ -0,0 +1,242 @@
+package aitools
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// 熔断器状态
+const (
+	CircuitClosed   = "closed"    // 正常调用
+	CircuitOpen     = "open"      // 连续失败次数过多，直接拒绝调用
+	CircuitHalfOpen = "half_open" // 冷却结束，放行一个试探请求
+)
+
+// ErrCircuitOpen 模型服务处于熔断状态
+var ErrCircuitOpen = errors.New("模型服务暂时不可用，请稍后重试")
+
+// CircuitBreakers 按提供方名称管理的模型服务熔断器
+// 连续失败达到阈值后熔断，冷却期内调用直接返回ErrCircuitOpen，避免任务和请求堆积在不可用的服务上；
+// 冷却结束后放行一个试探请求，成功则恢复，失败则重新熔断。同一提供方的不同模型共用一个熔断器
+type CircuitBreakers struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// NewCircuitBreakers 创建熔断器，threshold为触发熔断的连续失败次数，cooldown为熔断后的冷却时间
+func NewCircuitBreakers(threshold int, cooldown time.Duration) *CircuitBreakers {
+	if threshold <= 0 {
+		threshold = 5
+	}
+	if cooldown <= 0 {
+		cooldown = 30 * time.Second
+	}
+	return &CircuitBreakers{threshold: threshold, cooldown: cooldown, circuits: make(map[string]*circuit)}
+}
+
+// Wrap 为提供方加上熔断保护，返回的提供方保留流式对话、对比分析和上下文长度等可选能力
+func (b *CircuitBreakers) Wrap(provider LLMProvider) LLMProvider {
+	return &circuitProvider{LLMProvider: provider, circuit: b.circuit(provider.Name())}
+}
+
+// States 返回各提供方熔断器的当前状态，只包含已使用过的提供方
+func (b *CircuitBreakers) States() map[string]string {
+	b.mu.Lock()
+	names := make([]string, 0, len(b.circuits))
+	for name := range b.circuits {
+		names = append(names, name)
+	}
+	b.mu.Unlock()
+	sort.Strings(names)
+	states := make(map[string]string, len(names))
+	for _, name := range names {
+		states[name] = b.circuit(name).state()
+	}
+	return states
+}
+
+// circuit 返回提供方的熔断器，不存在时创建
+func (b *CircuitBreakers) circuit(name string) *circuit {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.circuits[name]
+	if !ok {
+		c = &circuit{threshold: b.threshold, cooldown: b.cooldown, status: CircuitClosed}
+		b.circuits[name] = c
+	}
+	return c
+}
+
+// circuit 单个提供方的熔断器
+type circuit struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	status   string
+	failures int       // 连续失败次数
+	openedAt time.Time // 最近一次熔断的时间
+	probing  bool      // 半开状态下是否已有试探请求在执行
+}
+
+// state 当前状态，熔断冷却结束后视为半开
+func (c *circuit) state() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.status == CircuitOpen && time.Since(c.openedAt) >= c.cooldown {
+		return CircuitHalfOpen
+	}
+	return c.status
+}
+
+// allow 判断是否放行本次调用
+func (c *circuit) allow() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch c.status {
+	case CircuitOpen:
+		if time.Since(c.openedAt) < c.cooldown {
+			return ErrCircuitOpen
+		}
+		c.status = CircuitHalfOpen
+		c.probing = true
+		return nil
+	case CircuitHalfOpen:
+		if c.probing {
+			return ErrCircuitOpen
+		}
+		c.probing = true
+		return nil
+	}
+	return nil
+}
+
+// record 记录调用结果；调用方取消或超时导致的失败不计入
+// 只有服务不可用类的错误计入连续失败，请求被拒绝（4xx）或模型输出不合法时服务仍有响应，按成功处理
+func (c *circuit) record(ctx context.Context, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case ctx.Err() != nil:
+		c.probing = false
+	case err == nil || !unavailable(err):
+		c.status = CircuitClosed
+		c.failures = 0
+		c.probing = false
+	default:
+		c.failures++
+		if c.status == CircuitHalfOpen || c.failures >= c.threshold {
+			c.status = CircuitOpen
+			c.openedAt = time.Now()
+			c.probing = false
+		}
+	}
+}
+
+// unavailable 判断错误是否表明模型服务不可用：网络错误、5xx和429
+func unavailable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return unavailableStatus(apiErr.StatusCode)
+	}
+	var genaiErr genai.APIError
+	if errors.As(err, &genaiErr) {
+		return unavailableStatus(genaiErr.Code)
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// unavailableStatus 服务端错误和限流视为服务不可用
+func unavailableStatus(code int) bool {
+	return code >= http.StatusInternalServerError || code == http.StatusTooManyRequests
+}
+
+// call 在熔断保护下执行一次调用
+func (c *circuit) call(ctx context.Context, fn func() error) error {
+	if err := c.allow(); err != nil {
+		return err
+	}
+	err := fn()
+	c.record(ctx, err)
+	return err
+}
+
+// circuitProvider 带熔断保护的模型服务提供方
+type circuitProvider struct {
+	LLMProvider
+	circuit *circuit
+}
+
+// AnalyzePaper 分析论文
+func (p *circuitProvider) AnalyzePaper(ctx context.Context, input *PaperInput) (*PaperAnalysis, Usage, error) {
+	var analysis *PaperAnalysis
+	var usage Usage
+	err := p.circuit.call(ctx, func() (err error) {
+		analysis, usage, err = p.LLMProvider.AnalyzePaper(ctx, input)
+		return err
+	})
+	return analysis, usage, err
+}
+
+// Chat 多轮对话
+func (p *circuitProvider) Chat(ctx context.Context, messages []ChatMessage) (string, Usage, error) {
+	var reply string
+	var usage Usage
+	err := p.circuit.call(ctx, func() (err error) {
+		reply, usage, err = p.LLMProvider.Chat(ctx, messages)
+		return err
+	})
+	return reply, usage, err
+}
+
+// Embed 计算文本向量
+func (p *circuitProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var vectors [][]float32
+	err := p.circuit.call(ctx, func() (err error) {
+		vectors, err = p.LLMProvider.Embed(ctx, texts)
+		return err
+	})
+	return vectors, err
+}
+
+// ChatStream 流式对话，被包装的提供方不支持流式输出时整段输出
+func (p *circuitProvider) ChatStream(ctx context.Context, messages []ChatMessage, onDelta ChatDeltaFunc) (string, Usage, error) {
+	var reply string
+	var usage Usage
+	err := p.circuit.call(ctx, func() (err error) {
+		reply, usage, err = StreamChat(ctx, p.LLMProvider, messages, onDelta)
+		return err
+	})
+	return reply, usage, err
+}
+
+// ComparePapers 对比分析多篇论文
+func (p *circuitProvider) ComparePapers(ctx context.Context, inputs []*PaperInput, opts CompareOptions) (*PaperComparison, Usage, error) {
+	var comparison *PaperComparison
+	var usage Usage
+	err := p.circuit.call(ctx, func() (err error) {
+		comparison, usage, err = ComparePapers(ctx, p.LLMProvider, inputs, opts)
+		return err
+	})
+	return comparison, usage, err
+}
+
+// ContextTokens 被包装提供方的上下文长度，未声明时返回0，按DefaultContextTokens估算
+func (p *circuitProvider) ContextTokens() int {
+	if w, ok := p.LLMProvider.(ContextWindowProvider); ok {
+		return w.ContextTokens()
+	}
+	return 0
+}
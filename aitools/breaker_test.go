@@ -0,0 +1,60 @@
+package aitools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+func TestUnavailable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "连接失败", err: &url.Error{Op: "Post", URL: "http://x", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}, want: true},
+		{name: "响应被截断", err: fmt.Errorf("读取响应失败: %w", io.ErrUnexpectedEOF), want: true},
+		{name: "千问5xx", err: fmt.Errorf("分析失败: %w", &APIError{StatusCode: 503}), want: true},
+		{name: "千问429", err: &APIError{StatusCode: 429}, want: true},
+		{name: "千问400", err: &APIError{StatusCode: 400}, want: false},
+		{name: "千问401", err: &APIError{StatusCode: 401}, want: false},
+		{name: "Gemini 500", err: fmt.Errorf("生成内容失败: %w", genai.APIError{Code: 500}), want: true},
+		{name: "Gemini 400", err: genai.APIError{Code: 400}, want: false},
+		{name: "输出不合法", err: fmt.Errorf("模型输出3次均不合法: %w", &ValidationError{Problems: []string{"x"}}), want: false},
+		{name: "其他错误", err: errors.New("论文内容为空"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unavailable(tt.err); got != tt.want {
+				t.Errorf("unavailable(%v)=%v，期望%v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCircuitCountsOnlyUnavailable(t *testing.T) {
+	ctx := context.Background()
+	c := &circuit{threshold: 2, cooldown: time.Hour, status: CircuitClosed}
+
+	c.record(ctx, &APIError{StatusCode: 503})
+	c.record(ctx, &APIError{StatusCode: 400})
+	c.record(ctx, &APIError{StatusCode: 503})
+	if c.state() != CircuitClosed {
+		t.Fatalf("4xx应中断连续失败计数，实际状态%s", c.state())
+	}
+
+	c.record(ctx, &APIError{StatusCode: 429})
+	if c.state() != CircuitOpen {
+		t.Fatalf("连续两次不可用应熔断，实际状态%s", c.state())
+	}
+	if err := c.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("熔断期间应拒绝调用，实际为%v", err)
+	}
+}
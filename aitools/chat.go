@@ -0,0 +1,199 @@
+package aitools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// 论文问答
+// 以论文逐页文本和结构化分析报告为依据回答问题，回答中用[p.N]标注出处页码
+
+// ChatDeltaFunc 流式输出回调，每收到一段增量文本调用一次，返回错误时中止输出
+type ChatDeltaFunc func(delta string) error
+
+// ChatStreamer 支持流式输出的模型服务提供方
+type ChatStreamer interface {
+	ChatStream(ctx context.Context, messages []ChatMessage, onDelta ChatDeltaFunc) (string, Usage, error)
+}
+
+// StreamChat 流式对话，提供方不支持流式输出时整段回复作为一次增量输出
+// 返回完整回复内容和token用量
+func StreamChat(ctx context.Context, provider LLMProvider, messages []ChatMessage, onDelta ChatDeltaFunc) (string, Usage, error) {
+	if streamer, ok := provider.(ChatStreamer); ok {
+		return streamer.ChatStream(ctx, messages, onDelta)
+	}
+	reply, usage, err := provider.Chat(ctx, messages)
+	if err != nil {
+		return "", usage, err
+	}
+	if onDelta != nil && reply != "" {
+		if err := onDelta(reply); err != nil {
+			return reply, usage, err
+		}
+	}
+	return reply, usage, nil
+}
+
+// 问答默认参数
+const (
+	DefaultChatAnswerTokens = 2048 // 为回答预留的token数
+)
+
+// paperChatPrompt 论文问答的系统指令
+const paperChatPrompt = `你是论文问答助手。只根据下面提供的论文内容和分析报告回答用户的问题，不要编造论文中没有的信息；论文中找不到答案时请直接说明。
+引用论文原文作为依据时，在相应句子末尾用[p.页码]标注出处，例如[p.3]；涉及多页时写成[p.3][p.5]。分析报告不是原文，不要为其标注页码。
+使用与用户提问相同的语言回答。`
+
+// PaperChatSource 问答依据的论文内容
+type PaperChatSource struct {
+	Title    string
+	Abstract string
+	Pages    []string       // 逐页文本，页码从1开始
+	Analysis *PaperAnalysis // 可选，结构化分析报告
+}
+
+// BuildPaperChatMessages 构造论文问答的消息列表
+// history为之前的对话（按时间顺序），contextTokens为模型上下文窗口；
+// 论文全文放不下时按与问题的相关度挑选页面，挑出的页面仍按页码顺序排列
+func BuildPaperChatMessages(src *PaperChatSource, history []ChatMessage, question string, contextTokens int) []ChatMessage {
+	if contextTokens <= 0 {
+		contextTokens = DefaultContextTokens
+	}
+
+	var b strings.Builder
+	b.WriteString(paperChatPrompt)
+	if src.Title != "" {
+		fmt.Fprintf(&b, "\n\n论文标题：%s", src.Title)
+	}
+	if src.Abstract != "" {
+		fmt.Fprintf(&b, "\n\n摘要：%s", src.Abstract)
+	}
+	if src.Analysis != nil {
+		if data, err := json.Marshal(src.Analysis); err == nil {
+			fmt.Fprintf(&b, "\n\n分析报告（JSON）：%s", data)
+		}
+	}
+
+	used := EstimateTokens(b.String()) + EstimateTokens(question) + DefaultChatAnswerTokens
+	for _, msg := range history {
+		used += EstimateTokens(msg.Content)
+	}
+	if pages := selectPages(src.Pages, question, contextTokens-used); len(pages) > 0 {
+		b.WriteString("\n\n论文内容（按页）：")
+		for _, p := range pages {
+			fmt.Fprintf(&b, "\n\n[p.%d]\n%s", p, strings.TrimSpace(src.Pages[p-1]))
+		}
+	}
+
+	messages := make([]ChatMessage, 0, len(history)+2)
+	messages = append(messages, ChatMessage{Role: RoleSystem, Content: b.String()})
+	messages = append(messages, history...)
+	messages = append(messages, ChatMessage{Role: RoleUser, Content: question})
+	return messages
+}
+
+// selectPages 在token预算内挑选页面，返回按页码排序的页码（从1开始）
+// 全部放得下时返回所有非空页面，否则优先保留与问题词语重合度高的页面
+func selectPages(pages []string, question string, budget int) []int {
+	type candidate struct {
+		page   int
+		tokens int
+		score  int
+	}
+	var candidates []candidate
+	total := 0
+	terms := chatTerms(question)
+	for i, text := range pages {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		c := candidate{page: i + 1, tokens: EstimateTokens(text) + 8}
+		for t := range chatTerms(text) {
+			if _, ok := terms[t]; ok {
+				c.score++
+			}
+		}
+		total += c.tokens
+		candidates = append(candidates, c)
+	}
+	if budget <= 0 {
+		return nil
+	}
+	if total > budget {
+		// 相关度高的优先，相关度相同时靠前的页面优先（通常包含摘要和引言）
+		sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+	}
+
+	var selected []int
+	for _, c := range candidates {
+		if c.tokens > budget {
+			continue
+		}
+		budget -= c.tokens
+		selected = append(selected, c.page)
+	}
+	sort.Ints(selected)
+	return selected
+}
+
+// chatTerms 提取用于相关度计算的词：拉丁字母和数字按单词切分，中文按相邻两字切分
+func chatTerms(text string) map[string]struct{} {
+	terms := make(map[string]struct{})
+	var word []rune
+	var prevHan rune
+	flush := func() {
+		if len(word) >= 3 {
+			terms[string(word)] = struct{}{}
+		}
+		word = word[:0]
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flush()
+			if prevHan != 0 {
+				terms[string([]rune{prevHan, r})] = struct{}{}
+			}
+			prevHan = r
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word = append(word, r)
+		default:
+			flush()
+		}
+		prevHan = 0
+	}
+	flush()
+	return terms
+}
+
+// citationGroupPattern 匹配方括号内的引用，如[p.3]、[p.3, p.5]、[p3]
+var citationGroupPattern = regexp.MustCompile(`\[[^\[\]]{1,64}\]`)
+
+// citationPagePattern 匹配引用中的页码
+var citationPagePattern = regexp.MustCompile(`(?i)\bp\.?\s*(\d+)`)
+
+// ParseCitations 解析回答中引用的页码，去重后按页码排序
+// pageCount大于0时忽略超出页数范围的页码
+func ParseCitations(answer string, pageCount int) []int {
+	seen := make(map[int]bool)
+	var pages []int
+	for _, group := range citationGroupPattern.FindAllString(answer, -1) {
+		for _, m := range citationPagePattern.FindAllStringSubmatch(group, -1) {
+			page, err := strconv.Atoi(m[1])
+			if err != nil || page < 1 || (pageCount > 0 && page > pageCount) || seen[page] {
+				continue
+			}
+			seen[page] = true
+			pages = append(pages, page)
+		}
+	}
+	sort.Ints(pages)
+	return pages
+}
@@ -0,0 +1,219 @@
+package aitools
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// 多篇论文对比分析
+// 针对同一研究问题的2-5篇论文，输出方法、数据集、结果以及各对比维度上的优劣
+
+// 对比论文数量范围
+const (
+	MinComparePapers = 2
+	MaxComparePapers = 5
+)
+
+// ComparisonPromptVersion 对比分析prompt版本，prompt或输出结构变化时需要递增
+const ComparisonPromptVersion = "v1"
+
+// PaperComparison 多篇论文对比分析结果
+type PaperComparison struct {
+	Topic          string                `json:"topic"`          // 论文共同关注的研究问题
+	Papers         []ComparedPaper       `json:"papers"`         // 每篇论文的要点，按输入顺序
+	Dimensions     []ComparisonDimension `json:"dimensions"`     // 各对比维度上的评价
+	Summary        string                `json:"summary"`        // 总体对比结论
+	Recommendation string                `json:"recommendation"` // 针对不同需求的阅读或选用建议
+}
+
+// ComparedPaper 单篇论文在对比中的要点
+type ComparedPaper struct {
+	Index      int    `json:"index"` // 论文序号，从1开始，与输入顺序一致
+	Title      string `json:"title"`
+	Methods    string `json:"methods"`
+	Datasets   string `json:"datasets"`
+	Results    string `json:"results"`
+	Strengths  string `json:"strengths"`
+	Weaknesses string `json:"weaknesses"`
+}
+
+// ComparisonDimension 一个对比维度，如方法创新性、实验充分性、结果表现
+type ComparisonDimension struct {
+	Name    string            `json:"name"`
+	Entries []ComparisonEntry `json:"entries"` // 每篇论文在该维度上的评价
+	Best    int               `json:"best"`    // 该维度表现最好的论文序号
+}
+
+// ComparisonEntry 单篇论文在某个维度上的评价
+type ComparisonEntry struct {
+	Index      int    `json:"index"`
+	Assessment string `json:"assessment"`
+	Rating     int    `json:"rating"`
+}
+
+// CompareOptions 对比分析参数
+type CompareOptions struct {
+	OnAnalyzing func() // 可选回调，需要上传的论文全部上传完成、开始调用模型对比时调用
+}
+
+// analyzing 调用OnAnalyzing回调
+func (o CompareOptions) analyzing() {
+	if o.OnAnalyzing != nil {
+		o.OnAnalyzing()
+	}
+}
+
+// PaperComparer 支持多篇论文对比分析的模型服务提供方
+// 不支持的提供方通过Chat按论文文本对比
+type PaperComparer interface {
+	ComparePapers(ctx context.Context, inputs []*PaperInput, opts CompareOptions) (*PaperComparison, Usage, error)
+}
+
+// ComparePapers 对比分析多篇论文
+// 提供方实现了PaperComparer时直接使用，否则将各篇论文文本放入同一个prompt通过Chat对比
+func ComparePapers(ctx context.Context, provider LLMProvider, inputs []*PaperInput, opts CompareOptions) (*PaperComparison, Usage, error) {
+	if len(inputs) < MinComparePapers || len(inputs) > MaxComparePapers {
+		return nil, Usage{}, fmt.Errorf("对比论文数量需在%d-%d篇之间", MinComparePapers, MaxComparePapers)
+	}
+	if comparer, ok := provider.(PaperComparer); ok {
+		return comparer.ComparePapers(ctx, inputs, opts)
+	}
+	return compareByChat(ctx, provider, inputs, 0, opts)
+}
+
+// compareByChat 将各篇论文文本按上下文窗口平均分配后通过Chat对比
+func compareByChat(ctx context.Context, provider LLMProvider, inputs []*PaperInput, maxAttempts int, opts CompareOptions) (*PaperComparison, Usage, error) {
+	window := DefaultContextTokens
+	if p, ok := provider.(ContextWindowProvider); ok && p.ContextTokens() > 0 {
+		window = p.ContextTokens()
+	}
+	// 预留指令和输出的空间，其余平均分配给各篇论文
+	perPaper := max((window-EstimateTokens(comparisonJsonSchema)-8000)/len(inputs), 1000)
+
+	var b strings.Builder
+	for i, input := range inputs {
+		if strings.TrimSpace(input.Text) == "" {
+			return nil, Usage{}, fmt.Errorf("论文%d缺少文本内容", i+1)
+		}
+		fmt.Fprintf(&b, "===== 论文%d：%s =====\n%s\n\n", i+1, input.FileName, truncateTokens(input.Text, perPaper))
+	}
+	messages := []ChatMessage{
+		{Role: RoleSystem, Content: comparisonPrompt(len(inputs))},
+		{Role: RoleUser, Content: b.String()},
+	}
+	opts.analyzing()
+	return generateStructured(ctx, maxAttempts, func(ctx context.Context, feedback string) (string, Usage, error) {
+		if feedback != "" {
+			return provider.Chat(ctx, append(messages, ChatMessage{Role: RoleUser, Content: feedback}))
+		}
+		return provider.Chat(ctx, messages)
+	}, func(raw string) (*PaperComparison, error) {
+		return ParsePaperComparison(raw, len(inputs))
+	})
+}
+
+// comparisonPrompt 对比分析指令
+func comparisonPrompt(n int) string {
+	return fmt.Sprintf(`请对给出的%d篇论文进行对比分析。论文按顺序编号为1到%d，输出中的index和best都使用该编号。
+papers中每篇论文各一项，依次说明其方法、使用的数据集（没有时写"无"）、主要结果、优点和不足；
+dimensions给出3-6个对比维度（如方法创新性、实验充分性、结果表现、适用场景），每个维度对每篇论文给出评价和1-5分的评分，并指出该维度表现最好的论文。
+只输出如下结构的JSON对象，不要包含markdown代码块或其他文字：
+%s`, n, n, comparisonJsonSchema)
+}
+
+// comparisonJsonSchema 用于prompt，指导模型输出对比结果JSON
+const comparisonJsonSchema = `{
+  "topic": "",
+  "papers": [
+    {"index": 1, "title": "", "methods": "", "datasets": "", "results": "", "strengths": "", "weaknesses": ""}
+  ],
+  "dimensions": [
+    {"name": "", "entries": [{"index": 1, "assessment": "", "rating": 3}], "best": 1}
+  ],
+  "summary": "",
+  "recommendation": ""
+}`
+
+// comparisonSchema 由PaperComparison结构生成的Gemini响应结构定义
+var comparisonSchema = schemaFor(reflect.TypeOf(PaperComparison{}))
+
+// ParsePaperComparison 解析并校验对比分析输出，n为论文数量
+func ParsePaperComparison(raw string, n int) (*PaperComparison, error) {
+	var result PaperComparison
+	if err := decodeRepaired(raw, &result); err != nil {
+		return nil, err
+	}
+	if err := result.Validate(n); err != nil {
+		return nil, err
+	}
+	// 按论文序号排序
+	papers := make([]ComparedPaper, n)
+	for _, p := range result.Papers {
+		papers[p.Index-1] = p
+	}
+	result.Papers = papers
+	return &result, nil
+}
+
+// Validate 校验对比结果是否完整，n为论文数量，返回的ValidationError包含全部问题
+func (c *PaperComparison) Validate(n int) error {
+	var problems []string
+	if strings.TrimSpace(c.Summary) == "" {
+		problems = append(problems, "summary不能为空")
+	}
+	if len(c.Papers) != n {
+		problems = append(problems, fmt.Sprintf("papers应包含%d篇论文，当前为%d", n, len(c.Papers)))
+	}
+	seen := make(map[int]bool)
+	for i, p := range c.Papers {
+		if p.Index < 1 || p.Index > n || seen[p.Index] {
+			problems = append(problems, fmt.Sprintf("papers[%d].index必须是1-%d之间且不重复的整数，当前为%d", i, n, p.Index))
+			continue
+		}
+		seen[p.Index] = true
+		for _, f := range []struct{ name, value string }{{"title", p.Title}, {"methods", p.Methods}, {"datasets", p.Datasets}, {"results", p.Results}} {
+			if strings.TrimSpace(f.value) == "" {
+				problems = append(problems, fmt.Sprintf("papers[%d].%s不能为空", i, f.name))
+			}
+		}
+	}
+	if len(c.Dimensions) == 0 {
+		problems = append(problems, "dimensions不能为空")
+	}
+	for i, d := range c.Dimensions {
+		if strings.TrimSpace(d.Name) == "" {
+			problems = append(problems, fmt.Sprintf("dimensions[%d].name不能为空", i))
+		}
+		if d.Best < 1 || d.Best > n {
+			problems = append(problems, fmt.Sprintf("dimensions[%d].best必须是1-%d之间的整数，当前为%d", i, n, d.Best))
+		}
+		covered := make(map[int]bool)
+		for j, e := range d.Entries {
+			if e.Index >= 1 && e.Index <= n {
+				covered[e.Index] = true
+			}
+			if e.Rating < MinRating || e.Rating > MaxRating {
+				problems = append(problems, fmt.Sprintf("dimensions[%d].entries[%d].rating必须是%d-%d之间的整数，当前为%d", i, j, MinRating, MaxRating, e.Rating))
+			}
+		}
+		if len(covered) != n {
+			problems = append(problems, fmt.Sprintf("dimensions[%d].entries应覆盖全部%d篇论文", i, n))
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// truncateTokens 按估算token数截断文本
+func truncateTokens(text string, maxTokens int) string {
+	tokens := EstimateTokens(text)
+	if maxTokens <= 0 || tokens <= maxTokens {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:len(runes)*maxTokens/tokens]) + "\n……（后文已截断）"
+}
@@ -0,0 +1,192 @@
+package aitools
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"path/filepath"
+	"strings"
+)
+
+// FakeModel 假模型名称
+const FakeModel = "fake-paper-analyzer"
+
+// fakeEmbeddingDim 假向量维度
+const fakeEmbeddingDim = 8
+
+// FakeProvider 进程内假模型
+// 不访问网络，相同输入总是返回相同结果，用于测试和本地开发
+type FakeProvider struct{}
+
+// NewFakeProvider 创建假模型
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{}
+}
+
+// Name 提供方名称
+func (f *FakeProvider) Name() string {
+	return ProviderFake
+}
+
+// ModelName 模型名称
+func (f *FakeProvider) ModelName() string {
+	return FakeModel
+}
+
+// AnalyzePaper 返回固定的分析结果，标题取自文件名，不报告token用量
+func (f *FakeProvider) AnalyzePaper(ctx context.Context, input *PaperInput) (*PaperAnalysis, Usage, error) {
+	usage := Usage{Model: FakeModel}
+	if err := ctx.Err(); err != nil {
+		return nil, usage, err
+	}
+	if input.OnProgress != nil && len(input.PDF) > 0 {
+		total := int64(len(input.PDF))
+		if !input.OnProgress(total, total) {
+			return nil, usage, context.Canceled
+		}
+	}
+
+	title := strings.TrimSuffix(filepath.Base(input.FileName), filepath.Ext(input.FileName))
+	if title == "" || title == "." {
+		title = "示例论文"
+	}
+
+	var a PaperAnalysis
+	a.BasicInfo.Title = title
+	a.BasicInfo.Authors = []string{"张三", "李四"}
+	a.BasicInfo.PublicationDate = "2024-01-01"
+	a.BasicInfo.ResearchField = "计算机科学"
+	a.Summary.Purpose = "研究目的（示例数据）"
+	a.Summary.Methods = "研究方法（示例数据）"
+	a.Summary.KeyFindings = "主要发现（示例数据）"
+	a.Summary.Conclusion = "研究结论（示例数据）"
+
+	q := &a.ContentQuality
+	q.ResearchQuestionImportance.AddressesValuableQuestion = "问题具有研究价值"
+	q.ResearchQuestionImportance.SignificanceInTheoryOrPractice = "具有一定理论与实践意义"
+	q.ResearchQuestionImportance.Rating = 4
+	q.Innovation.NewIdeasMethodsModels = "提出了改进的方法"
+	q.Innovation.BreakthroughsOrImprovements = "在已有工作基础上有所改进"
+	q.Innovation.Rating = 3
+	q.MethodologyRigor.ExperimentalDesignReasonable = "实验设计合理"
+	q.MethodologyRigor.DataSourcesReliableAndSampleSizeSufficient = "数据来源可靠"
+	q.MethodologyRigor.ControlVariablesAndStatisticsConsidered = "考虑了对照实验"
+	q.MethodologyRigor.Rating = 4
+	q.ResultsValidityReproducibility.ResultsClearAndCredible = "结果清晰可信"
+	q.ResultsValidityReproducibility.DetailsForReproductionProvided = "提供了复现细节"
+	q.ResultsValidityReproducibility.Rating = 3
+	q.DataAnalysisDepthBreadth.DataMiningConducted = "进行了数据分析"
+	q.DataAnalysisDepthBreadth.StatisticalToolsAndVisualizationsUsed = "使用了统计图表"
+	q.DataAnalysisDepthBreadth.Rating = 3
+	q.PracticalApplicationValue.SolvesRealWorldProblem = "可用于实际问题"
+	q.PracticalApplicationValue.EngineeringOrCommercialPotential = "具备工程应用潜力"
+	q.PracticalApplicationValue.Rating = 4
+	q.FutureResearchInspiration.OffersNewInsightsOrDirections = "提供了新的研究思路"
+	q.FutureResearchInspiration.RaisesOpenQuestionsOrFutureTopics = "提出了后续研究问题"
+	q.FutureResearchInspiration.Rating = 3
+	return &a, usage, nil
+}
+
+// ComparePapers 返回固定结构的对比结果，标题取自文件名
+func (f *FakeProvider) ComparePapers(ctx context.Context, inputs []*PaperInput, opts CompareOptions) (*PaperComparison, Usage, error) {
+	usage := Usage{Model: FakeModel}
+	if err := ctx.Err(); err != nil {
+		return nil, usage, err
+	}
+	opts.analyzing()
+	c := &PaperComparison{
+		Topic:          "共同研究问题（示例数据）",
+		Summary:        "对比结论（示例数据）",
+		Recommendation: "选用建议（示例数据）",
+	}
+	for _, name := range []string{"方法创新性", "实验充分性", "结果表现"} {
+		d := ComparisonDimension{Name: name, Best: 1}
+		for i := range inputs {
+			d.Entries = append(d.Entries, ComparisonEntry{Index: i + 1, Assessment: "评价（示例数据）", Rating: 3})
+		}
+		c.Dimensions = append(c.Dimensions, d)
+	}
+	for i, input := range inputs {
+		title := strings.TrimSuffix(filepath.Base(input.FileName), filepath.Ext(input.FileName))
+		if title == "" || title == "." {
+			title = "示例论文"
+		}
+		c.Papers = append(c.Papers, ComparedPaper{
+			Index:      i + 1,
+			Title:      title,
+			Methods:    "研究方法（示例数据）",
+			Datasets:   "数据集（示例数据）",
+			Results:    "主要结果（示例数据）",
+			Strengths:  "优点（示例数据）",
+			Weaknesses: "不足（示例数据）",
+		})
+	}
+	return c, usage, nil
+}
+
+// fakeChatEchoRunes 假对话回复最多复述的字符数
+const fakeChatEchoRunes = 100
+
+// Chat 复述最后一条用户消息的开头部分，不报告token用量
+func (f *FakeProvider) Chat(ctx context.Context, messages []ChatMessage) (string, Usage, error) {
+	usage := Usage{Model: FakeModel}
+	if err := ctx.Err(); err != nil {
+		return "", usage, err
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == RoleUser {
+			content := []rune(messages[i].Content)
+			if len(content) > fakeChatEchoRunes {
+				content = content[:fakeChatEchoRunes]
+			}
+			return "示例回复：" + string(content), usage, nil
+		}
+	}
+	return "示例回复", usage, nil
+}
+
+// fakeChatChunkRunes 假流式对话每段增量的字符数
+const fakeChatChunkRunes = 8
+
+// ChatStream 将Chat的回复按固定长度分段输出
+func (f *FakeProvider) ChatStream(ctx context.Context, messages []ChatMessage, onDelta ChatDeltaFunc) (string, Usage, error) {
+	reply, usage, err := f.Chat(ctx, messages)
+	if err != nil {
+		return "", usage, err
+	}
+	runes := []rune(reply)
+	for start := 0; start < len(runes) && onDelta != nil; start += fakeChatChunkRunes {
+		end := min(start+fakeChatChunkRunes, len(runes))
+		if err := onDelta(string(runes[start:end])); err != nil {
+			return string(runes[:end]), usage, err
+		}
+	}
+	return reply, usage, nil
+}
+
+// Embed 根据文本哈希生成归一化向量
+func (f *FakeProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vec := make([]float32, fakeEmbeddingDim)
+		var norm float64
+		for d := range vec {
+			h := fnv.New32a()
+			h.Write([]byte{byte(d)})
+			h.Write([]byte(text))
+			v := float64(h.Sum32())/math.MaxUint32*2 - 1
+			vec[d] = float32(v)
+			norm += v * v
+		}
+		if norm > 0 {
+			for d := range vec {
+				vec[d] = float32(float64(vec[d]) / math.Sqrt(norm))
+			}
+		}
+		vectors[i] = vec
+	}
+	return vectors, nil
+}
@@ -7,8 +7,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"google.golang.org/genai"
 )
@@ -69,52 +72,277 @@ type PaperAnalysis struct {
 	} `json:"contentQuality"`
 }
 
+// Gemini默认配置
+const (
+	DefaultGeminiBaseURL = "https://generativelanguage.googleapis.com/"
+	DefaultGeminiModel   = "gemini-2.5-flash"
+
+	DefaultGeminiEmbeddingModel = "text-embedding-004"
+)
+
+// PaperAnalysisPromptVersion 论文分析prompt版本，prompt或输出结构变化时需要递增
+const PaperAnalysisPromptVersion = "v2"
+
 // Gemini多模态分析器
 // 支持文本和图片输入
 
 type GeminiClient struct {
-	ApiKey string
+	ApiKey         string
+	BaseURL        string       // API地址，为空时使用官方地址，测试时可指向本地httptest服务
+	Model          string       // 模型名称
+	EmbeddingModel string       // 向量模型名称
+	MaxAttempts    int          // 输出不合法时的最大调用次数，为0时使用DefaultMaxAnalysisAttempts
+	HTTPClient     *http.Client // 可选HTTP客户端，为空时使用http.DefaultClient
 }
 
 // NewGeminiClient 创建Gemini客户端
 func NewGeminiClient(apiKey string) *GeminiClient {
-	return &GeminiClient{ApiKey: apiKey}
+	return &GeminiClient{ApiKey: apiKey, BaseURL: DefaultGeminiBaseURL, Model: DefaultGeminiModel}
 }
 
-// AnalyzePaper 调用Gemini API分析论文，返回结构化结果
-// text: 论文全文或主要内容
-// images: 可选图片（如论文图表），可为空
-func (g *GeminiClient) AnalyzePaper(ctx context.Context, text string, images [][]byte) (*PaperAnalysis, error) {
-	// 设置API Key
-	os.Setenv("GEMINI_API_KEY", g.ApiKey)
-	client, err := genai.NewClient(ctx, nil)
+// GeminiFile Gemini File API中的文件信息
+type GeminiFile struct {
+	Name     string `json:"name"`     // 文件资源名，如files/abc123
+	URI      string `json:"uri"`      // 文件URI，用于多模态分析
+	MIMEType string `json:"mimeType"` // 文件MIME类型
+	State    string `json:"state"`    // 文件状态：PROCESSING/ACTIVE/FAILED
+}
+
+// baseURL 返回以/结尾的API地址
+func (g *GeminiClient) baseURL() string {
+	base := g.BaseURL
+	if base == "" {
+		base = DefaultGeminiBaseURL
+	}
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	return base
+}
+
+// model 返回使用的模型名称
+func (g *GeminiClient) model() string {
+	if g.Model == "" {
+		return DefaultGeminiModel
+	}
+	return g.Model
+}
+
+// embeddingModel 返回向量模型名称
+func (g *GeminiClient) embeddingModel() string {
+	if g.EmbeddingModel == "" {
+		return DefaultGeminiEmbeddingModel
+	}
+	return g.EmbeddingModel
+}
+
+// httpClient 返回HTTP客户端
+func (g *GeminiClient) httpClient() *http.Client {
+	if g.HTTPClient != nil {
+		return g.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// newClient 创建genai SDK客户端
+func (g *GeminiClient) newClient(ctx context.Context) (*genai.Client, error) {
+	return genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:      g.ApiKey,
+		Backend:     genai.BackendGeminiAPI,
+		HTTPClient:  g.httpClient(),
+		HTTPOptions: genai.HTTPOptions{BaseURL: g.baseURL()},
+	})
+}
+
+// Name 提供方名称
+func (g *GeminiClient) Name() string {
+	return ProviderGemini
+}
+
+// ModelName 模型名称
+func (g *GeminiClient) ModelName() string {
+	return g.model()
+}
+
+// ContextTokens 模型上下文长度
+func (g *GeminiClient) ContextTokens() int {
+	return 1048576
+}
+
+// AnalyzePaper 分析论文，返回结构化结果
+// 优先按本地提取的文本分析；文本不可用时把PDF上传到File API做多模态分析，分析结束后删除上传的文件
+// 长文档模式汇总阶段只传入文本，此时走文本分析
+func (g *GeminiClient) AnalyzePaper(ctx context.Context, input *PaperInput) (*PaperAnalysis, Usage, error) {
+	// 本地提取的文本可用时直接发送文本，省去上传和PDF按页计费的图像token；
+	// 没有文本或提取质量差（如扫描件、字体编码异常）时再上传PDF原文由模型解析
+	if !UploadsPDF(input) {
+		if input.Text == "" {
+			return nil, Usage{}, fmt.Errorf("论文内容不能为空")
+		}
+		return g.AnalyzePaperText(ctx, input.Text, input.Images)
+	}
+
+	file, err := g.UploadPDF(ctx, input.PDF, input.FileName, input.OnProgress)
+	if err != nil {
+		return nil, Usage{}, fmt.Errorf("上传论文到Gemini失败: %w", err)
+	}
+	defer func() {
+		// 清理失败不影响分析结果，文件会在48小时后自动过期
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = g.DeleteFile(cleanupCtx, file.Name)
+	}()
+	return g.AnalyzeMultiModalWithGemini(ctx, []string{file.URI}, []string{file.MIMEType}, "")
+}
+
+// Chat 多轮对话，system消息作为系统指令传入
+func (g *GeminiClient) Chat(ctx context.Context, messages []ChatMessage) (string, Usage, error) {
+	client, err := g.newClient(ctx)
+	if err != nil {
+		return "", Usage{}, err
+	}
+
+	contents, cfg := geminiChatContents(messages)
+	resp, err := client.Models.GenerateContent(ctx, g.model(), contents, cfg)
+	if err != nil {
+		return "", Usage{}, err
+	}
+	return resp.Text(), g.usage(resp), nil
+}
+
+// ChatStream 流式多轮对话，返回完整回复
+// 每段增量都带有截至当前的累计用量，以最后一段为准
+func (g *GeminiClient) ChatStream(ctx context.Context, messages []ChatMessage, onDelta ChatDeltaFunc) (string, Usage, error) {
+	client, err := g.newClient(ctx)
+	if err != nil {
+		return "", Usage{}, err
+	}
+
+	contents, cfg := geminiChatContents(messages)
+	var reply strings.Builder
+	usage := Usage{Model: g.model()}
+	for resp, err := range client.Models.GenerateContentStream(ctx, g.model(), contents, cfg) {
+		if err != nil {
+			return reply.String(), usage, err
+		}
+		if resp.UsageMetadata != nil {
+			usage = g.usage(resp)
+		}
+		delta := resp.Text()
+		if delta == "" {
+			continue
+		}
+		reply.WriteString(delta)
+		if onDelta != nil {
+			if err := onDelta(delta); err != nil {
+				return reply.String(), usage, err
+			}
+		}
+	}
+	return reply.String(), usage, nil
+}
+
+// usage 读取响应中的token用量，思考过程的token计入输出
+func (g *GeminiClient) usage(resp *genai.GenerateContentResponse) Usage {
+	u := Usage{Model: g.model()}
+	if m := resp.UsageMetadata; m != nil {
+		u.InputTokens = int(m.PromptTokenCount)
+		u.OutputTokens = int(m.CandidatesTokenCount + m.ThoughtsTokenCount)
+	}
+	return u
+}
+
+// geminiChatContents 将对话消息转换为Gemini请求内容，system消息合并为系统指令
+func geminiChatContents(messages []ChatMessage) ([]*genai.Content, *genai.GenerateContentConfig) {
+	var contents []*genai.Content
+	var system []string
+	for _, msg := range messages {
+		switch msg.Role {
+		case RoleSystem:
+			system = append(system, msg.Content)
+		case RoleAssistant:
+			contents = append(contents, genai.NewContentFromText(msg.Content, genai.RoleModel))
+		default:
+			contents = append(contents, genai.NewContentFromText(msg.Content, genai.RoleUser))
+		}
+	}
+	var cfg *genai.GenerateContentConfig
+	if len(system) > 0 {
+		cfg = &genai.GenerateContentConfig{
+			SystemInstruction: genai.NewContentFromText(strings.Join(system, "\n"), genai.RoleUser),
+		}
+	}
+	return contents, cfg
+}
+
+// Embed 计算文本向量
+func (g *GeminiClient) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	client, err := g.newClient(ctx)
 	if err != nil {
 		return nil, err
 	}
-	// defer client.Close() // 无Close方法
 
+	contents := make([]*genai.Content, len(texts))
+	for i, text := range texts {
+		contents[i] = genai.NewContentFromText(text, genai.RoleUser)
+	}
+	resp, err := client.Models.EmbedContent(ctx, g.embeddingModel(), contents, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("Gemini返回向量数量不匹配: 期望%d, 实际%d", len(texts), len(resp.Embeddings))
+	}
+	vectors := make([][]float32, len(texts))
+	for i, e := range resp.Embeddings {
+		vectors[i] = e.Values
+	}
+	return vectors, nil
+}
+
+// AnalyzePaperText 调用Gemini API按文本分析论文，返回结构化结果
+// text: 论文全文或主要内容
+// images: 可选图片（如论文图表），可为空
+func (g *GeminiClient) AnalyzePaperText(ctx context.Context, text string, images [][]byte) (*PaperAnalysis, Usage, error) {
 	// 构造prompt，要求结构化输出
 	prompt := `请对以下论文内容进行多维度分析，并以如下JSON结构输出：
 ` + paperAnalysisJsonSchema + `
 论文内容：\n` + text + `\n如有图片信息请结合分析。`
 
-	// 构造内容输入
-	input := genai.NewPartFromText(prompt)
-	content := &genai.Content{Parts: []*genai.Part{input}}
-	// 支持图片可扩展：如有图片可用genai.ImageData
+	// 构造内容输入，图片以内联数据附加
+	parts := []*genai.Part{genai.NewPartFromText(prompt)}
+	for _, img := range images {
+		parts = append(parts, genai.NewPartFromBytes(img, "image/png"))
+	}
+	return g.generateAnalysis(ctx, parts)
+}
 
-	// 调用Gemini模型
-	resp, err := client.Models.GenerateContent(ctx, "gemini-2.5-flash", []*genai.Content{content}, nil)
+// generateAnalysis 以JSON模式调用Gemini生成分析结果，输出不合法时附加修正提示重试
+func (g *GeminiClient) generateAnalysis(ctx context.Context, parts []*genai.Part) (*PaperAnalysis, Usage, error) {
+	client, err := g.newClient(ctx)
 	if err != nil {
-		return nil, err
+		return nil, Usage{}, err
+	}
+	cfg := &genai.GenerateContentConfig{
+		ResponseMIMEType: "application/json",
+		ResponseSchema:   paperAnalysisSchema,
 	}
+	return generateAnalysis(ctx, g.MaxAttempts, g.generator(client, parts, cfg))
+}
 
-	// 解析结构化JSON
-	var result PaperAnalysis
-	if err := json.Unmarshal([]byte(resp.Text()), &result); err != nil {
-		return nil, fmt.Errorf("Gemini返回内容解析失败: %w, 原始内容: %s", err, resp.Text())
+// generator 返回以parts为输入调用一次Gemini的函数，feedback非空时附加在最后
+func (g *GeminiClient) generator(client *genai.Client, parts []*genai.Part, cfg *genai.GenerateContentConfig) generateFunc {
+	return func(ctx context.Context, feedback string) (string, Usage, error) {
+		reqParts := parts
+		if feedback != "" {
+			reqParts = append(append([]*genai.Part{}, parts...), genai.NewPartFromText(feedback))
+		}
+		resp, err := client.Models.GenerateContent(ctx, g.model(), []*genai.Content{{Parts: reqParts, Role: genai.RoleUser}}, cfg)
+		if err != nil {
+			return "", Usage{}, err
+		}
+		return resp.Text(), g.usage(resp), nil
 	}
-	return &result, nil
 }
 
 // paperAnalysisJsonSchema 用于prompt，指导Gemini输出结构化JSON
@@ -178,201 +406,235 @@ type UploadProgressCallback func(current, total int64) bool
 
 // UploadPDFToGemini 上传PDF到Gemini File API，返回file_uri，支持进度回调
 func UploadPDFToGemini(ctx context.Context, apiKey, filePath, displayName string, onProgress UploadProgressCallback) (string, error) {
-	file, err := os.Open(filePath)
+	return UploadFileToGemini(ctx, apiKey, filePath, "application/pdf", displayName, onProgress)
+}
+
+// UploadPDF 上传PDF内容到Gemini File API，等待文件处理完成后返回文件信息
+func (g *GeminiClient) UploadPDF(ctx context.Context, data []byte, displayName string, onProgress UploadProgressCallback) (*GeminiFile, error) {
+	return g.UploadFile(ctx, data, "application/pdf", displayName, onProgress)
+}
+
+// UploadFile 通过resumable协议分片上传文件到Gemini File API，支持进度回调
+// 上传完成后轮询文件状态，直到文件可用于分析
+func (g *GeminiClient) UploadFile(ctx context.Context, data []byte, mimeType, displayName string, onProgress UploadProgressCallback) (*GeminiFile, error) {
+	total := int64(len(data))
+	client := g.httpClient()
+
+	// 1. 启动resumable upload
+	meta, err := json.Marshal(map[string]interface{}{"file": map[string]string{"display_name": displayName}})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer file.Close()
-	stat, _ := file.Stat()
-	total := stat.Size()
-	fileBytes := make([]byte, total)
-	_, err = file.Read(fileBytes)
+	startURL := g.baseURL() + "upload/v1beta/files?key=" + url.QueryEscape(g.ApiKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, startURL, bytes.NewReader(meta))
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("创建启动上传请求失败: %w", err)
 	}
-
-	// 1. 启动resumable upload
-	startURL := fmt.Sprintf("https://generativelanguage.googleapis.com/upload/v1beta/files?key=%s", apiKey)
-	req, _ := http.NewRequest("POST", startURL, strings.NewReader(fmt.Sprintf(`{"file": {"display_name": "%s"}}`, displayName)))
 	req.Header.Set("X-Goog-Upload-Protocol", "resumable")
 	req.Header.Set("X-Goog-Upload-Command", "start")
-	req.Header.Set("X-Goog-Upload-Header-Content-Length", fmt.Sprintf("%d", total))
-	req.Header.Set("X-Goog-Upload-Header-Content-Type", "application/pdf")
+	req.Header.Set("X-Goog-Upload-Header-Content-Length", strconv.FormatInt(total, 10))
+	req.Header.Set("X-Goog-Upload-Header-Content-Type", mimeType)
 	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("启动上传请求失败: %w", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("启动上传失败，状态码: %d, 响应: %s", resp.StatusCode, string(body))}
 	}
-	defer resp.Body.Close()
 	uploadURL := resp.Header.Get("X-Goog-Upload-URL")
 	if uploadURL == "" {
-		return "", fmt.Errorf("未获取到上传URL")
+		return nil, fmt.Errorf("未获取到上传URL")
 	}
 
-	// 2. 上传文件内容（支持进度回调）
+	// 2. 分片上传文件内容，最后一片携带finalize指令，其响应体包含文件信息
 	const chunkSize = 2 * 1024 * 1024 // 2MB分片
-	var uploaded int64 = 0
-	for uploaded < total {
-		remain := total - uploaded
-		sz := chunkSize
-		if remain < int64(chunkSize) {
-			sz = int(remain)
+	var uploaded int64
+	var finalBody []byte
+	for {
+		end := uploaded + chunkSize
+		if end > total {
+			end = total
 		}
-		chunk := fileBytes[uploaded : uploaded+int64(sz)]
 		cmd := "upload"
-		if uploaded+int64(sz) == total {
+		if end == total {
 			cmd = "upload, finalize"
 		}
-		req2, _ := http.NewRequest("POST", uploadURL, bytes.NewReader(chunk))
-		req2.Header.Set("X-Goog-Upload-Command", cmd)
-		req2.Header.Set("X-Goog-Upload-Offset", fmt.Sprintf("%d", uploaded))
-		req2.Header.Set("Content-Length", fmt.Sprintf("%d", sz))
-		resp2, err := client.Do(req2)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, bytes.NewReader(data[uploaded:end]))
 		if err != nil {
-			return "", err
+			return nil, fmt.Errorf("创建分片上传请求失败: %w", err)
 		}
-		resp2.Body.Close()
-		uploaded += int64(sz)
+		req.Header.Set("X-Goog-Upload-Command", cmd)
+		req.Header.Set("X-Goog-Upload-Offset", strconv.FormatInt(uploaded, 10))
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("分片上传请求失败: %w", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, &APIError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("分片上传失败，状态码: %d, 响应: %s", resp.StatusCode, string(body))}
+		}
+		uploaded = end
 		if onProgress != nil && !onProgress(uploaded, total) {
-			return "", fmt.Errorf("用户中断上传")
+			return nil, fmt.Errorf("用户中断上传")
 		}
+		if cmd != "upload" {
+			finalBody = body
+			break
+		}
+	}
+
+	// 3. 解析文件信息
+	var result struct {
+		File GeminiFile `json:"file"`
+	}
+	if err := json.Unmarshal(finalBody, &result); err != nil {
+		return nil, fmt.Errorf("解析file_uri失败: %w, 原始内容: %s", err, string(finalBody))
+	}
+	if result.File.URI == "" {
+		return nil, fmt.Errorf("上传响应缺少file_uri, 原始内容: %s", string(finalBody))
 	}
+	return g.waitFileActive(ctx, &result.File)
+}
 
-	// 3. 获取file_uri
-	// 最后一次响应体包含file信息
-	finalResp, err := client.Get(uploadURL)
+// waitFileActive 轮询文件状态直到ACTIVE，PDF等文件上传后需要服务端处理
+func (g *GeminiClient) waitFileActive(ctx context.Context, file *GeminiFile) (*GeminiFile, error) {
+	if file.State == "" || file.State == string(genai.FileStateActive) {
+		return file, nil
+	}
+	client, err := g.newClient(ctx)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer finalResp.Body.Close()
-	body, _ := io.ReadAll(finalResp.Body)
-	var result struct {
-		File struct {
-			URI string `json:"uri"`
-		} `json:"file"`
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	for {
+		switch genai.FileState(file.State) {
+		case genai.FileStateActive:
+			return file, nil
+		case genai.FileStateFailed:
+			return nil, fmt.Errorf("Gemini文件处理失败: %s", file.Name)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+		latest, err := client.Files.Get(ctx, file.Name, nil)
+		if err != nil {
+			return nil, fmt.Errorf("查询Gemini文件状态失败: %w", err)
+		}
+		file.State = string(latest.State)
+		if latest.URI != "" {
+			file.URI = latest.URI
+		}
 	}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("解析file_uri失败: %w, 原始内容: %s", err, string(body))
+}
+
+// DeleteFile 删除已上传到Gemini File API的文件
+func (g *GeminiClient) DeleteFile(ctx context.Context, name string) error {
+	client, err := g.newClient(ctx)
+	if err != nil {
+		return err
 	}
-	return result.File.URI, nil
+	_, err = client.Files.Delete(ctx, name, nil)
+	return err
 }
 
 // AnalyzeMultiModalWithGemini 支持多模态（PDF、图片）分析，返回结构化结果
 // fileURIs: PDF/图片等file_uri列表
 // imageMIMEs: 与fileURIs一一对应的MIME类型，如"application/pdf"、"image/png"
 // extraText: 附加文本内容
-func (g *GeminiClient) AnalyzeMultiModalWithGemini(ctx context.Context, fileURIs []string, imageMIMEs []string, extraText string) (*PaperAnalysis, error) {
-	os.Setenv("GEMINI_API_KEY", g.ApiKey)
-	client, err := genai.NewClient(ctx, nil)
+func (g *GeminiClient) AnalyzeMultiModalWithGemini(ctx context.Context, fileURIs []string, imageMIMEs []string, extraText string) (*PaperAnalysis, Usage, error) {
+	parts := multiModalParts(fileURIs, imageMIMEs, extraText)
+	// 指定结构化输出prompt
+	parts = append(parts, genai.NewPartFromText("请对上述论文及其多模态内容进行多维度分析，并以如下JSON结构输出：\n"+paperAnalysisJsonSchema))
+	return g.generateAnalysis(ctx, parts)
+}
+
+// CompareMultiModalWithGemini 对多篇论文PDF进行对比分析，fileURIs按论文编号顺序排列
+func (g *GeminiClient) CompareMultiModalWithGemini(ctx context.Context, fileURIs []string, mimeTypes []string, extraText string) (*PaperComparison, Usage, error) {
+	n := len(fileURIs)
+	parts := multiModalParts(fileURIs, mimeTypes, extraText)
+	parts = append(parts, genai.NewPartFromText(comparisonPrompt(n)))
+
+	client, err := g.newClient(ctx)
 	if err != nil {
-		return nil, err
+		return nil, Usage{}, err
+	}
+	cfg := &genai.GenerateContentConfig{
+		ResponseMIMEType: "application/json",
+		ResponseSchema:   comparisonSchema,
 	}
-	// defer client.Close() // 无Close方法
+	return generateStructured(ctx, g.MaxAttempts, g.generator(client, parts, cfg), func(raw string) (*PaperComparison, error) {
+		return ParsePaperComparison(raw, n)
+	})
+}
 
-	// 构造多模态内容
+// ComparePapers 对比分析多篇论文
+// 全部论文都有PDF时上传原文对比，否则按提取的文本对比
+func (g *GeminiClient) ComparePapers(ctx context.Context, inputs []*PaperInput, opts CompareOptions) (*PaperComparison, Usage, error) {
+	for _, input := range inputs {
+		if len(input.PDF) == 0 {
+			return compareByChat(ctx, g, inputs, g.MaxAttempts, opts)
+		}
+	}
+
+	var uris, mimeTypes, names []string
+	defer func() {
+		// 清理失败不影响对比结果，文件会在48小时后自动过期
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		for _, name := range names {
+			_ = g.DeleteFile(cleanupCtx, name)
+		}
+	}()
+	var order strings.Builder
+	order.WriteString("上述PDF文件按顺序依次为：")
+	for i, input := range inputs {
+		file, err := g.UploadPDF(ctx, input.PDF, input.FileName, input.OnProgress)
+		if err != nil {
+			return nil, Usage{}, fmt.Errorf("上传论文%d到Gemini失败: %w", i+1, err)
+		}
+		names = append(names, file.Name)
+		uris = append(uris, file.URI)
+		mimeTypes = append(mimeTypes, file.MIMEType)
+		fmt.Fprintf(&order, "\n论文%d：%s", i+1, input.FileName)
+	}
+	opts.analyzing()
+	return g.CompareMultiModalWithGemini(ctx, uris, mimeTypes, order.String())
+}
+
+// multiModalParts 构造多模态内容，mimeTypes与fileURIs一一对应，缺省为PDF
+func multiModalParts(fileURIs []string, mimeTypes []string, extraText string) []*genai.Part {
 	var parts []*genai.Part
 	for i, uri := range fileURIs {
 		mime := "application/pdf"
-		if i < len(imageMIMEs) {
-			mime = imageMIMEs[i]
+		if i < len(mimeTypes) {
+			mime = mimeTypes[i]
 		}
 		parts = append(parts, genai.NewPartFromURI(uri, mime))
 	}
 	if extraText != "" {
 		parts = append(parts, genai.NewPartFromText(extraText))
 	}
-	// 指定结构化输出prompt
-	parts = append(parts, genai.NewPartFromText("请对上述论文及其多模态内容进行多维度分析，并以如下JSON结构输出：\n"+paperAnalysisJsonSchema))
-	content := &genai.Content{Parts: parts}
-
-	// 调用 Gemini
-	resp, err := client.Models.GenerateContent(ctx, "gemini-2.5-flash", []*genai.Content{content}, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	// 解析结构化JSON
-	var result PaperAnalysis
-	if err := json.Unmarshal([]byte(resp.Text()), &result); err != nil {
-		return nil, fmt.Errorf("Gemini返回内容解析失败: %w, 原始内容: %s", err, resp.Text())
-	}
-	return &result, nil
+	return parts
 }
 
 // UploadFileToGemini 上传文件到Gemini File API，返回file_uri，支持进度回调
 func UploadFileToGemini(ctx context.Context, apiKey, filePath, mimeType, displayName string, onProgress UploadProgressCallback) (string, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return "", fmt.Errorf("打开文件失败: %w", err)
-	}
-	defer file.Close()
-	stat, err := file.Stat()
-	if err != nil {
-		return "", fmt.Errorf("获取文件信息失败: %w", err)
-	}
-	total := stat.Size()
-	fileBytes := make([]byte, total)
-	_, err = file.Read(fileBytes)
+	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return "", fmt.Errorf("读取文件内容失败: %w", err)
 	}
-
-	// 1. 启动resumable upload
-	startURL := fmt.Sprintf("https://generativelanguage.googleapis.com/upload/v1beta/files?key=%s", apiKey)
-	req, err := http.NewRequest("POST", startURL, nil)
-	if err != nil {
-		return "", fmt.Errorf("创建启动上传请求失败: %w", err)
-	}
-	resp, err := http.DefaultClient.Do(req)
+	file, err := NewGeminiClient(apiKey).UploadFile(ctx, data, mimeType, displayName, onProgress)
 	if err != nil {
-		return "", fmt.Errorf("启动上传请求失败: %w", err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("启动上传失败，状态码: %d, 响应: %s", resp.StatusCode, string(body))
-	}
-	uploadURL := resp.Header.Get("Location")
-	if uploadURL == "" {
-		return "", fmt.Errorf("未获取到上传URL")
-	}
-
-	// 2. 分块上传，支持进度回调
-	const chunkSize = 2 * 1024 * 1024 // 2MB
-	var uploaded int64 = 0
-	for uploaded < total {
-		end := uploaded + chunkSize
-		if end > total {
-			end = total
-		}
-		chunk := fileBytes[uploaded:end]
-		chunkReader := bytes.NewReader(chunk)
-		req, err := http.NewRequest("PUT", uploadURL, chunkReader)
-		if err != nil {
-			return "", fmt.Errorf("创建分块上传请求失败: %w", err)
-		}
-		rangeHeader := fmt.Sprintf("bytes %d-%d/%d", uploaded, end-1, total)
-		req.Header.Set("Content-Range", rangeHeader)
-		req.Header.Set("Content-Type", mimeType)
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return "", fmt.Errorf("分块上传请求失败: %w", err)
-		}
-		resp.Body.Close()
-		if resp.StatusCode != 200 && resp.StatusCode != 201 && resp.StatusCode != 308 {
-			return "", fmt.Errorf("分块上传失败，状态码: %d", resp.StatusCode)
-		}
-		uploaded = end
-		if onProgress != nil && !onProgress(uploaded, total) {
-			return "", fmt.Errorf("上传被用户中断")
-		}
+		return "", err
 	}
-
-	// 3. 获取file_uri（假设最后响应体中有file_uri，实际需根据API文档调整）
-	// 这里仅做演示，实际应解析响应体
-	fileURI := "file_uri_placeholder" // TODO: 解析真实file_uri
-	return fileURI, nil
+	return file.URI, nil
 }
 
 // Gemini多模态分析，详细错误处理
@@ -380,11 +642,6 @@ func (g *GeminiClient) AnalyzePaperWithFile(ctx context.Context, fileURI string,
 	if fileURI == "" {
 		return nil, fmt.Errorf("fileURI不能为空")
 	}
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{APIKey: g.ApiKey})
-	if err != nil {
-		return nil, fmt.Errorf("Gemini SDK初始化失败: %w", err)
-	}
-	// defer client.Close() // genai.Client没有Close方法
 
 	// 构造多模态输入
 	var parts []*genai.Part
@@ -400,29 +657,11 @@ func (g *GeminiClient) AnalyzePaperWithFile(ctx context.Context, fileURI string,
 		})
 	}
 	// prompt部分
-	parts = append(parts, &genai.Part{
-		Text: "请对论文进行结构化分析，输出JSON，字段包括basicInfo、summary、contentQuality等，具体结构见API文档。",
-	})
+	parts = append(parts, genai.NewPartFromText("请对论文进行结构化分析，并以如下JSON结构输出：\n"+paperAnalysisJsonSchema))
 
-	content := &genai.Content{Parts: parts}
-	resp, err := client.Models.GenerateContent(ctx, "models/gemini-1.5-pro-latest", []*genai.Content{content}, nil)
+	result, _, err := g.generateAnalysis(ctx, parts)
 	if err != nil {
 		return nil, fmt.Errorf("Gemini内容生成失败: %w", err)
 	}
-	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
-		return nil, fmt.Errorf("Gemini无返回内容")
-	}
-	// 解析返回内容
-	var text string
-	if len(resp.Candidates[0].Content.Parts) > 0 {
-		text = resp.Candidates[0].Content.Parts[0].Text
-	} else {
-		text = ""
-	}
-	var result PaperAnalysis
-	err = json.Unmarshal([]byte(text), &result)
-	if err != nil {
-		return nil, fmt.Errorf("Gemini返回内容解析失败: %w, 原始内容: %s", err, text)
-	}
-	return &result, nil
+	return result, nil
 }
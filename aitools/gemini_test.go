@@ -0,0 +1,169 @@
+package aitools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// geminiTestServer 模拟Gemini API，按顺序返回预设的生成内容并记录收到的请求
+type geminiTestServer struct {
+	t        *testing.T
+	url      string
+	mu       sync.Mutex
+	replies  []string // 依次返回的生成内容
+	generate []string // 收到的generateContent请求体
+	uploads  []string // 收到的上传指令（start、upload, finalize）
+	deleted  []string // 删除的文件路径
+}
+
+func newGeminiTestServer(t *testing.T, replies ...string) (*geminiTestServer, *GeminiClient) {
+	t.Helper()
+	s := &geminiTestServer{t: t, replies: replies}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	s.url = srv.URL
+	client := NewGeminiClient("test-key")
+	client.BaseURL = srv.URL
+	client.HTTPClient = srv.Client()
+	return s, client
+}
+
+func (s *geminiTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	body, _ := io.ReadAll(r.Body)
+	switch {
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, ":generateContent"):
+		s.generate = append(s.generate, string(body))
+		if len(s.replies) == 0 {
+			s.t.Errorf("生成请求次数超出预期")
+			http.Error(w, "no reply", http.StatusInternalServerError)
+			return
+		}
+		reply := s.replies[0]
+		s.replies = s.replies[1:]
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"candidates": []any{map[string]any{
+				"content": map[string]any{"role": "model", "parts": []any{map[string]any{"text": reply}}},
+			}},
+			"usageMetadata": map[string]any{"promptTokenCount": 100, "candidatesTokenCount": 20, "thoughtsTokenCount": 5},
+		})
+	case r.Method == http.MethodPost && r.URL.Path == "/upload/v1beta/files":
+		s.uploads = append(s.uploads, r.Header.Get("X-Goog-Upload-Command"))
+		w.Header().Set("X-Goog-Upload-URL", s.url+"/upload-session")
+	case r.Method == http.MethodPost && r.URL.Path == "/upload-session":
+		s.uploads = append(s.uploads, r.Header.Get("X-Goog-Upload-Command"))
+		fmt.Fprint(w, `{"file":{"name":"files/abc","uri":"https://example.com/files/abc","mimeType":"application/pdf","state":"ACTIVE"}}`)
+	case r.Method == http.MethodDelete:
+		s.deleted = append(s.deleted, r.URL.Path)
+		fmt.Fprint(w, `{}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestGeminiAnalyzePaperTextParsesResponse(t *testing.T) {
+	srv, client := newGeminiTestServer(t, validAnalysisJSON(t))
+	text := strings.Repeat("This paper studies graph neural networks. ", 20)
+
+	analysis, usage, err := client.AnalyzePaper(context.Background(), &PaperInput{Text: text, PDF: []byte("%PDF-1.4"), FileName: "paper.pdf"})
+	if err != nil {
+		t.Fatalf("分析失败: %v", err)
+	}
+	if analysis.BasicInfo.Title != "paper" {
+		t.Errorf("标题解析错误: %q", analysis.BasicInfo.Title)
+	}
+	if usage.Model != DefaultGeminiModel || usage.InputTokens != 100 || usage.OutputTokens != 25 {
+		t.Errorf("token用量错误，思考token应计入输出: %+v", usage)
+	}
+	if len(srv.uploads) != 0 {
+		t.Errorf("文本可用时不应上传PDF，实际上传指令为%v", srv.uploads)
+	}
+	req := srv.generate[0]
+	if !strings.Contains(req, "graph neural networks") || !strings.Contains(req, `"responseMimeType":"application/json"`) {
+		t.Errorf("请求应以JSON模式发送论文文本，实际为%s", req)
+	}
+}
+
+func TestGeminiAnalyzePaperReprompts(t *testing.T) {
+	raw := validAnalysisJSON(t)
+	invalid := strings.Replace(raw, `"rating":4`, `"rating":9`, 1)
+	srv, client := newGeminiTestServer(t, invalid, raw)
+
+	_, usage, err := client.AnalyzePaperText(context.Background(), "论文正文", nil)
+	if err != nil {
+		t.Fatalf("分析失败: %v", err)
+	}
+	if len(srv.generate) != 2 {
+		t.Fatalf("评分不合法时应重新生成一次，实际调用%d次", len(srv.generate))
+	}
+	if !strings.Contains(srv.generate[1], "rating必须是") || strings.Contains(srv.generate[0], "rating必须是") {
+		t.Error("只有重新生成的请求应附带校验问题")
+	}
+	if usage.InputTokens != 200 || usage.OutputTokens != 50 {
+		t.Errorf("token用量应为两次调用之和: %+v", usage)
+	}
+}
+
+func TestGeminiAnalyzePaperUploadsAndDeletesPDF(t *testing.T) {
+	srv, client := newGeminiTestServer(t, validAnalysisJSON(t))
+
+	var progress int64
+	_, _, err := client.AnalyzePaper(context.Background(), &PaperInput{
+		PDF:      []byte("%PDF-1.4 scanned"),
+		FileName: "paper.pdf",
+		OnProgress: func(current, total int64) bool {
+			progress = current
+			return true
+		},
+	})
+	if err != nil {
+		t.Fatalf("分析失败: %v", err)
+	}
+	if strings.Join(srv.uploads, ";") != "start;upload, finalize" {
+		t.Errorf("上传指令错误: %v", srv.uploads)
+	}
+	if progress != int64(len("%PDF-1.4 scanned")) {
+		t.Errorf("上传进度应报告到全部字节，实际为%d", progress)
+	}
+	if len(srv.generate) != 1 || !strings.Contains(srv.generate[0], "https://example.com/files/abc") {
+		t.Errorf("分析请求应引用上传的文件，实际为%v", srv.generate)
+	}
+	if len(srv.deleted) != 1 || !strings.HasSuffix(srv.deleted[0], "/files/abc") {
+		t.Errorf("分析结束后应删除上传的文件，实际为%v", srv.deleted)
+	}
+}
+
+func TestGeminiErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"error":{"code":503,"message":"model overloaded","status":"UNAVAILABLE"}}`)
+	}))
+	defer srv.Close()
+	client := NewGeminiClient("test-key")
+	client.BaseURL = srv.URL
+
+	_, _, err := client.Chat(context.Background(), []ChatMessage{{Role: RoleUser, Content: "你好"}})
+	if err == nil {
+		t.Fatal("状态码非2xx时应返回错误")
+	}
+	if !unavailable(err) {
+		t.Errorf("503应视为服务不可用: %v", err)
+	}
+
+	_, err = client.UploadPDF(context.Background(), []byte("%PDF-1.4"), "paper.pdf", nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("上传失败应返回带状态码的APIError，实际为%v", err)
+	}
+}
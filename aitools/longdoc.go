@@ -0,0 +1,348 @@
+package aitools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 分析模式
+const (
+	AnalysisModeDirect  = "direct"   // 全文一次性分析
+	AnalysisModeLongDoc = "long_doc" // 分块摘要后汇总分析
+)
+
+// DefaultContextTokens 未声明上下文长度的提供方默认按该值估算
+const DefaultContextTokens = 32000
+
+// ContextWindowProvider 可选接口，提供方声明模型上下文长度（token数）
+type ContextWindowProvider interface {
+	ContextTokens() int
+}
+
+// LongDocOptions 长文档分析参数，零值字段按模型上下文长度推算
+type LongDocOptions struct {
+	MaxDirectTokens int                   // 预估token数超过该值时使用分块模式
+	ChunkTokens     int                   // 单个分块的token预算
+	SummaryTokens   int                   // 单个分块摘要的token预算
+	OnChunk         func(done, total int) // 可选分块进度回调
+	OnAnalyzing     func()                // 可选回调，不需要上传PDF时在调用模型前调用；需要上传时以上传进度回调到全部字节为准
+}
+
+// ChunkUsage 单个分块的token使用情况，提供方未报告用量时按字符数估算
+type ChunkUsage struct {
+	Index        int    `json:"index"`         // 分块序号，从0开始
+	Title        string `json:"title"`         // 分块首个章节标题或页码范围
+	InputTokens  int    `json:"input_tokens"`  // 输入token数
+	OutputTokens int    `json:"output_tokens"` // 摘要token数
+}
+
+// AnalysisUsage 一次分析的模式与token使用情况，提供方未报告用量时按字符数估算
+type AnalysisUsage struct {
+	Model        string       `json:"-"`                // 生成分析结果的模型，保存在AnalysisResult.Model
+	Mode         string       `json:"mode"`             // 分析模式
+	InputTokens  int          `json:"input_tokens"`     // 输入token总数
+	OutputTokens int          `json:"output_tokens"`    // 输出token总数
+	Chunks       []ChunkUsage `json:"chunks,omitempty"` // 分块模式下每个分块的使用情况
+}
+
+// EstimateTokens 估算文本token数
+// 中日韩字符约1个token，其他字符约4个字符1个token
+func EstimateTokens(text string) int {
+	var cjk, other int
+	for _, r := range text {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			cjk++
+		} else {
+			other++
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+// 本地提取文本的质量要求
+const (
+	minUsableTextRunes = 500  // 字数过少说明只提取到页眉页码等零散内容，如扫描件
+	maxGarbledRatio    = 0.02 // 乱码字符占比上限，超过说明字体编码无法正确解析
+)
+
+// usableText 判断本地提取的文本能否代替PDF原文用于分析
+// 乱码字符包括无效编码、私有区字符和除换行制表外的控制字符
+func usableText(text string) bool {
+	var total, garbled int
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		total++
+		if r == utf8.RuneError || unicode.Is(unicode.Co, r) || unicode.IsControl(r) {
+			garbled++
+		}
+	}
+	return total >= minUsableTextRunes && float64(garbled) <= float64(total)*maxGarbledRatio
+}
+
+// UploadsPDF 分析时是否需要把PDF原文上传给模型服务
+// 本地提取的文本不可用（如扫描件、字体编码异常）且有PDF时上传原文，否则按文本分析
+func UploadsPDF(input *PaperInput) bool {
+	return len(input.PDF) > 0 && !usableText(input.Text)
+}
+
+// withDefaults 根据提供方上下文长度补全参数
+func (o LongDocOptions) withDefaults(provider LLMProvider) LongDocOptions {
+	window := DefaultContextTokens
+	if p, ok := provider.(ContextWindowProvider); ok && p.ContextTokens() > 0 {
+		window = p.ContextTokens()
+	}
+	// 预留prompt、输出结构和模型回复的空间
+	if o.MaxDirectTokens <= 0 {
+		o.MaxDirectTokens = window * 6 / 10
+	}
+	if o.ChunkTokens <= 0 {
+		o.ChunkTokens = min(window/2, 24000)
+	}
+	if o.SummaryTokens <= 0 {
+		o.SummaryTokens = 1500
+	}
+	return o
+}
+
+// AnalyzePaperAuto 按预估token数自动选择分析模式
+// 文本未超出预算或只有PDF时直接分析，超出时分块摘要后汇总为一份PaperAnalysis
+func AnalyzePaperAuto(ctx context.Context, provider LLMProvider, input *PaperInput, opts LongDocOptions) (*PaperAnalysis, *AnalysisUsage, error) {
+	opts = opts.withDefaults(provider)
+	tokens := EstimateTokens(input.Text)
+	if input.Text == "" || tokens <= opts.MaxDirectTokens {
+		if !UploadsPDF(input) && opts.OnAnalyzing != nil {
+			opts.OnAnalyzing()
+		}
+		analysis, used, err := provider.AnalyzePaper(ctx, input)
+		if err != nil {
+			return nil, nil, err
+		}
+		usage := &AnalysisUsage{Model: used.Model, Mode: AnalysisModeDirect}
+		usage.InputTokens, usage.OutputTokens = reportedOrEstimated(used, input.Text, analysisText(analysis))
+		return analysis, usage, nil
+	}
+	if opts.OnAnalyzing != nil {
+		opts.OnAnalyzing()
+	}
+	return AnalyzeLongPaper(ctx, provider, input, opts)
+}
+
+// AnalyzeLongPaper 长文档分析
+// 按章节或页面切分为不超过预算的分块，逐块提炼要点，再把要点汇总交给模型生成结构化分析
+func AnalyzeLongPaper(ctx context.Context, provider LLMProvider, input *PaperInput, opts LongDocOptions) (*PaperAnalysis, *AnalysisUsage, error) {
+	opts = opts.withDefaults(provider)
+	usage := &AnalysisUsage{Mode: AnalysisModeLongDoc}
+
+	var units []docUnit
+	if len(input.Pages) > 0 {
+		for i, page := range input.Pages {
+			units = append(units, docUnit{Title: fmt.Sprintf("第%d页", i+1), Text: page})
+		}
+	} else {
+		units = splitSections(input.Text)
+	}
+	chunks := packChunks(units, opts.ChunkTokens)
+
+	summaries := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		prompt := fmt.Sprintf(chunkSummaryPrompt, i+1, len(chunks), opts.SummaryTokens) + chunk.Text
+		summary, used, err := provider.Chat(ctx, []ChatMessage{
+			{Role: RoleSystem, Content: "你是一名严谨的学术论文审稿人。"},
+			{Role: RoleUser, Content: prompt},
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("第%d/%d个分块摘要失败: %w", i+1, len(chunks), err)
+		}
+		summary = strings.TrimSpace(summary)
+		summaries = append(summaries, fmt.Sprintf("【第%d部分：%s】\n%s", i+1, chunk.Title, summary))
+
+		in, out := reportedOrEstimated(used, prompt, summary)
+		usage.Chunks = append(usage.Chunks, ChunkUsage{Index: len(usage.Chunks), Title: chunk.Title, InputTokens: in, OutputTokens: out})
+		usage.InputTokens += in
+		usage.OutputTokens += out
+		if opts.OnChunk != nil {
+			opts.OnChunk(i+1, len(chunks))
+		}
+	}
+
+	// 汇总阶段只使用分块摘要，不再附带PDF原文
+	combined := strings.Join(summaries, "\n\n")
+	if t := EstimateTokens(combined); t > opts.MaxDirectTokens && t < EstimateTokens(input.Text) && len(chunks) > 1 {
+		// 摘要仍超出预算时对摘要再做一轮分块汇总
+		analysis, next, err := AnalyzeLongPaper(ctx, provider, &PaperInput{Text: combined, FileName: input.FileName, Images: input.Images}, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, c := range next.Chunks {
+			c.Index = len(usage.Chunks)
+			usage.Chunks = append(usage.Chunks, c)
+		}
+		usage.Model = next.Model
+		usage.InputTokens += next.InputTokens
+		usage.OutputTokens += next.OutputTokens
+		return analysis, usage, nil
+	}
+	combined = reducePromptHeader + combined
+	analysis, used, err := provider.AnalyzePaper(ctx, &PaperInput{Text: combined, FileName: input.FileName, Images: input.Images})
+	if err != nil {
+		return nil, nil, fmt.Errorf("汇总分析失败: %w", err)
+	}
+	in, out := reportedOrEstimated(used, combined, analysisText(analysis))
+	usage.Model = used.Model
+	usage.InputTokens += in
+	usage.OutputTokens += out
+	return analysis, usage, nil
+}
+
+// reportedOrEstimated 返回一次调用的输入、输出token数，提供方未报告用量时按输入输出文本估算
+// 只有PDF、没有提取文本时无法估算输入
+func reportedOrEstimated(used Usage, input, output string) (int, int) {
+	if used.Reported() {
+		return used.InputTokens, used.OutputTokens
+	}
+	return EstimateTokens(input), EstimateTokens(output)
+}
+
+// analysisText 分析结果的JSON文本，用于估算输出token数
+func analysisText(a *PaperAnalysis) string {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// chunkSummaryPrompt 分块摘要prompt，参数依次为分块序号、分块总数、摘要token预算
+const chunkSummaryPrompt = `以下是一篇长论文的第%d/%d部分。请提炼该部分的要点，包括（如有）：论文标题与作者、研究问题与目的、研究方法、实验设计与数据、主要结果与数据指标、结论与局限、对未来研究的启发。
+只输出要点，不要评价写作，不超过%d字。
+
+`
+
+// reducePromptHeader 汇总阶段附加在分块摘要前的说明
+const reducePromptHeader = "以下内容是一篇长论文按顺序分块提炼的要点摘要，请基于这些要点对整篇论文进行分析。\n\n"
+
+// docUnit 切分后的最小文本单元（章节或页面）
+type docUnit struct {
+	Title string
+	Text  string
+}
+
+// sectionHeadingPattern 识别常见章节标题，如"1 Introduction"、"2.1 方法"、"Abstract"、"第三章 实验"
+var sectionHeadingPattern = regexp.MustCompile(`^(?:\d{1,2}(?:\.\d{1,2}){0,3}\.?\s+[\p{Lu}\p{Han}].{0,80}|(?i:abstract|introduction|related work|background|method(?:s|ology)?|experiments?|results|discussion|conclusions?|references|bibliography|appendix)\b.{0,40}|摘\s*要|引\s*言|参考文献|致\s*谢|附\s*录|第[一二三四五六七八九十百\d]+[章节].{0,40})$`)
+
+// tocLeaderPattern 目录中的点线引导符，如"....."、": : : :"
+var tocLeaderPattern = regexp.MustCompile(`(?:[.:·…]\s?){4,}`)
+
+// IsSectionHeading 判断一行文本是否像章节标题
+func IsSectionHeading(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" || utf8.RuneCountInString(line) > 100 {
+		return false
+	}
+	// 以句号或逗号结尾的通常是正文，带点线引导符的是目录行
+	if strings.HasSuffix(line, ".") || strings.HasSuffix(line, "。") || strings.HasSuffix(line, ",") || strings.HasSuffix(line, "，") {
+		return false
+	}
+	if tocLeaderPattern.MatchString(line) {
+		return false
+	}
+	return sectionHeadingPattern.MatchString(line)
+}
+
+// splitSections 按章节标题切分文本，未识别到标题时整篇作为一个单元
+func splitSections(text string) []docUnit {
+	var units []docUnit
+	current := docUnit{Title: "开头"}
+	var b strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if IsSectionHeading(trimmed) {
+			if strings.TrimSpace(b.String()) != "" {
+				current.Text = b.String()
+				units = append(units, current)
+			}
+			current = docUnit{Title: trimmed}
+			b.Reset()
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	if strings.TrimSpace(b.String()) != "" {
+		current.Text = b.String()
+		units = append(units, current)
+	}
+	return units
+}
+
+// packChunks 把单元按顺序合并为不超过预算的分块，超长单元按段落或字符继续切分
+func packChunks(units []docUnit, budget int) []docUnit {
+	var chunks []docUnit
+	var current docUnit
+	tokens := 0
+	flush := func() {
+		if current.Text != "" {
+			chunks = append(chunks, current)
+		}
+		current, tokens = docUnit{}, 0
+	}
+	for _, unit := range units {
+		for _, piece := range splitUnit(unit, budget) {
+			t := EstimateTokens(piece.Text)
+			if tokens > 0 && tokens+t > budget {
+				flush()
+			}
+			if current.Text == "" {
+				current.Title = piece.Title
+			}
+			current.Text += piece.Text
+			tokens += t
+		}
+	}
+	flush()
+	return chunks
+}
+
+// splitUnit 把超出预算的单元先按段落、再按字符切分
+func splitUnit(unit docUnit, budget int) []docUnit {
+	if EstimateTokens(unit.Text) <= budget {
+		return []docUnit{unit}
+	}
+	var pieces []docUnit
+	var b strings.Builder
+	tokens := 0
+	add := func(text string) {
+		t := EstimateTokens(text)
+		if tokens > 0 && tokens+t > budget {
+			pieces = append(pieces, docUnit{Title: unit.Title, Text: b.String()})
+			b.Reset()
+			tokens = 0
+		}
+		b.WriteString(text)
+		tokens += t
+	}
+	for _, para := range strings.SplitAfter(unit.Text, "\n\n") {
+		if EstimateTokens(para) <= budget {
+			add(para)
+			continue
+		}
+		// 单个段落仍超出预算，按字符数切分（中文按1字1token的保守估计）
+		runes := []rune(para)
+		for start := 0; start < len(runes); start += budget {
+			add(string(runes[start:min(start+budget, len(runes))]))
+		}
+	}
+	if b.Len() > 0 {
+		pieces = append(pieces, docUnit{Title: unit.Title, Text: b.String()})
+	}
+	return pieces
+}
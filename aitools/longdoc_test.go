@@ -0,0 +1,29 @@
+package aitools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUsableText(t *testing.T) {
+	body := strings.Repeat("Graph neural networks aggregate features from neighbours. ", 20)
+	tests := []struct {
+		name string
+		text string
+		want bool
+	}{
+		{name: "正常正文", text: body, want: true},
+		{name: "中文正文", text: strings.Repeat("本文提出了一种基于图神经网络的论文推荐方法。", 30), want: true},
+		{name: "空文本", text: "", want: false},
+		{name: "扫描件只有页码", text: "1\n\n2\n\n3\n\narXiv:2401.00001", want: false},
+		{name: "字体编码异常", text: body + strings.Repeat("�", 40), want: false},
+		{name: "少量乱码", text: body + "�", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := usableText(tt.text); got != tt.want {
+				t.Errorf("usableText=%v，期望%v", got, tt.want)
+			}
+		})
+	}
+}
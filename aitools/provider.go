@@ -0,0 +1,120 @@
+package aitools
+
+import (
+	"context"
+	"fmt"
+)
+
+// 模型服务提供方名称，对应配置中的LLM_PROVIDER
+const (
+	ProviderGemini = "gemini"
+	ProviderQwen   = "qwen"
+	ProviderFake   = "fake"
+)
+
+// 对话消息角色
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// LLMProvider 大模型服务统一接口
+// 分析流程、论文问答等功能只依赖该接口，具体使用哪个模型由配置决定
+type LLMProvider interface {
+	// Name 提供方名称，如gemini、qwen、fake
+	Name() string
+	// ModelName 配置的模型名称，按输入切换模型的提供方以AnalyzePaper返回的实际模型为准
+	ModelName() string
+	// AnalyzePaper 分析论文，返回结构化结果、实际使用的模型和token用量
+	AnalyzePaper(ctx context.Context, input *PaperInput) (*PaperAnalysis, Usage, error)
+	// Chat 多轮对话，返回模型回复文本和token用量
+	Chat(ctx context.Context, messages []ChatMessage) (string, Usage, error)
+	// Embed 计算文本向量，返回结果与texts一一对应
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// PaperInput 论文分析输入
+// PDF与Text至少提供一个，支持原生PDF的提供方优先使用PDF
+type PaperInput struct {
+	Text       string                 // 论文全文或主要内容
+	Pages      []string               // 可选按页拆分的正文，长文档模式优先按页切分
+	PDF        []byte                 // 论文PDF原文
+	FileName   string                 // 文件名，用于上传时的显示名称
+	Images     [][]byte               // 可选图片（如论文图表）
+	OnProgress UploadProgressCallback // 可选上传进度回调
+}
+
+// Usage 一次模型调用的实际情况
+// token数为提供方报告的用量，提供方未报告时为0，由调用方按EstimateTokens估算
+type Usage struct {
+	Model        string // 实际使用的模型，如千问上传PDF时使用长文档模型而不是配置的文本模型
+	InputTokens  int    // 输入token数，包括上传的PDF
+	OutputTokens int    // 输出token数，包括思考过程
+}
+
+// Reported 提供方是否报告了token用量
+func (u Usage) Reported() bool {
+	return u.InputTokens > 0 || u.OutputTokens > 0
+}
+
+// Add 累加另一次调用的token用量，多次调用（如输出不合法时重新生成）合计为一次
+func (u *Usage) Add(other Usage) {
+	if other.Model != "" {
+		u.Model = other.Model
+	}
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+}
+
+// APIError 模型服务接口返回的非2xx响应
+type APIError struct {
+	StatusCode int    // HTTP状态码
+	Message    string // 错误描述，包含提供方名称和响应中的错误信息
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// ChatMessage 对话消息
+type ChatMessage struct {
+	Role    string `json:"role"`    // 角色：system/user/assistant
+	Content string `json:"content"` // 消息内容
+}
+
+// ProviderConfig 创建模型服务提供方所需的配置
+type ProviderConfig struct {
+	Provider string // 提供方名称
+	APIKey   string
+	BaseURL  string // 为空时使用各提供方的官方地址
+	Model    string // 为空时使用各提供方的默认模型
+}
+
+// NewProvider 根据配置创建模型服务提供方
+func NewProvider(cfg ProviderConfig) (LLMProvider, error) {
+	switch cfg.Provider {
+	case ProviderGemini, "":
+		client := NewGeminiClient(cfg.APIKey)
+		if cfg.BaseURL != "" {
+			client.BaseURL = cfg.BaseURL
+		}
+		if cfg.Model != "" {
+			client.Model = cfg.Model
+		}
+		return client, nil
+	case ProviderQwen:
+		client := NewQwenClient(cfg.APIKey)
+		if cfg.BaseURL != "" {
+			client.BaseURL = cfg.BaseURL
+		}
+		if cfg.Model != "" {
+			client.Model = cfg.Model
+		}
+		return client, nil
+	case ProviderFake:
+		return NewFakeProvider(), nil
+	default:
+		return nil, fmt.Errorf("不支持的模型服务提供方: %s", cfg.Provider)
+	}
+}
@@ -1,11 +1,411 @@
 package aitools
 
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
 // 实现阿里千问模型调用
+// 通过DashScope的OpenAI兼容接口访问，作为无法访问Gemini时的备用提供方
+
+// 千问默认配置
+const (
+	DefaultQwenBaseURL        = "https://dashscope.aliyuncs.com/compatible-mode/v1"
+	DefaultQwenModel          = "qwen-plus"
+	DefaultQwenVisionModel    = "qwen-vl-plus" // 带图片输入时使用
+	DefaultQwenLongModel      = "qwen-long"    // 文本不可用、需上传PDF时使用，支持文件解析
+	DefaultQwenEmbeddingModel = "text-embedding-v3"
+)
 
 type QwenClient struct {
-	ApiKey string
+	ApiKey         string
+	BaseURL        string       // API地址，为空时使用DashScope官方地址，测试时可指向本地httptest服务
+	Model          string       // 文本模型名称
+	VisionModel    string       // 多模态模型名称
+	LongModel      string       // 长文档模型名称
+	EmbeddingModel string       // 向量模型名称
+	MaxAttempts    int          // 输出不合法时的最大调用次数，为0时使用DefaultMaxAnalysisAttempts
+	HTTPClient     *http.Client // 可选HTTP客户端，为空时使用http.DefaultClient
 }
 
 func NewQwenClient(apiKey string) *QwenClient {
-	return &QwenClient{ApiKey: apiKey}
+	return &QwenClient{
+		ApiKey:         apiKey,
+		BaseURL:        DefaultQwenBaseURL,
+		Model:          DefaultQwenModel,
+		VisionModel:    DefaultQwenVisionModel,
+		LongModel:      DefaultQwenLongModel,
+		EmbeddingModel: DefaultQwenEmbeddingModel,
+	}
+}
+
+// qwenMessage OpenAI兼容协议的对话消息，content为字符串或多模态内容数组
+type qwenMessage struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"`
+}
+
+// qwenContentPart 多模态内容片段
+type qwenContentPart struct {
+	Type     string        `json:"type"`
+	Text     string        `json:"text,omitempty"`
+	ImageURL *qwenImageURL `json:"image_url,omitempty"`
+}
+
+type qwenImageURL struct {
+	URL string `json:"url"`
+}
+
+// qwenChatRequest 对话补全请求
+type qwenChatRequest struct {
+	Model          string              `json:"model"`
+	Messages       []qwenMessage       `json:"messages"`
+	ResponseFormat *qwenResponseFormat `json:"response_format,omitempty"`
+	Stream         bool                `json:"stream,omitempty"`
+	StreamOptions  *qwenStreamOptions  `json:"stream_options,omitempty"`
+}
+
+// qwenStreamOptions 流式输出选项，include_usage为true时最后一段返回token用量
+type qwenStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type qwenResponseFormat struct {
+	Type string `json:"type"`
+}
+
+// qwenChatResponse 对话补全响应
+type qwenChatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage qwenUsage `json:"usage"`
+}
+
+// qwenUsage token用量
+type qwenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// qwenStreamChunk 流式对话补全的增量数据，开启include_usage时最后一段只有用量没有choices
+type qwenStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *qwenUsage `json:"usage"`
+}
+
+// qwenErrorResponse 错误响应
+type qwenErrorResponse struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Name 提供方名称
+func (q *QwenClient) Name() string {
+	return ProviderQwen
+}
+
+// ModelName 模型名称
+func (q *QwenClient) ModelName() string {
+	return orDefault(q.Model, DefaultQwenModel)
+}
+
+// ContextTokens 模型上下文长度，qwen-plus/qwen-max为128K，qwen-turbo为1M
+func (q *QwenClient) ContextTokens() int {
+	if strings.HasPrefix(q.ModelName(), "qwen-turbo") {
+		return 1000000
+	}
+	return 131072
+}
+
+// AnalyzePaper 分析论文，返回结构化结果和实际使用的模型
+// 本地提取的文本可用时按文本分析（带图片时使用多模态模型）；文本不可用（如扫描件、字体编码异常）时上传PDF原文，
+// 使用长文档模型解析，判断规则与Gemini相同，见UploadsPDF
+func (q *QwenClient) AnalyzePaper(ctx context.Context, input *PaperInput) (*PaperAnalysis, Usage, error) {
+	prompt := "请对论文内容进行多维度分析，并以如下JSON结构输出：\n" + paperAnalysisJsonSchema
+
+	var model string
+	var messages []qwenMessage
+	switch {
+	case UploadsPDF(input):
+		fileID, err := q.uploadFile(ctx, input.PDF, input.FileName, input.OnProgress)
+		if err != nil {
+			return nil, Usage{}, fmt.Errorf("上传论文到千问失败: %w", err)
+		}
+		defer func() {
+			cleanupCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			_ = q.deleteFile(cleanupCtx, fileID)
+		}()
+		model = orDefault(q.LongModel, DefaultQwenLongModel)
+		messages = []qwenMessage{
+			{Role: RoleSystem, Content: "fileid://" + fileID},
+			{Role: RoleUser, Content: prompt},
+		}
+	case input.Text != "" && len(input.Images) > 0:
+		model = orDefault(q.VisionModel, DefaultQwenVisionModel)
+		parts := []qwenContentPart{{Type: "text", Text: prompt + "\n论文内容：\n" + input.Text + "\n请结合图片信息分析。"}}
+		for _, img := range input.Images {
+			parts = append(parts, qwenContentPart{
+				Type:     "image_url",
+				ImageURL: &qwenImageURL{URL: "data:" + http.DetectContentType(img) + ";base64," + base64.StdEncoding.EncodeToString(img)},
+			})
+		}
+		messages = []qwenMessage{{Role: RoleUser, Content: parts}}
+	case input.Text != "":
+		model = q.ModelName()
+		messages = []qwenMessage{{Role: RoleUser, Content: prompt + "\n论文内容：\n" + input.Text}}
+	default:
+		return nil, Usage{}, fmt.Errorf("论文内容不能为空")
+	}
+
+	return generateAnalysis(ctx, q.MaxAttempts, func(ctx context.Context, feedback string) (string, Usage, error) {
+		reqMessages := messages
+		if feedback != "" {
+			reqMessages = append(append([]qwenMessage{}, messages...), qwenMessage{Role: RoleUser, Content: feedback})
+		}
+		return q.chat(ctx, qwenChatRequest{
+			Model:          model,
+			Messages:       reqMessages,
+			ResponseFormat: &qwenResponseFormat{Type: "json_object"},
+		})
+	})
+}
+
+// Chat 多轮对话
+func (q *QwenClient) Chat(ctx context.Context, messages []ChatMessage) (string, Usage, error) {
+	req := qwenChatRequest{Model: q.ModelName()}
+	for _, msg := range messages {
+		req.Messages = append(req.Messages, qwenMessage{Role: msg.Role, Content: msg.Content})
+	}
+	return q.chat(ctx, req)
+}
+
+// ChatStream 流式多轮对话，返回完整回复
+func (q *QwenClient) ChatStream(ctx context.Context, messages []ChatMessage, onDelta ChatDeltaFunc) (string, Usage, error) {
+	req := qwenChatRequest{Model: q.ModelName(), Stream: true, StreamOptions: &qwenStreamOptions{IncludeUsage: true}}
+	for _, msg := range messages {
+		req.Messages = append(req.Messages, qwenMessage{Role: msg.Role, Content: msg.Content})
+	}
+	usage := Usage{Model: req.Model}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", usage, err
+	}
+	resp, err := q.send(ctx, http.MethodPost, "/chat/completions", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", usage, err
+	}
+	defer resp.Body.Close()
+
+	// 响应为SSE格式，每行"data: {...}"，以"data: [DONE]"结束
+	var reply strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+		var chunk qwenStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return reply.String(), usage, fmt.Errorf("千问流式响应解析失败: %w", err)
+		}
+		if chunk.Usage != nil {
+			usage.InputTokens, usage.OutputTokens = chunk.Usage.PromptTokens, chunk.Usage.CompletionTokens
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+		delta := chunk.Choices[0].Delta.Content
+		reply.WriteString(delta)
+		if onDelta != nil {
+			if err := onDelta(delta); err != nil {
+				return reply.String(), usage, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return reply.String(), usage, fmt.Errorf("读取千问流式响应失败: %w", err)
+	}
+	return reply.String(), usage, nil
+}
+
+// Embed 计算文本向量
+func (q *QwenClient) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]any{
+		"model":           orDefault(q.EmbeddingModel, DefaultQwenEmbeddingModel),
+		"input":           texts,
+		"encoding_format": "float",
+	})
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := q.do(ctx, http.MethodPost, "/embeddings", "application/json", bytes.NewReader(body), &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("千问返回向量数量不匹配: 期望%d, 实际%d", len(texts), len(resp.Data))
+	}
+	vectors := make([][]float32, len(texts))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("千问返回向量序号越界: %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
+
+// chat 调用对话补全接口，返回第一条回复内容和token用量
+func (q *QwenClient) chat(ctx context.Context, req qwenChatRequest) (string, Usage, error) {
+	usage := Usage{Model: req.Model}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", usage, err
+	}
+	var resp qwenChatResponse
+	if err := q.do(ctx, http.MethodPost, "/chat/completions", "application/json", bytes.NewReader(body), &resp); err != nil {
+		return "", usage, err
+	}
+	usage.InputTokens, usage.OutputTokens = resp.Usage.PromptTokens, resp.Usage.CompletionTokens
+	if len(resp.Choices) == 0 {
+		return "", usage, fmt.Errorf("千问无返回内容")
+	}
+	return resp.Choices[0].Message.Content, usage, nil
+}
+
+// uploadFile 上传文件用于长文档解析，返回文件ID
+func (q *QwenClient) uploadFile(ctx context.Context, data []byte, fileName string, onProgress UploadProgressCallback) (string, error) {
+	if fileName == "" {
+		fileName = "paper.pdf"
+	}
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	if err := w.WriteField("purpose", "file-extract"); err != nil {
+		return "", err
+	}
+	part, err := w.CreateFormFile("file", fileName)
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	total := int64(len(data))
+	var resp struct {
+		ID string `json:"id"`
+	}
+	if err := q.do(ctx, http.MethodPost, "/files", w.FormDataContentType(), &buf, &resp); err != nil {
+		return "", err
+	}
+	if resp.ID == "" {
+		return "", fmt.Errorf("千问未返回文件ID")
+	}
+	if onProgress != nil && !onProgress(total, total) {
+		_ = q.deleteFile(context.Background(), resp.ID)
+		return "", fmt.Errorf("上传被中断")
+	}
+	return resp.ID, nil
+}
+
+// deleteFile 删除上传的文件
+func (q *QwenClient) deleteFile(ctx context.Context, fileID string) error {
+	return q.do(ctx, http.MethodDelete, "/files/"+fileID, "", nil, nil)
+}
+
+// do 发送请求并解析JSON响应，out为nil时忽略响应内容
+func (q *QwenClient) do(ctx context.Context, method, path, contentType string, body io.Reader, out any) error {
+	resp, err := q.send(ctx, method, path, contentType, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取千问响应失败: %w", err)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("千问响应解析失败: %w", err)
+	}
+	return nil
+}
+
+// send 发送请求，状态码非2xx时读取错误信息并返回错误；成功时由调用方关闭响应体
+func (q *QwenClient) send(ctx context.Context, method, path, contentType string, body io.Reader) (*http.Response, error) {
+	base := strings.TrimSuffix(orDefault(q.BaseURL, DefaultQwenBaseURL), "/")
+	req, err := http.NewRequestWithContext(ctx, method, base+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+q.ApiKey)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	client := q.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取千问响应失败: %w", err)
+	}
+	var apiErr qwenErrorResponse
+	if json.Unmarshal(respBody, &apiErr) == nil && apiErr.Error.Message != "" {
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("千问接口错误: status=%d, code=%s, message=%s", resp.StatusCode, apiErr.Error.Code, apiErr.Error.Message)}
+	}
+	return nil, &APIError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("千问接口错误: status=%d, body=%s", resp.StatusCode, string(respBody))}
+}
+
+// orDefault value为空时返回默认值
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
 }
@@ -0,0 +1,232 @@
+package aitools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// qwenTestServer 模拟DashScope兼容接口，按顺序返回预设的对话回复并记录收到的请求
+type qwenTestServer struct {
+	t       *testing.T
+	mu      sync.Mutex
+	replies []string          // 依次返回的对话回复内容
+	chats   []qwenChatRequest // 收到的对话请求
+	uploads []string          // 上传文件的purpose
+	deleted []string          // 删除的文件ID
+}
+
+func newQwenTestServer(t *testing.T, replies ...string) (*qwenTestServer, *QwenClient) {
+	t.Helper()
+	s := &qwenTestServer{t: t, replies: replies}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	client := NewQwenClient("test-key")
+	client.BaseURL = srv.URL
+	client.HTTPClient = srv.Client()
+	return s, client
+}
+
+func (s *qwenTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer test-key" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/chat/completions":
+		var req qwenChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.t.Errorf("解析对话请求失败: %v", err)
+		}
+		s.chats = append(s.chats, req)
+		if len(s.replies) == 0 {
+			s.t.Errorf("对话请求次数超出预期")
+			http.Error(w, "no reply", http.StatusInternalServerError)
+			return
+		}
+		reply := s.replies[0]
+		s.replies = s.replies[1:]
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []any{map[string]any{"message": map[string]any{"content": reply}}},
+			"usage":   map[string]any{"prompt_tokens": 100, "completion_tokens": 20},
+		})
+	case r.Method == http.MethodPost && r.URL.Path == "/files":
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			s.t.Errorf("解析上传请求失败: %v", err)
+		}
+		s.uploads = append(s.uploads, r.FormValue("purpose"))
+		fmt.Fprint(w, `{"id":"file-1"}`)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/files/"):
+		s.deleted = append(s.deleted, strings.TrimPrefix(r.URL.Path, "/files/"))
+		fmt.Fprint(w, `{"deleted":true}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// validAnalysisJSON 返回能通过校验的分析结果JSON
+func validAnalysisJSON(t *testing.T) string {
+	t.Helper()
+	analysis, _, err := NewFakeProvider().AnalyzePaper(context.Background(), &PaperInput{FileName: "paper.pdf"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(analysis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestQwenAnalyzePaperParsesCompletion(t *testing.T) {
+	raw := validAnalysisJSON(t)
+	srv, client := newQwenTestServer(t, raw)
+
+	analysis, usage, err := client.AnalyzePaper(context.Background(), &PaperInput{Text: "论文正文"})
+	if err != nil {
+		t.Fatalf("分析失败: %v", err)
+	}
+	if analysis.BasicInfo.Title != "paper" {
+		t.Errorf("标题解析错误: %q", analysis.BasicInfo.Title)
+	}
+	if usage.Model != DefaultQwenModel || usage.InputTokens != 100 || usage.OutputTokens != 20 {
+		t.Errorf("token用量错误: %+v", usage)
+	}
+	req := srv.chats[0]
+	if req.Model != DefaultQwenModel || req.ResponseFormat == nil || req.ResponseFormat.Type != "json_object" {
+		t.Errorf("请求参数错误: model=%s, response_format=%+v", req.Model, req.ResponseFormat)
+	}
+}
+
+func TestQwenAnalyzePaperRepairsAndReprompts(t *testing.T) {
+	raw := validAnalysisJSON(t)
+	invalid := strings.Replace(raw, `"rating":4`, `"rating":"10"`, 1)
+	fenced := "以下是分析结果：\n```json\n" + strings.TrimSuffix(raw, "}") + ",}\n```"
+	srv, client := newQwenTestServer(t, "```json\n"+invalid+"\n```", fenced)
+
+	analysis, usage, err := client.AnalyzePaper(context.Background(), &PaperInput{Text: "论文正文"})
+	if err != nil {
+		t.Fatalf("分析失败: %v", err)
+	}
+	if err := analysis.Validate(); err != nil {
+		t.Errorf("修复后的结果应通过校验: %v", err)
+	}
+	if len(srv.chats) != 2 {
+		t.Fatalf("评分不合法时应重新生成一次，实际调用%d次", len(srv.chats))
+	}
+	retry := srv.chats[1].Messages
+	if len(retry) != 2 || retry[1].Role != RoleUser || !strings.Contains(fmt.Sprint(retry[1].Content), "rating") {
+		t.Errorf("重新生成时应附带校验问题，实际消息为%+v", retry)
+	}
+	if usage.InputTokens != 200 || usage.OutputTokens != 40 {
+		t.Errorf("token用量应为两次调用之和: %+v", usage)
+	}
+}
+
+func TestQwenAnalyzePaperUploadsAndDeletesPDF(t *testing.T) {
+	srv, client := newQwenTestServer(t, validAnalysisJSON(t))
+
+	_, usage, err := client.AnalyzePaper(context.Background(), &PaperInput{PDF: []byte("%PDF-1.4"), FileName: "paper.pdf"})
+	if err != nil {
+		t.Fatalf("分析失败: %v", err)
+	}
+	if len(srv.uploads) != 1 || srv.uploads[0] != "file-extract" {
+		t.Errorf("应以file-extract上传一次文件，实际为%v", srv.uploads)
+	}
+	if len(srv.deleted) != 1 || srv.deleted[0] != "file-1" {
+		t.Errorf("分析结束后应删除上传的文件，实际为%v", srv.deleted)
+	}
+	req := srv.chats[0]
+	if req.Model != DefaultQwenLongModel || usage.Model != DefaultQwenLongModel {
+		t.Errorf("仅有PDF时应使用长文档模型，实际为%s", req.Model)
+	}
+	if len(req.Messages) == 0 || req.Messages[0].Role != RoleSystem || req.Messages[0].Content != "fileid://file-1" {
+		t.Errorf("应以system消息引用上传的文件，实际为%+v", req.Messages)
+	}
+}
+
+func TestQwenAnalyzePaperChoosesTextOrPDF(t *testing.T) {
+	usable := strings.Repeat("This paper studies graph neural networks. ", 20)
+	tests := []struct {
+		name   string
+		text   string
+		upload bool
+	}{
+		{"文本可用", usable, false},
+		{"文本过短", "Abstract", true},
+		{"文本乱码", strings.Repeat("\uFFFD\uE000", 400), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, client := newQwenTestServer(t, validAnalysisJSON(t))
+			_, usage, err := client.AnalyzePaper(context.Background(), &PaperInput{Text: tt.text, PDF: []byte("%PDF-1.4"), FileName: "paper.pdf"})
+			if err != nil {
+				t.Fatalf("分析失败: %v", err)
+			}
+			if uploaded := len(srv.uploads) == 1; uploaded != tt.upload {
+				t.Fatalf("期望上传PDF=%v，实际上传为%v", tt.upload, srv.uploads)
+			}
+			wantModel := DefaultQwenModel
+			if tt.upload {
+				wantModel = DefaultQwenLongModel
+			}
+			if usage.Model != wantModel || srv.chats[0].Model != wantModel {
+				t.Errorf("期望使用%s，实际为%s", wantModel, srv.chats[0].Model)
+			}
+		})
+	}
+}
+
+func TestQwenErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"error":{"code":"Throttling","message":"rate limited"}}`)
+	}))
+	defer srv.Close()
+	client := NewQwenClient("test-key")
+	client.BaseURL = srv.URL
+
+	_, _, err := client.Chat(context.Background(), []ChatMessage{{Role: RoleUser, Content: "你好"}})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("期望APIError，实际为%v", err)
+	}
+	if apiErr.StatusCode != http.StatusTooManyRequests || !strings.Contains(apiErr.Error(), "Throttling") {
+		t.Errorf("错误信息不完整: status=%d, message=%s", apiErr.StatusCode, apiErr.Error())
+	}
+	if !unavailable(err) {
+		t.Error("429应视为服务不可用")
+	}
+}
+
+func TestQwenEmbedKeepsInputOrder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 按与输入相反的顺序返回，客户端应按index归位
+		fmt.Fprint(w, `{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`)
+	}))
+	defer srv.Close()
+	client := NewQwenClient("test-key")
+	client.BaseURL = srv.URL
+
+	vectors, err := client.Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("计算向量失败: %v", err)
+	}
+	if len(vectors) != 2 || vectors[0][0] != 1 || vectors[1][1] != 1 {
+		t.Errorf("向量顺序应与输入一致，实际为%v", vectors)
+	}
+
+	if _, err := client.Embed(context.Background(), []string{"a"}); err == nil {
+		t.Error("返回向量数量与输入不一致时应报错")
+	}
+}
@@ -0,0 +1,358 @@
+package aitools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// DefaultMaxAnalysisAttempts 模型输出不合法时的默认最大调用次数（含首次）
+const DefaultMaxAnalysisAttempts = 3
+
+// 评分取值范围
+const (
+	MinRating = 1
+	MaxRating = 5
+)
+
+// ValidationError 模型输出校验失败
+type ValidationError struct {
+	Problems []string // 具体问题，会反馈给模型用于修正
+}
+
+func (e *ValidationError) Error() string {
+	return "分析结果校验失败: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate 校验分析结果：必填字段不能为空，评分必须在1-5之间
+func (a *PaperAnalysis) Validate() error {
+	var problems []string
+	required := func(path, value string) {
+		if strings.TrimSpace(value) == "" {
+			problems = append(problems, path+"不能为空")
+		}
+	}
+	required("basicInfo.title", a.BasicInfo.Title)
+	required("summary.purpose", a.Summary.Purpose)
+	required("summary.methods", a.Summary.Methods)
+	required("summary.keyFindings", a.Summary.KeyFindings)
+	required("summary.conclusion", a.Summary.Conclusion)
+
+	for _, r := range a.Ratings() {
+		if r.Rating < MinRating || r.Rating > MaxRating {
+			problems = append(problems, fmt.Sprintf("contentQuality.%s.rating必须是%d-%d之间的整数，当前为%d", r.Key, MinRating, MaxRating, r.Rating))
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// RatingItem 单个内容质量维度的评分
+type RatingItem struct {
+	Key    string // contentQuality下的字段名
+	Rating int
+}
+
+// Ratings 按固定顺序返回七个内容质量维度的评分
+func (a *PaperAnalysis) Ratings() []RatingItem {
+	q := a.ContentQuality
+	return []RatingItem{
+		{"researchQuestionImportance", q.ResearchQuestionImportance.Rating},
+		{"innovation", q.Innovation.Rating},
+		{"methodologyRigor", q.MethodologyRigor.Rating},
+		{"resultsValidityReproducibility", q.ResultsValidityReproducibility.Rating},
+		{"dataAnalysisDepthBreadth", q.DataAnalysisDepthBreadth.Rating},
+		{"practicalApplicationValue", q.PracticalApplicationValue.Rating},
+		{"futureResearchInspiration", q.FutureResearchInspiration.Rating},
+	}
+}
+
+// ParsePaperAnalysis 解析并校验模型输出
+// 会去掉markdown代码块、截取JSON主体并修复常见格式问题
+func ParsePaperAnalysis(raw string) (*PaperAnalysis, error) {
+	var result PaperAnalysis
+	if err := decodeRepaired(raw, &result); err != nil {
+		return nil, err
+	}
+	if err := result.Validate(); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// fencedBlockPattern 匹配markdown代码块的内容，代码块前后可能带有说明文字，输出被截断时可能没有结束标记
+var fencedBlockPattern = regexp.MustCompile("(?s)```[\\w-]*[ \\t]*\\r?\\n(.*?)(?:```|$)")
+
+// ratingValuePattern 可转换为整数评分的值，如"4"、"10"、4.0
+var ratingValuePattern = regexp.MustCompile(`^\d+(?:\.\d+)?$`)
+
+// decodeRepaired 修复模型输出后解码到out，写成字符串或小数的评分转换为整数
+// 失败时返回ValidationError，供重新生成时反馈给模型
+func decodeRepaired(raw string, out any) error {
+	text := RepairJSON(raw)
+	if text == "" {
+		return &ValidationError{Problems: []string{"输出中没有找到JSON对象"}}
+	}
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return &ValidationError{Problems: []string{"JSON格式错误: " + err.Error()}}
+	}
+	data, err := json.Marshal(normalizeRatings(value))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return &ValidationError{Problems: []string{"JSON格式错误: " + err.Error()}}
+	}
+	return nil
+}
+
+// normalizeRatings 把键名为rating、写成字符串或小数的值转换为整数，如"rating": "4"、"rating": 4.0
+// 在解码后的值上处理，字符串内容中出现的"rating"不受影响；小数部分直接舍去，超出范围的评分由Validate报告
+func normalizeRatings(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if key == "rating" {
+				if n, ok := ratingNumber(item); ok {
+					v[key] = n
+					continue
+				}
+			}
+			v[key] = normalizeRatings(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeRatings(item)
+		}
+	}
+	return value
+}
+
+// ratingNumber 把评分值转换为整数，无法转换时返回false，保留原值由解码报错
+func ratingNumber(value any) (json.Number, bool) {
+	var text string
+	switch v := value.(type) {
+	case json.Number:
+		text = v.String()
+	case string:
+		text = strings.TrimSpace(v)
+	default:
+		return "", false
+	}
+	if !ratingValuePattern.MatchString(text) {
+		return "", false
+	}
+	integer, _, _ := strings.Cut(text, ".")
+	n, err := strconv.Atoi(integer)
+	if err != nil {
+		return "", false
+	}
+	return json.Number(strconv.Itoa(n)), true
+}
+
+// RepairJSON 修复模型输出中常见的JSON问题
+// 包括markdown代码块、前后多余文字、尾随逗号、字符串内未转义的换行、输出被截断导致的括号不闭合
+func RepairJSON(raw string) string {
+	text := strings.TrimSpace(strings.TrimPrefix(raw, "\ufeff"))
+
+	// 去掉markdown代码块，代码块不一定在输出开头
+	if m := fencedBlockPattern.FindStringSubmatch(text); m != nil && strings.Contains(m[1], "{") {
+		text = m[1]
+	}
+
+	// 截取第一个{到最后一个}之间的内容
+	start := strings.Index(text, "{")
+	if start < 0 {
+		return ""
+	}
+	text = text[start:]
+	if end := strings.LastIndex(text, "}"); end >= 0 && balanced(text[:end+1]) {
+		text = text[:end+1]
+	}
+
+	var b strings.Builder
+	var stack []byte
+	inString, escaped := false, false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			case c == '\n':
+				b.WriteString(`\n`)
+				continue
+			case c == '\r':
+				continue
+			case c == '\t':
+				b.WriteString(`\t`)
+				continue
+			}
+			b.WriteByte(c)
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{':
+			stack = append(stack, '}')
+		case '[':
+			stack = append(stack, ']')
+		case '}', ']':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			trimTrailingComma(&b)
+		}
+		b.WriteByte(c)
+	}
+
+	// 补全被截断的字符串和括号
+	if inString {
+		b.WriteByte('"')
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		trimTrailingComma(&b)
+		b.WriteByte(stack[i])
+	}
+	return b.String()
+}
+
+// balanced 判断括号是否完整闭合（忽略字符串内容）
+func balanced(text string) bool {
+	depth := 0
+	inString, escaped := false, false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+		}
+	}
+	return depth == 0 && !inString
+}
+
+// trimTrailingComma 去掉已写入内容末尾的逗号（忽略空白）
+func trimTrailingComma(b *strings.Builder) {
+	s := b.String()
+	trimmed := strings.TrimRight(s, " \t\r\n")
+	if strings.HasSuffix(trimmed, ",") {
+		b.Reset()
+		b.WriteString(trimmed[:len(trimmed)-1])
+	}
+}
+
+// generateFunc 调用一次模型，feedback非空时需附加到prompt中要求模型修正
+type generateFunc func(ctx context.Context, feedback string) (string, Usage, error)
+
+// generateAnalysis 调用模型并校验输出，不合法时把问题反馈给模型重新生成
+// 网络或接口错误直接返回，不在此处重试
+func generateAnalysis(ctx context.Context, maxAttempts int, generate generateFunc) (*PaperAnalysis, Usage, error) {
+	return generateStructured(ctx, maxAttempts, generate, ParsePaperAnalysis)
+}
+
+// generateStructured 调用模型并用parse解析校验输出，返回ValidationError时把问题反馈给模型重新生成
+// 返回的token用量为全部调用之和
+func generateStructured[T any](ctx context.Context, maxAttempts int, generate generateFunc, parse func(raw string) (T, error)) (T, Usage, error) {
+	var zero T
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAnalysisAttempts
+	}
+	var usage Usage
+	var feedback string
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		raw, used, err := generate(ctx, feedback)
+		usage.Add(used)
+		if err != nil {
+			return zero, usage, err
+		}
+		result, err := parse(raw)
+		if err == nil {
+			return result, usage, nil
+		}
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			return zero, usage, err
+		}
+		lastErr = err
+		feedback = retryFeedback(raw, verr)
+	}
+	return zero, usage, fmt.Errorf("模型输出%d次均不合法: %w", maxAttempts, lastErr)
+}
+
+// maxFeedbackOutputLen 反馈给模型的上次输出最大长度
+const maxFeedbackOutputLen = 4000
+
+// retryFeedback 构造修正提示
+func retryFeedback(raw string, verr *ValidationError) string {
+	if len(raw) > maxFeedbackOutputLen {
+		raw = raw[:maxFeedbackOutputLen] + "..."
+	}
+	return "你上一次的输出不符合要求：\n- " + strings.Join(verr.Problems, "\n- ") +
+		"\n上一次的输出如下：\n" + raw +
+		"\n请修正上述问题，只输出完整的JSON对象，不要包含markdown代码块或其他文字。"
+}
+
+// paperAnalysisSchema 由PaperAnalysis结构生成的Gemini响应结构定义
+var paperAnalysisSchema = schemaFor(reflect.TypeOf(PaperAnalysis{}))
+
+// schemaFor 根据json标签把Go类型转换为genai.Schema，所有字段均为必填，评分限制在1-5
+func schemaFor(t reflect.Type) *genai.Schema {
+	switch t.Kind() {
+	case reflect.Struct:
+		s := &genai.Schema{Type: genai.TypeObject, Properties: map[string]*genai.Schema{}}
+		for i := 0; i < t.NumField(); i++ {
+			name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			prop := schemaFor(t.Field(i).Type)
+			if name == "rating" {
+				prop.Minimum = genai.Ptr(float64(MinRating))
+				prop.Maximum = genai.Ptr(float64(MaxRating))
+			}
+			s.Properties[name] = prop
+			s.Required = append(s.Required, name)
+			s.PropertyOrdering = append(s.PropertyOrdering, name)
+		}
+		return s
+	case reflect.Slice:
+		return &genai.Schema{Type: genai.TypeArray, Items: schemaFor(t.Elem())}
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		return &genai.Schema{Type: genai.TypeInteger}
+	default:
+		return &genai.Schema{Type: genai.TypeString}
+	}
+}
@@ -0,0 +1,118 @@
+package aitools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// ratingDoc 用于测试修复和评分转换的最小结构
+type ratingDoc struct {
+	Note   string `json:"note"`
+	Rating int    `json:"rating"`
+	Items  []struct {
+		Rating int `json:"rating"`
+	} `json:"items"`
+}
+
+func TestDecodeRepaired(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		wantNote   string
+		wantRating int
+		wantItem   int // items[0].rating，没有items时为0
+		wantErr    bool
+	}{
+		{name: "合法JSON", raw: `{"note":"a","rating":4}`, wantNote: "a", wantRating: 4},
+		{name: "字符串评分", raw: `{"note":"a","rating": "4"}`, wantNote: "a", wantRating: 4},
+		{name: "两位数字符串评分", raw: `{"note":"a","rating": "10"}`, wantNote: "a", wantRating: 10},
+		{name: "小数评分", raw: `{"rating": 4.0}`, wantRating: 4},
+		{name: "字符串小数评分", raw: `{"rating": " 3.5 "}`, wantRating: 3},
+		{name: "嵌套数组中的评分", raw: `{"items":[{"rating":"5"}],"rating":1}`, wantRating: 1, wantItem: 5},
+		{
+			name:       "字符串内容中的rating不改写",
+			raw:        `{"note":"示例：\"rating\": \"4\"，rating: 4.0","rating":2}`,
+			wantNote:   `示例："rating": "4"，rating: 4.0`,
+			wantRating: 2,
+		},
+		{name: "开头的代码块", raw: "```json\n{\"note\":\"a\",\"rating\":3}\n```", wantNote: "a", wantRating: 3},
+		{
+			name:       "说明文字之后的代码块",
+			raw:        "以下是分析结果：\n```json\n{\"note\":\"a\",\"rating\":3,}\n```\n如需调整格式{例如缩进}请告诉我。",
+			wantNote:   "a",
+			wantRating: 3,
+		},
+		{name: "未闭合的代码块和被截断的输出", raw: "```json\n{\"rating\":2,\"note\":\"ab", wantNote: "ab", wantRating: 2},
+		{name: "字符串内未转义的换行", raw: "{\"note\":\"第一行\n第二行\",\"rating\":1}", wantNote: "第一行\n第二行", wantRating: 1},
+		{name: "无法转换的评分", raw: `{"rating":"high"}`, wantErr: true},
+		{name: "没有JSON", raw: "抱歉，无法分析该论文。", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc ratingDoc
+			err := decodeRepaired(tt.raw, &doc)
+			if tt.wantErr {
+				var verr *ValidationError
+				if !errors.As(err, &verr) {
+					t.Fatalf("期望ValidationError，实际为%v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("解码失败: %v", err)
+			}
+			if doc.Note != tt.wantNote || doc.Rating != tt.wantRating {
+				t.Errorf("note=%q rating=%d，期望note=%q rating=%d", doc.Note, doc.Rating, tt.wantNote, tt.wantRating)
+			}
+			item := 0
+			if len(doc.Items) > 0 {
+				item = doc.Items[0].Rating
+			}
+			if item != tt.wantItem {
+				t.Errorf("items[0].rating=%d，期望%d", item, tt.wantItem)
+			}
+		})
+	}
+}
+
+func TestRepairJSONOutputIsValid(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{name: "尾随逗号", raw: `{"a":[1,2,],"b":{"c":1,},}`},
+		{name: "截断的数组和对象", raw: `{"a":[{"b":"c"`},
+		{name: "代码块中的字符串评分", raw: "结果如下\n```\n{\"rating\": \"10\"}\n```"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RepairJSON(tt.raw); !json.Valid([]byte(got)) {
+				t.Errorf("修复结果不是合法JSON: %s", got)
+			}
+		})
+	}
+}
+
+func TestParsePaperAnalysisRatingOutOfRange(t *testing.T) {
+	analysis, _, err := NewFakeProvider().AnalyzePaper(context.Background(), &PaperInput{FileName: "paper.pdf"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(analysis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := strings.Replace(string(data), `"rating":4`, `"rating":"10"`, 1)
+
+	_, err = ParsePaperAnalysis(raw)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("期望ValidationError，实际为%v", err)
+	}
+	if len(verr.Problems) != 1 || !strings.Contains(verr.Problems[0], "rating") || !strings.Contains(verr.Problems[0], "10") {
+		t.Errorf("应只报告评分超出范围，实际为%v", verr.Problems)
+	}
+}
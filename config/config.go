@@ -3,133 +3,322 @@ package config
 import (
 	"fmt"
 	"papergraph/model"
+	"time"
 
 	"go.uber.org/zap"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
-// DB 全局数据库连接
-var DB *gorm.DB
+// 运行环境
+const (
+	ProfileDev  = "dev"  // 本地开发
+	ProfileTest = "test" // 测试和CI
+	ProfileProd = "prod" // 生产环境
+)
+
+// Config 应用配置
+// 按默认值、配置文件（YAML/TOML）、profile配置文件、环境变量的顺序加载，后者覆盖前者，见Load。
+// 字段的yaml标签为配置文件中的键名，env标签为覆盖该字段的环境变量，secret标签标记的字段不会出现在日志中
+type Config struct {
+	Profile        string               `yaml:"-"` // 运行环境：dev/test/prod，由Load的参数或环境变量APP_PROFILE决定
+	Server         ServerConfig         `yaml:"server"`
+	Log            LogConfig            `yaml:"log"`
+	Database       DatabaseConfig       `yaml:"database"`
+	MySQL          MySQLConfig          `yaml:"mysql"`
+	JWT            JWTConfig            `yaml:"jwt"`
+	GoogleOAuth    GoogleOAuthConfig    `yaml:"google_oauth"`
+	Gmail          GmailConfig          `yaml:"gmail"`
+	LLM            LLMConfig            `yaml:"llm"`
+	Gemini         GeminiConfig         `yaml:"gemini"`
+	Qwen           QwenConfig           `yaml:"qwen"`
+	AnalysisWorker AnalysisWorkerConfig `yaml:"analysis_worker"`
+	Chat           ChatConfig           `yaml:"chat"`
+	Import         ImportConfig         `yaml:"import"`
+	Batch          BatchConfig          `yaml:"batch"`
+	Storage        StorageConfig        `yaml:"storage"`
+	UploadTicket   UploadTicketConfig   `yaml:"upload_ticket"`
+	StorageGC      StorageGCConfig      `yaml:"storage_gc"`
+
+	warnings []string
+}
+
+// ServerConfig HTTP服务配置
+type ServerConfig struct {
+	Addr              string        `yaml:"addr" env:"SERVER_ADDR"`                         // 监听地址，如:8080
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`                            // 读取请求头的超时时间
+	ReadTimeout       time.Duration `yaml:"read_timeout"`                                   // 读取整个请求（含上传文件）的超时时间
+	WriteTimeout      time.Duration `yaml:"write_timeout"`                                  // 写响应的超时时间，SSE流式响应不受限制
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`                                   // keep-alive连接的空闲超时时间
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"` // 收到退出信号后等待请求和分析任务完成的最长时间，超时未完成的任务重新排队
+}
 
-// Logger 全局日志实例
-var Logger *zap.Logger
+// LogConfig 日志配置
+type LogConfig struct {
+	Development bool `yaml:"development" env:"LOG_DEVELOPMENT"` // 是否使用开发模式日志（彩色、可读格式、Debug级别）
+}
 
-// 免费试用次数配置
-var FreeTrialCount int = 3 // 可根据需要调整
+// DatabaseConfig 数据库配置
+type DatabaseConfig struct {
+	Driver      string `yaml:"driver" env:"DB_DRIVER"`             // 数据库类型：mysql/sqlite
+	SQLitePath  string `yaml:"sqlite_path" env:"SQLITE_PATH"`      // SQLite数据库文件路径，":memory:"为内存数据库
+	AutoMigrate bool   `yaml:"auto_migrate" env:"DB_AUTO_MIGRATE"` // 启动时自动执行未执行的迁移，prod环境默认关闭，需先执行migrate up
+}
 
 // MySQLConfig MySQL数据库配置
 type MySQLConfig struct {
-	Host     string
-	Port     int
-	User     string
-	Password string
-	DBName   string
-	Charset  string
+	Host     string `yaml:"host" env:"MYSQL_HOST"`
+	Port     int    `yaml:"port" env:"MYSQL_PORT"`
+	User     string `yaml:"user" env:"MYSQL_USER"`
+	Password string `yaml:"password" env:"MYSQL_PASSWORD" secret:"true"`
+	DBName   string `yaml:"db_name" env:"MYSQL_DATABASE"`
+	Charset  string `yaml:"charset" env:"MYSQL_CHARSET"`
+}
+
+// DSN 数据库连接串
+func (c MySQLConfig) DSN() string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=True&loc=Local",
+		c.User, c.Password, c.Host, c.Port, c.DBName, c.Charset)
+}
+
+// JWTConfig 登录令牌配置
+type JWTConfig struct {
+	Secret string `yaml:"secret" env:"JWT_SECRET" secret:"true"` // 签名密钥，dev/test环境为空时每次启动随机生成
 }
 
 // GoogleOAuthConfig Google OAuth相关配置
 type GoogleOAuthConfig struct {
-	ClientID     string
-	ClientSecret string
-	RedirectURL  string
+	ClientID     string `yaml:"client_id" env:"GOOGLE_CLIENT_ID"`
+	ClientSecret string `yaml:"client_secret" env:"GOOGLE_CLIENT_SECRET" secret:"true"`
+	RedirectURL  string `yaml:"redirect_url" env:"GOOGLE_REDIRECT_URL"`
 }
 
 // GmailConfig Gmail API配置
 type GmailConfig struct {
-	ClientID     string
-	ClientSecret string
-	RedirectURL  string
-	Scopes       []string
+	ClientID     string   `yaml:"client_id" env:"GMAIL_CLIENT_ID"`
+	ClientSecret string   `yaml:"client_secret" env:"GMAIL_CLIENT_SECRET" secret:"true"`
+	RedirectURL  string   `yaml:"redirect_url" env:"GMAIL_REDIRECT_URL"`
+	Scopes       []string `yaml:"scopes" env:"GMAIL_SCOPES"` // 环境变量中以逗号分隔
 }
 
-// 全局Gmail配置
-var GmailConf = GmailConfig{
-	ClientID:     "your-gmail-client-id.googleusercontent.com",
-	ClientSecret: "your-gmail-client-secret",
-	RedirectURL:  "http://localhost:8080/auth/gmail/callback",
-	Scopes: []string{
-		"https://www.googleapis.com/auth/gmail.readonly",
-		"https://www.googleapis.com/auth/userinfo.email",
-		"https://www.googleapis.com/auth/userinfo.profile",
-	},
+// LLMConfig 大模型服务配置
+type LLMConfig struct {
+	Provider                string        `yaml:"provider" env:"LLM_PROVIDER"` // 模型服务提供方：gemini/qwen/fake，本地开发和测试可使用fake，无需API Key
+	CircuitFailureThreshold int           `yaml:"circuit_failure_threshold"`   // 连续失败多少次后熔断，熔断期间调用直接失败
+	CircuitCooldown         time.Duration `yaml:"circuit_cooldown"`            // 熔断后的冷却时间，冷却结束后放行一个试探请求
 }
 
-// Init 初始化配置和数据库
-func Init() {
-	fmt.Println("配置初始化完成")
+// GeminiConfig Gemini API配置
+type GeminiConfig struct {
+	APIKey  string `yaml:"api_key" env:"GEMINI_API_KEY" secret:"true"`
+	BaseURL string `yaml:"base_url" env:"GEMINI_BASE_URL"` // 为空时使用官方地址
+	Model   string `yaml:"model" env:"GEMINI_MODEL"`
+}
 
-	// 初始化日志
-	var err error
-	Logger, err = zap.NewDevelopment()
-	if err != nil {
-		panic("日志初始化失败: " + err.Error())
+// QwenConfig 千问（DashScope）API配置
+type QwenConfig struct {
+	APIKey  string `yaml:"api_key" env:"DASHSCOPE_API_KEY" secret:"true"`
+	BaseURL string `yaml:"base_url" env:"QWEN_BASE_URL"` // 为空时使用DashScope官方地址
+	Model   string `yaml:"model" env:"QWEN_MODEL"`
+}
+
+// AnalysisWorkerConfig 分析任务工作池配置
+type AnalysisWorkerConfig struct {
+	Concurrency       int           `yaml:"concurrency" env:"ANALYSIS_WORKER_CONCURRENCY"` // 并发执行的worker数量
+	MaxAttempts       int           `yaml:"max_attempts"`                                  // 单个任务最大执行次数
+	PollInterval      time.Duration `yaml:"poll_interval"`                                 // 空闲时轮询数据库的间隔
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`                            // 执行中任务的心跳间隔
+	StaleAfter        time.Duration `yaml:"stale_after"`                                   // 心跳超时阈值，超时的任务视为执行实例已退出并重新排队
+	RetryBackoff      time.Duration `yaml:"retry_backoff"`                                 // 失败重试的基础退避时间，按已执行次数线性增长
+	TaskTimeout       time.Duration `yaml:"task_timeout"`                                  // 单次执行超时时间
+}
+
+// ChatConfig 论文问答配置
+type ChatConfig struct {
+	FreeDailyMessages       int `yaml:"free_daily_messages"`       // 未订阅用户每天可提问次数
+	SubscriberDailyMessages int `yaml:"subscriber_daily_messages"` // 订阅用户每天可提问次数
+	HistoryMessages         int `yaml:"history_messages"`          // 每次提问携带的历史消息条数
+	MaxQuestionRunes        int `yaml:"max_question_runes"`        // 单个问题最大字符数
+}
+
+// ImportConfig 按链接、arXiv ID或DOI导入论文的配置
+// 解析服务地址可配置为本地测试桩，配置的地址视为可信地址，不做内网地址校验
+type ImportConfig struct {
+	ArxivPDFURL    string        `yaml:"arxiv_pdf_url" env:"ARXIV_PDF_URL"`       // arXiv PDF下载地址模板，%s为arXiv ID
+	UnpaywallURL   string        `yaml:"unpaywall_url" env:"UNPAYWALL_URL"`       // Unpaywall API地址，用于查找DOI对应的开放获取PDF
+	UnpaywallEmail string        `yaml:"unpaywall_email" env:"UNPAYWALL_EMAIL"`   // 调用Unpaywall需要的联系邮箱，为空时只通过DOI解析地址查找
+	DOIResolverURL string        `yaml:"doi_resolver_url" env:"DOI_RESOLVER_URL"` // DOI解析地址，DOI拼接在其后
+	Timeout        time.Duration `yaml:"timeout"`                                 // 单次导入的下载超时时间
+	MaxRedirects   int           `yaml:"max_redirects"`                           // 最多跟随的重定向次数
+}
+
+// BatchConfig 批量上传配置
+type BatchConfig struct {
+	MaxFiles       int   `yaml:"max_files"`        // 单个批次最多包含的PDF文件数
+	MaxUploadBytes int64 `yaml:"max_upload_bytes"` // 单次请求上传的文件总大小上限（zip按压缩后大小计算）
+}
+
+// StorageConfig 对象存储配置
+type StorageConfig struct {
+	Driver string `yaml:"driver" env:"STORAGE_DRIVER"` // 存储驱动：oss/local/s3，未配置时有OSS_ENDPOINT则使用oss，否则使用local
+
+	// 阿里云OSS
+	OSSEndpoint        string `yaml:"oss_endpoint" env:"OSS_ENDPOINT"`
+	OSSAccessKeyID     string `yaml:"oss_access_key_id" env:"OSS_ACCESS_KEY_ID"`
+	OSSAccessKeySecret string `yaml:"oss_access_key_secret" env:"OSS_ACCESS_KEY_SECRET" secret:"true"`
+	OSSBucket          string `yaml:"oss_bucket" env:"OSS_BUCKET"`
+
+	// 本地文件系统，用于开发和CI环境
+	LocalDir        string `yaml:"local_dir" env:"STORAGE_LOCAL_DIR"`                         // 存储根目录
+	LocalBaseURL    string `yaml:"local_base_url" env:"STORAGE_LOCAL_BASE_URL"`               // 本服务的访问地址，用于生成签名地址
+	LocalSigningKey string `yaml:"local_signing_key" env:"STORAGE_SIGNING_KEY" secret:"true"` // 签名地址密钥，为空时每次启动随机生成
+
+	// S3兼容存储
+	S3Endpoint        string `yaml:"s3_endpoint" env:"S3_ENDPOINT"`
+	S3Region          string `yaml:"s3_region" env:"S3_REGION"`
+	S3AccessKeyID     string `yaml:"s3_access_key_id" env:"S3_ACCESS_KEY_ID"`
+	S3SecretAccessKey string `yaml:"s3_secret_access_key" env:"S3_SECRET_ACCESS_KEY" secret:"true"`
+	S3Bucket          string `yaml:"s3_bucket" env:"S3_BUCKET"`
+	S3UseSSL          bool   `yaml:"s3_use_ssl" env:"S3_USE_SSL"`
+}
+
+// UploadTicketConfig 浏览器直传对象存储的配置
+type UploadTicketConfig struct {
+	TTL             time.Duration `yaml:"ttl" env:"UPLOAD_TICKET_TTL"` // 上传地址和票据的有效期
+	CleanupInterval time.Duration `yaml:"cleanup_interval"`            // 清理过期票据和未确认对象的间隔
+	CleanupGrace    time.Duration `yaml:"cleanup_grace"`               // 票据过期后再等待多久清理，避免删除正在确认的对象
+}
+
+// StorageGCConfig 存储垃圾回收配置
+type StorageGCConfig struct {
+	Interval    time.Duration `yaml:"interval" env:"STORAGE_GC_INTERVAL"` // 执行间隔，0表示不启动后台回收
+	OrphanAfter time.Duration `yaml:"orphan_after"`                       // 无主对象创建超过该时长才删除，需大于直传票据有效期
+}
+
+// Default 返回profile对应的默认配置
+// 默认配置不包含任何密钥和密码，需通过配置文件或环境变量提供
+func Default(profile string) Config {
+	cfg := Config{
+		Profile: profile,
+		Server: ServerConfig{
+			Addr:              ":8080",
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       5 * time.Minute,
+			WriteTimeout:      5 * time.Minute,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Log: LogConfig{Development: profile != ProfileProd},
+		Database: DatabaseConfig{
+			Driver:      DatabaseMySQL,
+			SQLitePath:  "data/papergraph.db",
+			AutoMigrate: profile != ProfileProd,
+		},
+		MySQL: MySQLConfig{
+			Host:    "localhost",
+			Port:    3306,
+			User:    "root",
+			DBName:  "papergraph",
+			Charset: "utf8mb4",
+		},
+		GoogleOAuth: GoogleOAuthConfig{
+			RedirectURL: "http://localhost:8080/auth/google/callback",
+		},
+		Gmail: GmailConfig{
+			RedirectURL: "http://localhost:8080/auth/gmail/callback",
+			Scopes: []string{
+				"https://www.googleapis.com/auth/gmail.readonly",
+				"https://www.googleapis.com/auth/userinfo.email",
+				"https://www.googleapis.com/auth/userinfo.profile",
+			},
+		},
+		LLM:    LLMConfig{Provider: "gemini", CircuitFailureThreshold: 5, CircuitCooldown: 30 * time.Second},
+		Gemini: GeminiConfig{Model: "gemini-2.5-flash"},
+		Qwen:   QwenConfig{Model: "qwen-plus"},
+		AnalysisWorker: AnalysisWorkerConfig{
+			Concurrency:       2,
+			MaxAttempts:       3,
+			PollInterval:      5 * time.Second,
+			HeartbeatInterval: 15 * time.Second,
+			StaleAfter:        2 * time.Minute,
+			RetryBackoff:      30 * time.Second,
+			TaskTimeout:       10 * time.Minute,
+		},
+		Chat: ChatConfig{
+			FreeDailyMessages:       20,
+			SubscriberDailyMessages: 200,
+			HistoryMessages:         10,
+			MaxQuestionRunes:        2000,
+		},
+		Import: ImportConfig{
+			ArxivPDFURL:    "https://arxiv.org/pdf/%s",
+			UnpaywallURL:   "https://api.unpaywall.org/v2/",
+			DOIResolverURL: "https://doi.org/",
+			Timeout:        60 * time.Second,
+			MaxRedirects:   5,
+		},
+		Batch: BatchConfig{
+			MaxFiles:       50,
+			MaxUploadBytes: 200 * 1024 * 1024,
+		},
+		Storage: StorageConfig{
+			LocalDir:     "data/storage",
+			LocalBaseURL: "http://localhost:8080",
+			S3UseSSL:     true,
+		},
+		UploadTicket: UploadTicketConfig{
+			TTL:             15 * time.Minute,
+			CleanupInterval: 10 * time.Minute,
+			CleanupGrace:    5 * time.Minute,
+		},
+		StorageGC: StorageGCConfig{
+			Interval:    6 * time.Hour,
+			OrphanAfter: 24 * time.Hour,
+		},
+	}
+	if profile == ProfileTest {
+		// 测试环境使用内存数据库，不调用外部模型服务，也不在后台清理数据
+		cfg.Database.Driver = DatabaseSQLite
+		cfg.Database.SQLitePath = sqliteMemory
+		cfg.LLM.Provider = "fake"
+		cfg.StorageGC.Interval = 0
 	}
-	Logger.Info("日志初始化完成")
-
-	// MySQL配置（可根据实际情况修改）
-	cfg := MySQLConfig{
-		Host:     "localhost",
-		Port:     3306,
-		User:     "root",
-		Password: "12345678",
-		DBName:   "papergraph",
-		Charset:  "utf8mb4",
+	return cfg
+}
+
+// NewLogger 按配置创建日志实例
+func NewLogger(cfg LogConfig) (*zap.Logger, error) {
+	if cfg.Development {
+		return zap.NewDevelopment()
 	}
+	return zap.NewProduction()
+}
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=True&loc=Local",
-		cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.DBName, cfg.Charset)
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+// InitDatabase 打开数据库连接，检查结构版本并初始化默认数据
+func InitDatabase(cfg *Config, logger *zap.Logger) (*gorm.DB, error) {
+	db, err := OpenDatabase(cfg)
 	if err != nil {
-		panic("数据库连接失败: " + err.Error())
+		return nil, fmt.Errorf("数据库连接失败: %w", err)
 	}
-	DB = db
-
-	// 自动迁移所有模型
-	err = db.AutoMigrate(
-		&model.User{},
-		&model.Paper{},
-		&model.AnalysisTask{},
-		&model.AnalysisResult{},
-		&model.Comment{},
-		&model.Product{},
-		&model.UserSubscription{},
-		&model.PaymentRecord{},
-		&model.BadgeTemplate{},
-		&model.UserBadge{},
-		&model.UserStats{},
-		&model.UserActivity{},
-		&model.UserFollow{},
-		&model.TaskReaction{},
-		// 评价相关模型
-		&model.PaperEvaluation{},
-		&model.EvaluationDimension{},
-		&model.EvaluationMetric{},
-		&model.EvaluationComment{},
-		&model.EvaluationLike{},
-		// 邮件相关模型
-		&model.Email{},
-		&model.EmailAnalysis{},
-		&model.EmailDraft{},
-		&model.EmailFilter{},
-	)
-	if err != nil {
-		panic("自动迁移失败: " + err.Error())
+
+	// 检查数据库结构版本，未迁移时拒绝启动，避免在旧结构上运行
+	if err := migrateDatabase(cfg, db, logger); err != nil {
+		return nil, fmt.Errorf("数据库结构检查失败: %w", err)
 	}
-	fmt.Println("数据库连接和自动迁移完成")
+	fmt.Println("数据库连接和结构检查完成")
 
 	// 初始化默认产品数据
-	initializeProducts()
+	initializeProducts(db)
 
 	// 初始化默认奖章模板数据
-	initializeBadgeTemplates()
+	initializeBadgeTemplates(db)
+	return db, nil
 }
 
 // initializeProducts 初始化默认产品数据
-func initializeProducts() {
+func initializeProducts(db *gorm.DB) {
 	// 检查是否已有产品数据
 	var count int64
-	DB.Model(&model.Product{}).Count(&count)
+	db.Model(&model.Product{}).Count(&count)
 
 	if count == 0 {
 		// 创建默认产品
@@ -152,7 +341,7 @@ func initializeProducts() {
 		}
 
 		for _, product := range products {
-			if err := DB.Create(&product).Error; err != nil {
+			if err := db.Create(&product).Error; err != nil {
 				fmt.Printf("创建产品失败: %s\n", err.Error())
 			} else {
 				fmt.Printf("创建产品成功: %s\n", product.Name)
@@ -165,10 +354,10 @@ func initializeProducts() {
 }
 
 // initializeBadgeTemplates 初始化默认奖章模板数据
-func initializeBadgeTemplates() {
+func initializeBadgeTemplates(db *gorm.DB) {
 	// 检查是否已有奖章模板数据
 	var count int64
-	DB.Model(&model.BadgeTemplate{}).Count(&count)
+	db.Model(&model.BadgeTemplate{}).Count(&count)
 
 	if count == 0 {
 		// 创建默认奖章模板
@@ -300,7 +489,7 @@ func initializeBadgeTemplates() {
 		}
 
 		for _, template := range badgeTemplates {
-			if err := DB.Create(&template).Error; err != nil {
+			if err := db.Create(&template).Error; err != nil {
 				fmt.Printf("创建奖章模板失败: %s\n", err.Error())
 			} else {
 				fmt.Printf("创建奖章模板成功: %s\n", template.Name)
@@ -0,0 +1,291 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearConfigEnv 清空配置相关的环境变量，避免运行测试的环境影响结果
+func clearConfigEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("APP_PROFILE", "")
+	t.Setenv("CONFIG_FILE", "")
+	var walk func(reflect.Type)
+	walk = func(typ reflect.Type) {
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			if f.Type.Kind() == reflect.Struct {
+				walk(f.Type)
+			} else if name := f.Tag.Get("env"); name != "" {
+				t.Setenv(name, "")
+			}
+		}
+	}
+	walk(reflect.TypeOf(Config{}))
+	// 工作目录中没有默认配置文件
+	t.Chdir(t.TempDir())
+}
+
+// writeFile 在dir中写入配置文件，返回文件路径
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadYAMLAndTOML(t *testing.T) {
+	clearConfigEnv(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.yaml": `
+server:
+  addr: ":9000"
+  shutdown_timeout: 45s
+llm:
+  provider: fake
+gmail:
+  scopes: [a, b]
+analysis_worker:
+  concurrency: 4
+`,
+		"config.toml": `
+[server]
+addr = ":9000"
+shutdown_timeout = "45s"
+[llm]
+provider = "fake"
+[gmail]
+scopes = ["a", "b"]
+[analysis_worker]
+concurrency = 4
+`,
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := Load(writeFile(t, dir, name, content), ProfileDev)
+			if err != nil {
+				t.Fatalf("加载配置失败: %v", err)
+			}
+			if cfg.Server.Addr != ":9000" || cfg.Server.ShutdownTimeout != 45*time.Second || cfg.LLM.Provider != "fake" ||
+				cfg.AnalysisWorker.Concurrency != 4 || strings.Join(cfg.Gmail.Scopes, ",") != "a,b" {
+				t.Errorf("配置文件中的值未生效: %+v", cfg)
+			}
+			if cfg.Server.ReadTimeout != Default(ProfileDev).Server.ReadTimeout {
+				t.Error("配置文件未设置的项应保留默认值")
+			}
+		})
+	}
+}
+
+func TestLoadRejectsInvalidFiles(t *testing.T) {
+	clearConfigEnv(t)
+	dir := t.TempDir()
+	tests := map[string]string{
+		"unknown.yaml":  "llm:\n  provider: fake\n  temperature: 1\n",
+		"duration.yaml": "llm:\n  provider: fake\nserver:\n  shutdown_timeout: 30\n",
+		"type.yaml":     "llm:\n  provider: fake\nanalysis_worker:\n  concurrency: many\n",
+		"config.ini":    "llm.provider=fake\n",
+	}
+	for name, content := range tests {
+		if _, err := Load(writeFile(t, dir, name, content), ProfileDev); err == nil {
+			t.Errorf("%s: 应返回错误", name)
+		}
+	}
+	if _, err := Load(filepath.Join(dir, "missing.yaml"), ProfileDev); err == nil {
+		t.Error("指定的配置文件不存在时应返回错误")
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	clearConfigEnv(t)
+	dir := t.TempDir()
+	path := writeFile(t, dir, "config.yaml", "llm:\n  provider: fake\nserver:\n  addr: \":7000\"\nmysql:\n  host: base\n  user: base\n")
+	writeFile(t, dir, "config.test.yaml", "mysql:\n  host: profile\n")
+	t.Setenv("MYSQL_USER", "env")
+
+	cfg, err := Load(path, ProfileTest)
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	// 默认值 < 配置文件 < profile配置文件 < 环境变量
+	if cfg.Server.Addr != ":7000" {
+		t.Errorf("配置文件应覆盖默认值，实际为%s", cfg.Server.Addr)
+	}
+	if cfg.MySQL.Host != "profile" {
+		t.Errorf("profile配置文件应覆盖配置文件，实际为%s", cfg.MySQL.Host)
+	}
+	if cfg.MySQL.User != "env" {
+		t.Errorf("环境变量应覆盖配置文件，实际为%s", cfg.MySQL.User)
+	}
+	if cfg.MySQL.Port != 3306 {
+		t.Errorf("未覆盖的项应保留默认值，实际为%d", cfg.MySQL.Port)
+	}
+
+	// 其他profile的配置文件不生效
+	cfg, err = Load(path, ProfileDev)
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	if cfg.MySQL.Host != "base" {
+		t.Errorf("dev环境不应读取config.test.yaml，实际为%s", cfg.MySQL.Host)
+	}
+
+	t.Setenv("MYSQL_PORT", "not-a-port")
+	if _, err := Load(path, ProfileDev); err == nil || !strings.Contains(err.Error(), "MYSQL_PORT") {
+		t.Errorf("环境变量格式错误时应指明变量名，实际为%v", err)
+	}
+}
+
+func TestLoadSelectsProfile(t *testing.T) {
+	clearConfigEnv(t)
+	path := writeFile(t, t.TempDir(), "config.yaml", "llm:\n  provider: fake\n")
+
+	cfg, err := Load(path, "")
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	if cfg.Profile != ProfileDev {
+		t.Errorf("未指定时应使用dev环境，实际为%s", cfg.Profile)
+	}
+
+	t.Setenv("APP_PROFILE", ProfileTest)
+	if cfg, err = Load(path, ""); err != nil || cfg.Profile != ProfileTest || cfg.Database.Driver != DatabaseSQLite {
+		t.Errorf("应按APP_PROFILE选择test环境，实际为%+v, err=%v", cfg, err)
+	}
+	if cfg, err = Load(path, ProfileDev); err != nil || cfg.Profile != ProfileDev {
+		t.Errorf("参数指定的环境应优先于APP_PROFILE，实际为%v", err)
+	}
+	if _, err := Load(path, "staging"); err == nil {
+		t.Error("不支持的环境应返回错误")
+	}
+
+	t.Setenv("CONFIG_FILE", path)
+	if _, err := Load("", ProfileDev); err != nil {
+		t.Errorf("应读取CONFIG_FILE指定的配置文件: %v", err)
+	}
+}
+
+// prodConfig 除JWT密钥外都已配置的生产环境配置文件
+const prodConfig = `
+mysql:
+  password: db-password
+gemini:
+  api_key: gemini-key
+storage:
+  local_signing_key: signing-key
+`
+
+func TestValidateProd(t *testing.T) {
+	clearConfigEnv(t)
+	path := writeFile(t, t.TempDir(), "config.yaml", prodConfig)
+
+	_, err := Load(path, ProfileProd)
+	if err == nil || !strings.Contains(err.Error(), "JWT_SECRET") {
+		t.Fatalf("生产环境未配置JWT密钥时应校验失败，实际为%v", err)
+	}
+	t.Setenv("JWT_SECRET", "too-short")
+	if _, err := Load(path, ProfileProd); err == nil || !strings.Contains(err.Error(), "JWT_SECRET") {
+		t.Errorf("生产环境JWT密钥过短时应校验失败，实际为%v", err)
+	}
+	t.Setenv("JWT_SECRET", strings.Repeat("s", 32))
+	cfg, err := Load(path, ProfileProd)
+	if err != nil {
+		t.Fatalf("配置完整时应通过校验: %v", err)
+	}
+	if cfg.Database.AutoMigrate || len(cfg.Warnings()) != 0 {
+		t.Errorf("生产环境默认不自动迁移且不应有提示，实际auto_migrate=%v, warnings=%v", cfg.Database.AutoMigrate, cfg.Warnings())
+	}
+
+	// 一次返回全部不合法的配置项
+	t.Setenv("LLM_PROVIDER", "fake")
+	t.Setenv("JWT_SECRET", "")
+	_, err = Load(path, ProfileProd)
+	for _, want := range []string{"JWT_SECRET", "fake"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("应报告全部不合法的配置项，缺少%s，实际为%v", want, err)
+		}
+	}
+}
+
+func TestDevGeneratesJWTSecret(t *testing.T) {
+	clearConfigEnv(t)
+	cfg, err := Load(writeFile(t, t.TempDir(), "config.yaml", "llm:\n  provider: fake\n"), ProfileDev)
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	if len(cfg.JWT.Secret) < 32 || len(cfg.Warnings()) != 1 {
+		t.Errorf("开发环境未配置JWT密钥时应随机生成并提示，实际secret长度%d, warnings=%v", len(cfg.JWT.Secret), cfg.Warnings())
+	}
+}
+
+func TestRedactedHidesSecrets(t *testing.T) {
+	cfg := Default(ProfileProd)
+	secrets := map[string]*string{
+		"mysql-password":    &cfg.MySQL.Password,
+		"jwt-secret":        &cfg.JWT.Secret,
+		"google-secret":     &cfg.GoogleOAuth.ClientSecret,
+		"gmail-secret":      &cfg.Gmail.ClientSecret,
+		"gemini-api-key":    &cfg.Gemini.APIKey,
+		"qwen-api-key":      &cfg.Qwen.APIKey,
+		"oss-key-secret":    &cfg.Storage.OSSAccessKeySecret,
+		"local-signing-key": &cfg.Storage.LocalSigningKey,
+		"s3-secret-key":     &cfg.Storage.S3SecretAccessKey,
+	}
+	for value, field := range secrets {
+		*field = value
+	}
+	cfg.MySQL.User = "visible-user"
+
+	redacted := cfg.Redacted()
+	data, err := json.Marshal(redacted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs := map[string]string{
+		"String":      cfg.String(),
+		"%v":          fmt.Sprintf("%v", cfg),
+		"%+v指针":       fmt.Sprintf("%+v", &cfg),
+		"Redacted":    fmt.Sprintf("%+v", redacted),
+		"Redacted序列化": string(data),
+	}
+	for name, out := range outputs {
+		for secret := range secrets {
+			if strings.Contains(out, secret) {
+				t.Errorf("%s的输出包含密钥%s", name, secret)
+			}
+		}
+		if !strings.Contains(out, "visible-user") || !strings.Contains(out, redactedValue) {
+			t.Errorf("%s的输出应保留非密钥配置并以%s替代密钥", name, redactedValue)
+		}
+	}
+	if cfg.JWT.Secret != "jwt-secret" {
+		t.Error("Redacted不应修改原配置")
+	}
+
+	// 配置结构中所有密钥字段都应标记secret标签
+	var walk func(reflect.Type, string)
+	walk = func(typ reflect.Type, prefix string) {
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			if f.Type.Kind() == reflect.Struct {
+				walk(f.Type, prefix+f.Name+".")
+				continue
+			}
+			name := strings.ToLower(f.Name)
+			sensitive := strings.Contains(name, "secret") || strings.Contains(name, "password") || strings.Contains(name, "apikey") || strings.HasSuffix(name, "signingkey")
+			if sensitive && f.Tag.Get("secret") != "true" {
+				t.Errorf("%s%s应标记secret:\"true\"", prefix, f.Name)
+			}
+		}
+	}
+	walk(reflect.TypeOf(Config{}), "")
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"papergraph/migrations"
+
+	"github.com/glebarez/sqlite"
+	"go.uber.org/zap"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// 数据库类型
+const (
+	DatabaseMySQL  = "mysql"
+	DatabaseSQLite = "sqlite" // 纯Go实现，无需CGO和外部服务，适合本地开发和测试
+)
+
+// sqliteMemory 内存数据库的路径，进程退出后数据丢失
+const sqliteMemory = ":memory:"
+
+// OpenDatabase 按配置的数据库类型打开数据库连接
+func OpenDatabase(cfg *Config) (*gorm.DB, error) {
+	switch cfg.Database.Driver {
+	case DatabaseMySQL:
+		return gorm.Open(mysql.Open(cfg.MySQL.DSN()), &gorm.Config{})
+	case DatabaseSQLite:
+		return openSQLite(cfg.Database.SQLitePath)
+	default:
+		return nil, fmt.Errorf("不支持的数据库类型: %s", cfg.Database.Driver)
+	}
+}
+
+// openSQLite 打开SQLite数据库
+// 开启外键约束与MySQL保持一致；文件数据库使用WAL模式，写事务立即加锁并等待其他写入完成，避免并发写入时报database is locked
+func openSQLite(path string) (*gorm.DB, error) {
+	pragmas := []string{"_pragma=foreign_keys(1)", "_pragma=busy_timeout(5000)", "_txlock=immediate"}
+	if path != sqliteMemory {
+		if dir := filepath.Dir(path); dir != "." {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return nil, fmt.Errorf("创建数据库目录失败: %w", err)
+			}
+		}
+		pragmas = append(pragmas, "_pragma=journal_mode(WAL)")
+	}
+	db, err := gorm.Open(sqlite.Open(path+"?"+strings.Join(pragmas, "&")), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	if path == sqliteMemory {
+		// 内存数据库每个连接相互独立，只保留一个连接使所有请求共享同一个数据库
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+	return db, nil
+}
+
+// migrateDatabase 配置了auto_migrate时执行未执行的迁移，然后检查数据库结构是否为最新版本
+func migrateDatabase(cfg *Config, db *gorm.DB, logger *zap.Logger) error {
+	migrator, err := migrations.New(db, cfg.Database.Driver)
+	if err != nil {
+		return err
+	}
+	if cfg.Database.AutoMigrate {
+		applied, err := migrator.Up()
+		if err != nil {
+			return err
+		}
+		for _, m := range applied {
+			logger.Info("已执行数据库迁移", zap.Int64("version", m.Version), zap.String("name", m.Name))
+		}
+	}
+	return migrator.Check()
+}
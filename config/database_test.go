@@ -0,0 +1,44 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"papergraph/migrations"
+
+	"go.uber.org/zap"
+)
+
+func TestInitDatabaseRefusesUnmigratedSchema(t *testing.T) {
+	cfg := Default(ProfileTest)
+	cfg.Database.SQLitePath = filepath.Join(t.TempDir(), "papergraph.db")
+	cfg.Database.AutoMigrate = false
+
+	if _, err := InitDatabase(&cfg, zap.NewNop()); !errors.Is(err, migrations.ErrPendingMigrations) {
+		t.Fatalf("未迁移的数据库应拒绝启动，实际为%v", err)
+	}
+
+	// 执行迁移后同一个数据库可以正常启动
+	db, err := OpenDatabase(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrations.New(db, cfg.Database.Driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(); err != nil {
+		t.Fatalf("执行迁移失败: %v", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+	db, err = InitDatabase(&cfg, zap.NewNop())
+	if err != nil {
+		t.Fatalf("迁移后应能正常启动: %v", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
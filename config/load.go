@@ -0,0 +1,265 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFiles 未指定配置文件时在工作目录中依次查找的文件
+var defaultConfigFiles = []string{"config.yaml", "config.yml", "config.toml"}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Load 加载配置
+// profile为空时读取环境变量APP_PROFILE，默认dev；path为空时读取环境变量CONFIG_FILE，仍为空时在工作目录查找config.yaml/config.yml/config.toml，
+// 都不存在时只使用默认值和环境变量。配置文件同目录下的profile配置文件（如config.prod.yaml）存在时覆盖在其上。
+// 加载完成后校验配置，任何一项不合法都返回错误
+func Load(path, profile string) (*Config, error) {
+	if profile == "" {
+		profile = os.Getenv("APP_PROFILE")
+	}
+	if profile == "" {
+		profile = ProfileDev
+	}
+	if profile != ProfileDev && profile != ProfileTest && profile != ProfileProd {
+		return nil, fmt.Errorf("不支持的运行环境: %s，可选dev/test/prod", profile)
+	}
+	cfg := Default(profile)
+
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path == "" {
+		for _, name := range defaultConfigFiles {
+			if _, err := os.Stat(name); err == nil {
+				path = name
+				break
+			}
+		}
+	}
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+		ext := filepath.Ext(path)
+		profilePath := strings.TrimSuffix(path, ext) + "." + profile + ext
+		if _, err := os.Stat(profilePath); err == nil {
+			if err := cfg.loadFile(profilePath); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := applyEnv(reflect.ValueOf(&cfg).Elem()); err != nil {
+		return nil, err
+	}
+	if err := cfg.normalize(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// loadFile 读取YAML或TOML配置文件并覆盖到当前配置，出现未知的配置项时返回错误
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	values := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("不支持的配置文件格式: %s，可选YAML或TOML", path)
+	}
+	if err != nil {
+		return fmt.Errorf("解析配置文件%s失败: %w", path, err)
+	}
+	if err := applyMap(reflect.ValueOf(c).Elem(), values, ""); err != nil {
+		return fmt.Errorf("配置文件%s: %w", path, err)
+	}
+	return nil
+}
+
+// applyMap 将配置文件的键值按yaml标签写入结构体
+func applyMap(v reflect.Value, values map[string]any, prefix string) error {
+	for key, value := range values {
+		field, ok := fieldByTag(v, key)
+		if !ok {
+			return fmt.Errorf("未知的配置项: %s%s", prefix, key)
+		}
+		if field.Kind() == reflect.Struct {
+			nested, ok := value.(map[string]any)
+			if !ok {
+				return fmt.Errorf("配置项%s%s应为对象", prefix, key)
+			}
+			if err := applyMap(field, nested, prefix+key+"."); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := setValue(field, value); err != nil {
+			return fmt.Errorf("配置项%s%s: %w", prefix, key, err)
+		}
+	}
+	return nil
+}
+
+// fieldByTag 按yaml标签查找结构体字段
+func fieldByTag(v reflect.Value, key string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ","); name == key && name != "-" {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// setValue 将配置文件中的值写入字段，时长使用如"30s"、"15m"的字符串
+func setValue(field reflect.Value, value any) error {
+	if s, ok := value.(string); ok {
+		return setString(field, s)
+	}
+	switch field.Kind() {
+	case reflect.Bool:
+		if b, ok := value.(bool); ok {
+			field.SetBool(b)
+			return nil
+		}
+	case reflect.Int, reflect.Int64:
+		if field.Type() == durationType {
+			return errors.New("时长需写成字符串，如\"30s\"、\"15m\"")
+		}
+		switch n := value.(type) {
+		case int:
+			field.SetInt(int64(n))
+			return nil
+		case int64:
+			field.SetInt(n)
+			return nil
+		case uint64:
+			field.SetInt(int64(n))
+			return nil
+		}
+	case reflect.Slice:
+		items, ok := value.([]any)
+		if !ok || field.Type().Elem().Kind() != reflect.String {
+			break
+		}
+		list := make([]string, 0, len(items))
+		for _, item := range items {
+			s, ok := item.(string)
+			if !ok {
+				return errors.New("列表元素应为字符串")
+			}
+			list = append(list, s)
+		}
+		field.Set(reflect.ValueOf(list))
+		return nil
+	}
+	return fmt.Errorf("类型不匹配，应为%s", field.Type())
+}
+
+// setString 将字符串形式的值写入字段，环境变量和配置文件中的字符串都通过这里解析
+func setString(field reflect.Value, s string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("无效的布尔值: %s", s)
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		if field.Type() == durationType {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return fmt.Errorf("无效的时长: %s", s)
+			}
+			field.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("无效的整数: %s", s)
+		}
+		field.SetInt(n)
+	case reflect.Slice:
+		var list []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		field.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("不支持的配置类型: %s", field.Type())
+	}
+	return nil
+}
+
+// applyEnv 按env标签用环境变量覆盖配置，未设置或为空的环境变量不覆盖
+func applyEnv(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			if err := applyEnv(field); err != nil {
+				return err
+			}
+			continue
+		}
+		name := t.Field(i).Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		if value := os.Getenv(name); value != "" {
+			if err := setString(field, value); err != nil {
+				return fmt.Errorf("环境变量%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// normalize 补全依赖其他配置项的默认值
+func (c *Config) normalize() error {
+	if c.Storage.Driver == "" {
+		c.Storage.Driver = "local"
+		if c.Storage.OSSEndpoint != "" {
+			c.Storage.Driver = "oss"
+		}
+	}
+	// 开发和测试环境未配置JWT密钥时随机生成，重启后之前签发的令牌失效；生产环境必须配置，见Validate
+	if c.JWT.Secret == "" && c.Profile != ProfileProd {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return err
+		}
+		c.JWT.Secret = hex.EncodeToString(key)
+		c.warnings = append(c.warnings, "未配置JWT_SECRET，已随机生成，重启后登录状态失效")
+	}
+	return nil
+}
+
+// Warnings 加载配置时产生的提示，如自动生成的密钥
+func (c *Config) Warnings() []string {
+	return c.warnings
+}
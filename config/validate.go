@@ -0,0 +1,125 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// redactedValue 日志中替代密钥的占位符
+const redactedValue = "******"
+
+// Validate 校验配置，返回全部不合法的配置项
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Addr != "", "server.addr不能为空")
+	check(c.Server.ReadHeaderTimeout > 0 && c.Server.ReadTimeout > 0 && c.Server.WriteTimeout > 0 && c.Server.IdleTimeout > 0,
+		"server的读写超时和空闲超时需大于0")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout需大于0")
+	switch c.Database.Driver {
+	case DatabaseMySQL:
+		check(c.MySQL.Host != "" && c.MySQL.User != "" && c.MySQL.DBName != "", "mysql.host、mysql.user和mysql.db_name不能为空")
+		check(c.MySQL.Port > 0 && c.MySQL.Port < 65536, "mysql.port不合法: %d", c.MySQL.Port)
+		check(c.Profile != ProfileProd || c.MySQL.Password != "", "生产环境需配置MYSQL_PASSWORD")
+	case DatabaseSQLite:
+		check(c.Database.SQLitePath != "", "使用sqlite时需配置database.sqlite_path")
+		// 内存数据库重启后数据丢失，且只能单实例使用
+		check(c.Profile != ProfileProd || c.Database.SQLitePath != sqliteMemory, "生产环境不能使用SQLite内存数据库")
+	default:
+		errs = append(errs, fmt.Errorf("不支持的数据库类型: %s，可选mysql/sqlite", c.Database.Driver))
+	}
+
+	switch c.LLM.Provider {
+	case "gemini":
+		check(c.Gemini.APIKey != "", "使用gemini时需配置GEMINI_API_KEY")
+	case "qwen":
+		check(c.Qwen.APIKey != "", "使用qwen时需配置DASHSCOPE_API_KEY")
+	case "fake":
+		check(c.Profile != ProfileProd, "生产环境不能使用fake模型服务")
+	default:
+		errs = append(errs, fmt.Errorf("不支持的模型服务: %s，可选gemini/qwen/fake", c.LLM.Provider))
+	}
+	check(c.LLM.CircuitFailureThreshold > 0 && c.LLM.CircuitCooldown > 0, "llm.circuit_failure_threshold和circuit_cooldown需大于0")
+
+	w := c.AnalysisWorker
+	check(w.Concurrency > 0 && w.MaxAttempts > 0, "analysis_worker.concurrency和max_attempts需大于0")
+	check(w.PollInterval > 0 && w.HeartbeatInterval > 0 && w.TaskTimeout > 0, "analysis_worker的轮询间隔、心跳间隔和超时时间需大于0")
+	check(c.Chat.MaxQuestionRunes > 0 && c.Chat.HistoryMessages >= 0, "chat配置不合法")
+	check(strings.Contains(c.Import.ArxivPDFURL, "%s"), "import.arxiv_pdf_url需包含%%s")
+	check(c.Import.Timeout > 0 && c.Import.MaxRedirects >= 0, "import.timeout需大于0")
+	check(c.Batch.MaxFiles > 0 && c.Batch.MaxUploadBytes > 0, "batch.max_files和max_upload_bytes需大于0")
+	check(c.UploadTicket.TTL > 0 && c.UploadTicket.CleanupGrace >= 0, "upload_ticket.ttl需大于0")
+	check(c.StorageGC.Interval >= 0, "storage_gc.interval不能为负数")
+	check(c.StorageGC.OrphanAfter > c.UploadTicket.TTL+c.UploadTicket.CleanupGrace,
+		"storage_gc.orphan_after需大于直传票据有效期与清理等待时间之和")
+
+	s := c.Storage
+	switch s.Driver {
+	case "oss":
+		check(s.OSSEndpoint != "" && s.OSSAccessKeyID != "" && s.OSSAccessKeySecret != "" && s.OSSBucket != "", "使用oss存储时需配置OSS_ENDPOINT、OSS_ACCESS_KEY_ID、OSS_ACCESS_KEY_SECRET和OSS_BUCKET")
+	case "s3":
+		check(s.S3Endpoint != "" && s.S3AccessKeyID != "" && s.S3SecretAccessKey != "" && s.S3Bucket != "", "使用s3存储时需配置S3_ENDPOINT、S3_ACCESS_KEY_ID、S3_SECRET_ACCESS_KEY和S3_BUCKET")
+	case "local":
+		check(s.LocalDir != "" && s.LocalBaseURL != "", "使用local存储时需配置存储目录和服务访问地址")
+		// 多实例部署或重启后签名地址需保持有效
+		check(c.Profile != ProfileProd || s.LocalSigningKey != "", "生产环境使用local存储时需配置STORAGE_SIGNING_KEY")
+	default:
+		errs = append(errs, fmt.Errorf("不支持的存储驱动: %s，可选oss/local/s3", s.Driver))
+	}
+
+	if c.Profile == ProfileProd {
+		check(len(c.JWT.Secret) >= 32, "生产环境需配置至少32个字符的JWT_SECRET")
+	} else {
+		check(c.JWT.Secret != "", "jwt.secret不能为空")
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("配置校验失败: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Redacted 返回隐去密钥的配置副本，用于打印日志
+// 标记了secret标签且非空的字段替换为******
+func (c Config) Redacted() Config {
+	v := reflect.ValueOf(&c).Elem()
+	redact(v)
+	c.warnings = nil
+	return c
+}
+
+// redact 递归隐去结构体中的密钥字段
+func redact(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		if field.Kind() == reflect.Struct {
+			redact(field)
+			continue
+		}
+		if t.Field(i).Tag.Get("secret") == "true" && field.Kind() == reflect.String && field.String() != "" {
+			field.SetString(redactedValue)
+		}
+	}
+}
+
+// String 隐去密钥后的配置，避免格式化输出时泄露密钥
+func (c Config) String() string {
+	type plain Config
+	return fmt.Sprintf("%+v", plain(c.Redacted()))
+}
+
+// GoString 隐去密钥后的配置，%#v格式化时同样不泄露密钥
+func (c Config) GoString() string {
+	type plain Config
+	return fmt.Sprintf("%#v", plain(c.Redacted()))
+}
@@ -5,129 +5,122 @@ import (
 	"papergraph/utils"
 	"strconv"
 
-	"papergraph/config"
-
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
-// StartAnalysisHandler 手动触发分析任务接口（演示用）
-func StartAnalysisHandler(c *gin.Context) {
+// AnalysisHandler 分析任务接口
+type AnalysisHandler struct {
+	svc    *service.AnalysisService
+	logger *zap.Logger
+}
+
+// NewAnalysisHandler 创建AnalysisHandler实例
+func NewAnalysisHandler(svc *service.AnalysisService, logger *zap.Logger) *AnalysisHandler {
+	return &AnalysisHandler{svc: svc, logger: logger}
+}
+
+// StartAnalysis 手动触发分析任务接口，任务进入后台队列异步执行
+func (h *AnalysisHandler) StartAnalysis(c *gin.Context) {
 	taskIDStr := c.Query("task_id")
-	config.Logger.Info("手动触发分析请求", zap.String("task_id_str", taskIDStr), zap.String("client_ip", c.ClientIP()))
+	h.logger.Info("手动触发分析请求", zap.String("task_id_str", taskIDStr), zap.String("client_ip", c.ClientIP()))
 	if taskIDStr == "" {
-		config.Logger.Warn("缺少task_id参数", zap.String("client_ip", c.ClientIP()))
+		h.logger.Warn("缺少task_id参数", zap.String("client_ip", c.ClientIP()))
 		utils.Error(c, "缺少task_id参数", 400)
 		return
 	}
 	taskID, err := strconv.Atoi(taskIDStr)
 	if err != nil {
-		config.Logger.Warn("task_id参数错误", zap.Error(err), zap.String("client_ip", c.ClientIP()))
+		h.logger.Warn("task_id参数错误", zap.Error(err), zap.String("client_ip", c.ClientIP()))
 		utils.Error(c, "task_id参数错误", 400)
 		return
 	}
-	analysisService := service.NewAnalysisService()
-	err = analysisService.StartAnalysisTask(uint(taskID))
+	err = h.svc.StartAnalysisTask(uint(taskID))
 	if err != nil {
-		config.Logger.Error("分析任务处理失败", zap.Error(err), zap.String("client_ip", c.ClientIP()))
+		h.logger.Error("分析任务处理失败", zap.Error(err), zap.String("client_ip", c.ClientIP()))
 		utils.Error(c, err.Error(), 400)
 		return
 	}
-	config.Logger.Info("分析任务处理成功", zap.String("task_id_str", taskIDStr), zap.String("client_ip", c.ClientIP()))
-	utils.Success(c, gin.H{"message": "分析完成"})
+	h.logger.Info("分析任务已加入队列", zap.String("task_id_str", taskIDStr), zap.String("client_ip", c.ClientIP()))
+	utils.Success(c, gin.H{"message": "已加入分析队列"})
 }
 
-// GetUserTasksHandler 获取当前用户历史分析任务列表
-func GetUserTasksHandler(c *gin.Context) {
-	userIDVal, exists := c.Get("user_id")
-	config.Logger.Info("获取历史任务请求", zap.Any("user_id_val", userIDVal), zap.Bool("exists", exists), zap.String("client_ip", c.ClientIP()))
-	if !exists {
-		config.Logger.Warn("未登录获取历史任务", zap.String("client_ip", c.ClientIP()))
-		utils.Error(c, "未登录", 401)
-		return
-	}
-	userID, ok := userIDVal.(uint)
+// GetUserTasks 获取当前用户历史分析任务列表
+func (h *AnalysisHandler) GetUserTasks(c *gin.Context) {
+	userID, ok := currentUserID(c)
 	if !ok {
-		utils.Error(c, "用户ID类型错误", 500)
+		h.logger.Warn("未登录获取历史任务", zap.String("client_ip", c.ClientIP()))
+		utils.Error(c, "未登录", 401)
 		return
 	}
-	service := service.NewAnalysisService()
-	tasks, err := service.GetUserAnalysisTasks(userID)
+	tasks, err := h.svc.GetUserAnalysisTasks(userID)
 	if err != nil {
-		config.Logger.Error("获取历史任务失败", zap.Error(err), zap.String("client_ip", c.ClientIP()))
+		h.logger.Error("获取历史任务失败", zap.Error(err), zap.String("client_ip", c.ClientIP()))
 		utils.Error(c, err.Error(), 500)
 		return
 	}
-	config.Logger.Info("获取历史任务成功", zap.Any("user_id_val", userIDVal), zap.String("client_ip", c.ClientIP()))
+	h.logger.Debug("获取历史任务成功", zap.Uint("user_id", userID), zap.Int("count", len(tasks)))
 	utils.Success(c, tasks)
 }
 
-// GetTaskDetailHandler 获取单个任务详情
-func GetTaskDetailHandler(c *gin.Context) {
+// GetTaskDetail 获取单个任务详情
+func (h *AnalysisHandler) GetTaskDetail(c *gin.Context) {
 	taskIDStr := c.Query("task_id")
-	config.Logger.Info("获取任务详情请求", zap.String("task_id_str", taskIDStr), zap.String("client_ip", c.ClientIP()))
+	h.logger.Info("获取任务详情请求", zap.String("task_id_str", taskIDStr), zap.String("client_ip", c.ClientIP()))
 	if taskIDStr == "" {
-		config.Logger.Warn("缺少task_id参数", zap.String("client_ip", c.ClientIP()))
+		h.logger.Warn("缺少task_id参数", zap.String("client_ip", c.ClientIP()))
 		utils.Error(c, "缺少task_id参数", 400)
 		return
 	}
 	taskID, err := strconv.Atoi(taskIDStr)
 	if err != nil {
-		config.Logger.Warn("task_id参数错误", zap.Error(err), zap.String("client_ip", c.ClientIP()))
+		h.logger.Warn("task_id参数错误", zap.Error(err), zap.String("client_ip", c.ClientIP()))
 		utils.Error(c, "task_id参数错误", 400)
 		return
 	}
-	service := service.NewAnalysisService()
-	task, err := service.GetAnalysisTaskDetail(uint(taskID))
+	task, err := h.svc.GetAnalysisTaskDetail(uint(taskID))
 	if err != nil {
-		config.Logger.Error("获取任务详情失败", zap.Error(err), zap.String("client_ip", c.ClientIP()))
+		h.logger.Error("获取任务详情失败", zap.Error(err), zap.String("client_ip", c.ClientIP()))
 		utils.Error(c, err.Error(), 404)
 		return
 	}
-	config.Logger.Info("获取任务详情成功", zap.String("task_id_str", taskIDStr), zap.String("client_ip", c.ClientIP()))
+	h.logger.Info("获取任务详情成功", zap.String("task_id_str", taskIDStr), zap.String("client_ip", c.ClientIP()))
 	utils.Success(c, task)
 }
 
-// GetAnalysisResultHandler 获取分析结果
-func GetAnalysisResultHandler(c *gin.Context) {
+// GetAnalysisResult 获取分析结果
+func (h *AnalysisHandler) GetAnalysisResult(c *gin.Context) {
 	taskIDStr := c.Query("task_id")
-	config.Logger.Info("获取分析结果请求", zap.String("task_id_str", taskIDStr), zap.String("client_ip", c.ClientIP()))
+	h.logger.Info("获取分析结果请求", zap.String("task_id_str", taskIDStr), zap.String("client_ip", c.ClientIP()))
 	if taskIDStr == "" {
-		config.Logger.Warn("缺少task_id参数", zap.String("client_ip", c.ClientIP()))
+		h.logger.Warn("缺少task_id参数", zap.String("client_ip", c.ClientIP()))
 		utils.Error(c, "缺少task_id参数", 400)
 		return
 	}
 	taskID, err := strconv.Atoi(taskIDStr)
 	if err != nil {
-		config.Logger.Warn("task_id参数错误", zap.Error(err), zap.String("client_ip", c.ClientIP()))
+		h.logger.Warn("task_id参数错误", zap.Error(err), zap.String("client_ip", c.ClientIP()))
 		utils.Error(c, "task_id参数错误", 400)
 		return
 	}
-	service := service.NewAnalysisService()
-	result, err := service.GetAnalysisResult(uint(taskID))
+	result, err := h.svc.GetAnalysisResult(uint(taskID))
 	if err != nil {
-		config.Logger.Error("获取分析结果失败", zap.Error(err), zap.String("client_ip", c.ClientIP()))
+		h.logger.Error("获取分析结果失败", zap.Error(err), zap.String("client_ip", c.ClientIP()))
 		utils.Error(c, err.Error(), 404)
 		return
 	}
-	config.Logger.Info("获取分析结果成功", zap.String("task_id_str", taskIDStr), zap.String("client_ip", c.ClientIP()))
+	h.logger.Info("获取分析结果成功", zap.String("task_id_str", taskIDStr), zap.String("client_ip", c.ClientIP()))
 	utils.Success(c, result)
 }
 
-// GetUserActiveTasksHandler 获取当前用户正在分析的任务列表（最多2个）
-func GetUserActiveTasksHandler(c *gin.Context) {
-	userIDVal, exists := c.Get("user_id")
-	if !exists {
-		utils.Error(c, "未登录", 401)
-		return
-	}
-	userID, ok := userIDVal.(uint)
+// GetUserActiveTasks 获取当前用户排队中和正在分析的任务列表（最多2个），包含实时阶段和进度
+func (h *AnalysisHandler) GetUserActiveTasks(c *gin.Context) {
+	userID, ok := currentUserID(c)
 	if !ok {
-		utils.Error(c, "用户ID类型错误", 500)
+		utils.Error(c, "未登录", 401)
 		return
 	}
-	service := service.NewAnalysisService()
-	tasks, err := service.GetUserActiveTasks(userID)
+	tasks, err := h.svc.GetUserActiveTasks(userID)
 	if err != nil {
 		utils.Error(c, err.Error(), 500)
 		return
@@ -135,16 +128,11 @@ func GetUserActiveTasksHandler(c *gin.Context) {
 	utils.Success(c, tasks)
 }
 
-// SetTaskPublicStatusHandler 切换任务公开/私有状态
-func SetTaskPublicStatusHandler(c *gin.Context) {
-	userIDVal, exists := c.Get("user_id")
-	if !exists {
-		utils.Error(c, "未登录", 401)
-		return
-	}
-	userID, ok := userIDVal.(uint)
+// SetTaskPublicStatus 切换任务公开/私有状态
+func (h *AnalysisHandler) SetTaskPublicStatus(c *gin.Context) {
+	userID, ok := currentUserID(c)
 	if !ok {
-		utils.Error(c, "用户ID类型错误", 500)
+		utils.Error(c, "未登录", 401)
 		return
 	}
 	taskIDStr := c.PostForm("task_id")
@@ -159,8 +147,7 @@ func SetTaskPublicStatusHandler(c *gin.Context) {
 		return
 	}
 	isPublic := isPublicStr == "1" || isPublicStr == "true"
-	service := service.NewAnalysisService()
-	err = service.SetTaskPublicStatus(userID, uint(taskID), isPublic)
+	err = h.svc.SetTaskPublicStatus(userID, uint(taskID), isPublic)
 	if err != nil {
 		utils.Error(c, err.Error(), 400)
 		return
@@ -168,11 +155,10 @@ func SetTaskPublicStatusHandler(c *gin.Context) {
 	utils.Success(c, gin.H{"message": "设置成功"})
 }
 
-// GetPublicFeedHandler 获取公开分析任务Feed
-func GetPublicFeedHandler(c *gin.Context) {
+// GetPublicFeed 获取公开分析任务Feed
+func (h *AnalysisHandler) GetPublicFeed(c *gin.Context) {
 	orderBy := c.Query("order_by") // 可选：like/suggest/默认时间
-	service := service.NewAnalysisService()
-	tasks, err := service.GetPublicFeed(orderBy)
+	tasks, err := h.svc.GetPublicFeed(orderBy)
 	if err != nil {
 		utils.Error(c, err.Error(), 500)
 		return
@@ -180,8 +166,8 @@ func GetPublicFeedHandler(c *gin.Context) {
 	utils.Success(c, tasks)
 }
 
-// LikeTaskHandler 点赞分析任务
-func LikeTaskHandler(c *gin.Context) {
+// LikeTask 点赞分析任务
+func (h *AnalysisHandler) LikeTask(c *gin.Context) {
 	taskIDStr := c.PostForm("task_id")
 	if taskIDStr == "" {
 		utils.Error(c, "缺少task_id参数", 400)
@@ -192,8 +178,7 @@ func LikeTaskHandler(c *gin.Context) {
 		utils.Error(c, "task_id参数错误", 400)
 		return
 	}
-	service := service.NewAnalysisService()
-	err = service.LikeTask(uint(taskID))
+	err = h.svc.LikeTask(uint(taskID))
 	if err != nil {
 		utils.Error(c, err.Error(), 400)
 		return
@@ -201,8 +186,8 @@ func LikeTaskHandler(c *gin.Context) {
 	utils.Success(c, gin.H{"message": "点赞成功"})
 }
 
-// UnlikeTaskHandler 取消点赞分析任务
-func UnlikeTaskHandler(c *gin.Context) {
+// UnlikeTask 取消点赞分析任务
+func (h *AnalysisHandler) UnlikeTask(c *gin.Context) {
 	taskIDStr := c.PostForm("task_id")
 	if taskIDStr == "" {
 		utils.Error(c, "缺少task_id参数", 400)
@@ -213,11 +198,128 @@ func UnlikeTaskHandler(c *gin.Context) {
 		utils.Error(c, "task_id参数错误", 400)
 		return
 	}
-	service := service.NewAnalysisService()
-	err = service.UnlikeTask(uint(taskID))
+	err = h.svc.UnlikeTask(uint(taskID))
 	if err != nil {
 		utils.Error(c, err.Error(), 400)
 		return
 	}
 	utils.Success(c, gin.H{"message": "取消点赞成功"})
 }
+
+// CancelTask 取消排队中或正在分析的任务
+// POST /api/tasks/:id/cancel
+func (h *AnalysisHandler) CancelTask(c *gin.Context) {
+	userID, taskID, ok := taskOperationParams(c)
+	if !ok {
+		return
+	}
+	if err := h.svc.CancelTask(userID, taskID); err != nil {
+		h.logger.Warn("取消任务失败", zap.Error(err), zap.Uint("task_id", taskID), zap.String("client_ip", c.ClientIP()))
+		utils.Error(c, err.Error(), 400)
+		return
+	}
+	utils.Success(c, gin.H{"message": "任务已取消"})
+}
+
+// RetryTask 重新执行失败或已取消的任务
+// POST /api/tasks/:id/retry
+func (h *AnalysisHandler) RetryTask(c *gin.Context) {
+	userID, taskID, ok := taskOperationParams(c)
+	if !ok {
+		return
+	}
+	if err := h.svc.RetryTask(userID, taskID); err != nil {
+		h.logger.Warn("重试任务失败", zap.Error(err), zap.Uint("task_id", taskID), zap.String("client_ip", c.ClientIP()))
+		utils.Error(c, err.Error(), 400)
+		return
+	}
+	utils.Success(c, gin.H{"message": "已重新加入分析队列"})
+}
+
+// ReanalyzeRequest 重新分析请求参数
+type ReanalyzeRequest struct {
+	Provider string `json:"provider" binding:"max=32"` // 模型服务提供方：gemini/qwen，为空时使用默认配置
+	Model    string `json:"model" binding:"max=64"`    // 模型名称，为空时使用提供方默认模型
+}
+
+// ReanalyzeTask 使用指定模型重新分析，保留之前的分析结果版本
+// POST /api/tasks/:id/reanalyze
+func (h *AnalysisHandler) ReanalyzeTask(c *gin.Context) {
+	userID, taskID, ok := taskOperationParams(c)
+	if !ok {
+		return
+	}
+	var req ReanalyzeRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.Error(c, "请求参数错误", 400)
+			return
+		}
+	}
+	if err := h.svc.ReanalyzeTask(userID, taskID, req.Provider, req.Model); err != nil {
+		h.logger.Warn("重新分析失败", zap.Error(err), zap.Uint("task_id", taskID), zap.String("client_ip", c.ClientIP()))
+		utils.Error(c, err.Error(), 400)
+		return
+	}
+	utils.Success(c, gin.H{"message": "已加入分析队列"})
+}
+
+// CreateComparisonRequest 对比分析请求参数
+type CreateComparisonRequest struct {
+	PaperIDs []uint `json:"paper_ids" binding:"required,min=2,max=5"` // 参与对比的论文ID，按对比编号顺序
+	Provider string `json:"provider" binding:"max=32"`                // 模型服务提供方：gemini/qwen，为空时使用默认配置
+	Model    string `json:"model" binding:"max=64"`                   // 模型名称，为空时使用提供方默认模型
+}
+
+// CreateComparison 创建多篇论文对比分析任务
+// POST /api/comparisons
+// 对比任务与普通分析任务共用任务进度、取消重试、结果版本和公开分享接口
+func (h *AnalysisHandler) CreateComparison(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		utils.Error(c, "未登录", 401)
+		return
+	}
+	var req CreateComparisonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, "请求参数错误", 400)
+		return
+	}
+	task, err := h.svc.CreateComparisonTask(userID, req.PaperIDs, req.Provider, req.Model)
+	if err != nil {
+		h.logger.Warn("创建对比分析任务失败", zap.Error(err), zap.Uint("user_id", userID), zap.String("client_ip", c.ClientIP()))
+		utils.Error(c, err.Error(), 400)
+		return
+	}
+	utils.Success(c, task)
+}
+
+// ListAnalysisResults 获取任务的全部分析结果版本
+// GET /api/tasks/:id/results
+func (h *AnalysisHandler) ListAnalysisResults(c *gin.Context) {
+	userID, taskID, ok := taskOperationParams(c)
+	if !ok {
+		return
+	}
+	results, err := h.svc.ListAnalysisResults(userID, taskID)
+	if err != nil {
+		utils.Error(c, err.Error(), 404)
+		return
+	}
+	utils.Success(c, results)
+}
+
+// taskOperationParams 读取当前用户和路径中的任务ID，失败时已写入错误响应
+func taskOperationParams(c *gin.Context) (uint, uint, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		utils.Error(c, "未登录", 401)
+		return 0, 0, false
+	}
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(c, "任务ID参数错误", 400)
+		return 0, 0, false
+	}
+	return userID, uint(taskID), true
+}
@@ -14,11 +14,12 @@ import (
 // AuthHandler 认证处理器
 type AuthHandler struct {
 	userService *service.UserService
+	jwt         *utils.JWT
 }
 
 // NewAuthHandler 创建认证处理器
-func NewAuthHandler(userService *service.UserService) *AuthHandler {
-	return &AuthHandler{userService: userService}
+func NewAuthHandler(userService *service.UserService, jwt *utils.JWT) *AuthHandler {
+	return &AuthHandler{userService: userService, jwt: jwt}
 }
 
 // RegisterRequest 注册请求
@@ -97,7 +98,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	// 生成JWT令牌
-	token, err := utils.GenerateToken(user.ID, user.Email)
+	token, err := h.jwt.GenerateToken(user.ID, user.Email)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "令牌生成失败"})
 		return
@@ -153,7 +154,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	h.userService.UpdateLastLogin(user.ID)
 
 	// 生成JWT令牌
-	token, err := utils.GenerateToken(user.ID, user.Email)
+	token, err := h.jwt.GenerateToken(user.ID, user.Email)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "令牌生成失败"})
 		return
@@ -205,7 +206,7 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 	}
 
 	// 生成重置令牌
-	token, err := utils.GeneratePasswordResetToken(user.ID, user.Email)
+	token, err := h.jwt.GeneratePasswordResetToken(user.ID, user.Email)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "重置令牌生成失败"})
 		return
@@ -237,7 +238,7 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	}
 
 	// 验证重置令牌
-	claims, err := utils.ParsePasswordResetToken(req.Token)
+	claims, err := h.jwt.ParsePasswordResetToken(req.Token)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "重置令牌无效或已过期"})
 		return
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"papergraph/config"
+	"papergraph/service"
+	"papergraph/utils"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// BatchHandler 批量上传相关接口
+type BatchHandler struct {
+	svc    *service.BatchService
+	conf   config.BatchConfig
+	logger *zap.Logger
+}
+
+// NewBatchHandler 创建BatchHandler实例
+func NewBatchHandler(svc *service.BatchService, conf config.BatchConfig, logger *zap.Logger) *BatchHandler {
+	return &BatchHandler{svc: svc, conf: conf, logger: logger}
+}
+
+// Create 批量上传论文
+// POST /api/batches
+// 表单字段files（可多个）或file上传PDF文件或zip压缩包；name为批次名称，缺省为第一个文件名；
+// force_fresh=true时跳过去重和结果缓存
+func (h *BatchHandler) Create(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		utils.Error(c, "未登录", 401)
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.conf.MaxUploadBytes+1<<20)
+	form, err := c.MultipartForm()
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			utils.Error(c, fmt.Sprintf("上传文件总大小不能超过%dMB", h.conf.MaxUploadBytes>>20), 413)
+			return
+		}
+		utils.Error(c, "文件获取失败", 400)
+		return
+	}
+	headers := append(form.File["files"], form.File["file"]...)
+	if len(headers) == 0 {
+		utils.Error(c, "请上传PDF文件或zip压缩包", 400)
+		return
+	}
+
+	var files []service.BatchFile
+	defer func() { service.CloseBatchFiles(files) }()
+	for _, header := range headers {
+		expanded, err := h.expandFormFile(header)
+		if err != nil {
+			h.logger.Warn("文件读取失败", zap.Error(err), zap.String("file_name", header.Filename))
+			utils.Error(c, err.Error(), 400)
+			return
+		}
+		files = append(files, expanded...)
+	}
+	name := strings.TrimSpace(c.PostForm("name"))
+	if name == "" {
+		name = strings.TrimSuffix(path.Base(headers[0].Filename), path.Ext(headers[0].Filename))
+	}
+	forceFresh, _ := strconv.ParseBool(c.PostForm("force_fresh"))
+
+	progress, err := h.svc.CreateBatch(userID, name, files, forceFresh)
+	if err != nil {
+		h.logger.Warn("批量上传失败", zap.Error(err), zap.Uint("user_id", userID), zap.String("client_ip", c.ClientIP()))
+		code := 400
+		if errors.Is(err, service.ErrAnalysisQuotaExceeded) {
+			code = 429
+		}
+		utils.Error(c, err.Error(), code)
+		return
+	}
+	utils.Success(c, progress)
+}
+
+// List 获取当前用户的批量上传记录
+// GET /api/batches
+func (h *BatchHandler) List(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		utils.Error(c, "未登录", 401)
+		return
+	}
+	batches, err := h.svc.ListBatches(userID)
+	if err != nil {
+		h.logger.Error("查询批次失败", zap.Error(err), zap.Uint("user_id", userID))
+		utils.Error(c, "查询批次失败", 500)
+		return
+	}
+	utils.Success(c, batches)
+}
+
+// Get 获取批次整体进度和各文件任务进度
+// GET /api/batches/:id
+func (h *BatchHandler) Get(c *gin.Context) {
+	userID, batchID, ok := batchParams(c)
+	if !ok {
+		return
+	}
+	progress, err := h.svc.GetBatch(userID, batchID)
+	if err != nil {
+		utils.Error(c, err.Error(), 404)
+		return
+	}
+	utils.Success(c, progress)
+}
+
+// Export 合并导出批次中全部论文的分析结果
+// GET /api/batches/:id/export?format=json|markdown
+// 批次中的任务全部结束后才能导出
+func (h *BatchHandler) Export(c *gin.Context) {
+	userID, batchID, ok := batchParams(c)
+	if !ok {
+		return
+	}
+	format := c.DefaultQuery("format", service.BatchExportJSON)
+	if format != service.BatchExportJSON && format != service.BatchExportMarkdown {
+		utils.Error(c, "不支持的导出格式", 400)
+		return
+	}
+	export, err := h.svc.ExportBatch(userID, batchID)
+	if err != nil {
+		code := 404
+		if errors.Is(err, service.ErrBatchNotFinished) {
+			code = 409
+		}
+		utils.Error(c, err.Error(), code)
+		return
+	}
+	fileName := fmt.Sprintf("batch-%d", batchID)
+	if format == service.BatchExportMarkdown {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.md"`, fileName))
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(export.RenderMarkdown()))
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, fileName))
+	c.JSON(http.StatusOK, export)
+}
+
+// batchParams 读取当前用户和路径中的批次ID，失败时已写入错误响应
+func batchParams(c *gin.Context) (uint, uint, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		utils.Error(c, "未登录", 401)
+		return 0, 0, false
+	}
+	batchID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(c, "批次ID参数错误", 400)
+		return 0, 0, false
+	}
+	return userID, uint(batchID), true
+}
+
+// expandFormFile 打开上传的文件并展开其中的PDF
+func (h *BatchHandler) expandFormFile(header *multipart.FileHeader) ([]service.BatchFile, error) {
+	f, err := header.Open()
+	if err != nil {
+		return nil, errors.New("文件读取失败")
+	}
+	defer f.Close()
+	return h.svc.ExpandUpload(header.Filename, f, header.Size)
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"papergraph/service"
+	"papergraph/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ChatHandler 论文问答接口
+type ChatHandler struct {
+	chat   *service.ChatService
+	logger *zap.Logger
+}
+
+// NewChatHandler 创建论文问答处理器
+func NewChatHandler(chat *service.ChatService, logger *zap.Logger) *ChatHandler {
+	return &ChatHandler{chat: chat, logger: logger}
+}
+
+// ChatRequest 提问请求参数
+type ChatRequest struct {
+	ThreadID uint   `json:"thread_id"`                  // 会话ID，为空时新建会话
+	Message  string `json:"message" binding:"required"` // 问题
+	Stream   bool   `json:"stream"`                     // 是否以SSE流式返回，Accept为text/event-stream时同样流式返回
+}
+
+// Ask 针对论文提问
+// POST /api/papers/:id/chat
+// 流式返回时依次推送delta事件（回答增量）和done事件（保存后的问答记录），出错时推送error事件
+func (h *ChatHandler) Ask(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		utils.Error(c, "未登录", 401)
+		return
+	}
+	paperID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(c, "论文ID参数错误", 400)
+		return
+	}
+	var req ChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, "请求参数错误", 400)
+		return
+	}
+	chatReq := service.ChatRequest{UserID: userID, PaperID: uint(paperID), ThreadID: req.ThreadID, Question: req.Message}
+
+	if !req.Stream && !strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+		reply, err := h.chat.Ask(c.Request.Context(), chatReq, nil)
+		if err != nil {
+			h.logger.Warn("论文问答失败", zap.Error(err), zap.Uint("paper_id", uint(paperID)), zap.String("client_ip", c.ClientIP()))
+			utils.Error(c, err.Error(), chatErrorCode(err))
+			return
+		}
+		utils.Success(c, reply)
+		return
+	}
+
+	// 收到第一段回答后才切换为SSE响应，之前的错误（参数、权限、配额）仍以普通JSON返回
+	clearWriteDeadline(c)
+	streaming := false
+	onDelta := func(delta string) error {
+		if !streaming {
+			streaming = true
+			c.Header("Content-Type", "text/event-stream")
+			c.Header("Cache-Control", "no-cache")
+			c.Header("Connection", "keep-alive")
+			c.Header("X-Accel-Buffering", "no") // 关闭nginx缓冲
+		}
+		return writeChatEvent(c, "delta", gin.H{"content": delta})
+	}
+	reply, err := h.chat.Ask(c.Request.Context(), chatReq, onDelta)
+	if err != nil {
+		h.logger.Warn("论文问答失败", zap.Error(err), zap.Uint("paper_id", uint(paperID)), zap.String("client_ip", c.ClientIP()))
+		if !streaming {
+			utils.Error(c, err.Error(), chatErrorCode(err))
+			return
+		}
+		writeChatEvent(c, "error", gin.H{"code": chatErrorCode(err), "message": err.Error()})
+		return
+	}
+	if !streaming {
+		utils.Success(c, reply)
+		return
+	}
+	writeChatEvent(c, "done", reply)
+}
+
+// ListThreads 获取当前用户在论文下的问答会话
+// GET /api/papers/:id/chat/threads
+func (h *ChatHandler) ListThreads(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		utils.Error(c, "未登录", 401)
+		return
+	}
+	paperID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(c, "论文ID参数错误", 400)
+		return
+	}
+	threads, err := h.chat.ListThreads(userID, uint(paperID))
+	if err != nil {
+		h.logger.Error("查询问答会话失败", zap.Error(err), zap.Uint("paper_id", uint(paperID)))
+		utils.Error(c, "查询问答会话失败", 500)
+		return
+	}
+	utils.Success(c, threads)
+}
+
+// ListMessages 获取会话消息
+// GET /api/chat/threads/:id/messages
+func (h *ChatHandler) ListMessages(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		utils.Error(c, "未登录", 401)
+		return
+	}
+	threadID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(c, "会话ID参数错误", 400)
+		return
+	}
+	messages, err := h.chat.ListMessages(userID, uint(threadID))
+	if err != nil {
+		utils.Error(c, err.Error(), 404)
+		return
+	}
+	utils.Success(c, messages)
+}
+
+// GetQuota 获取当前用户当日提问配额
+// GET /api/chat/quota
+func (h *ChatHandler) GetQuota(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		utils.Error(c, "未登录", 401)
+		return
+	}
+	quota, err := h.chat.GetQuota(userID)
+	if err != nil {
+		h.logger.Error("查询问答配额失败", zap.Error(err), zap.Uint("user_id", userID))
+		utils.Error(c, "查询问答配额失败", 500)
+		return
+	}
+	utils.Success(c, quota)
+}
+
+// chatErrorCode 问答错误对应的响应码
+func chatErrorCode(err error) int {
+	switch {
+	case errors.Is(err, service.ErrChatQuotaExceeded):
+		return 429
+	case errors.Is(err, service.ErrChatForbidden):
+		return 403
+	case errors.Is(err, service.ErrChatNotReady):
+		return 409
+	}
+	return 400
+}
+
+// writeChatEvent 写出一条SSE事件，写失败说明客户端已断开
+func writeChatEvent(c *gin.Context, name string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", name, data); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
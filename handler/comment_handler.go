@@ -8,16 +8,21 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// AddCommentHandler 添加评论或回复
-func AddCommentHandler(c *gin.Context) {
-	userIDVal, exists := c.Get("user_id")
-	if !exists {
-		utils.Error(c, "未登录", 401)
-		return
-	}
-	userID, ok := userIDVal.(uint)
+// CommentHandler 评论接口
+type CommentHandler struct {
+	svc *service.CommentService
+}
+
+// NewCommentHandler 创建CommentHandler实例
+func NewCommentHandler(svc *service.CommentService) *CommentHandler {
+	return &CommentHandler{svc: svc}
+}
+
+// AddComment 添加评论或回复
+func (h *CommentHandler) AddComment(c *gin.Context) {
+	userID, ok := currentUserID(c)
 	if !ok {
-		utils.Error(c, "用户ID类型错误", 500)
+		utils.Error(c, "未登录", 401)
 		return
 	}
 	taskIDStr := c.PostForm("task_id")
@@ -40,8 +45,7 @@ func AddCommentHandler(c *gin.Context) {
 			parentID = &pidUint
 		}
 	}
-	service := service.NewCommentService()
-	comment, err := service.AddComment(userID, uint(taskID), content, parentID)
+	comment, err := h.svc.AddComment(userID, uint(taskID), content, parentID)
 	if err != nil {
 		utils.Error(c, err.Error(), 400)
 		return
@@ -49,8 +53,8 @@ func AddCommentHandler(c *gin.Context) {
 	utils.Success(c, comment)
 }
 
-// GetCommentsHandler 获取评论列表
-func GetCommentsHandler(c *gin.Context) {
+// GetComments 获取评论列表
+func (h *CommentHandler) GetComments(c *gin.Context) {
 	taskIDStr := c.Query("task_id")
 	if taskIDStr == "" {
 		utils.Error(c, "缺少task_id参数", 400)
@@ -61,8 +65,7 @@ func GetCommentsHandler(c *gin.Context) {
 		utils.Error(c, "task_id参数错误", 400)
 		return
 	}
-	service := service.NewCommentService()
-	comments, err := service.GetComments(uint(taskID))
+	comments, err := h.svc.GetComments(uint(taskID))
 	if err != nil {
 		utils.Error(c, err.Error(), 500)
 		return
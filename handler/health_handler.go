@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"papergraph/aitools"
+	"papergraph/storage"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// healthCheckTimeout 就绪检查中单项依赖的超时时间
+const healthCheckTimeout = 2 * time.Second
+
+// healthProbeKey 检查对象存储连通性时查询的对象，不要求存在
+const healthProbeKey = "healthz"
+
+// HealthHandler 存活和就绪检查
+// 供负载均衡和容器编排探测使用，直接返回HTTP状态码，不使用统一的响应格式
+type HealthHandler struct {
+	db       *gorm.DB
+	store    storage.Storage
+	breakers *aitools.CircuitBreakers
+}
+
+// NewHealthHandler 创建健康检查处理器
+func NewHealthHandler(db *gorm.DB, store storage.Storage, breakers *aitools.CircuitBreakers) *HealthHandler {
+	return &HealthHandler{db: db, store: store, breakers: breakers}
+}
+
+// Healthz 存活检查，进程能处理请求即返回200
+// GET /healthz
+func (h *HealthHandler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz 就绪检查
+// GET /readyz
+// 数据库或对象存储不可用时返回503；模型服务熔断只影响分析和问答，实例仍可处理其他请求，返回200并标记为degraded
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	checks := gin.H{}
+	ready := true
+	if err := h.checkDatabase(c.Request.Context()); err != nil {
+		checks["database"] = err.Error()
+		ready = false
+	} else {
+		checks["database"] = "ok"
+	}
+	if err := h.checkStorage(c.Request.Context()); err != nil {
+		checks["storage"] = err.Error()
+		ready = false
+	} else {
+		checks["storage"] = "ok"
+	}
+
+	status := "ok"
+	circuits := h.breakers.States()
+	for _, state := range circuits {
+		if state != aitools.CircuitClosed {
+			status = "degraded"
+		}
+	}
+	checks["llm"] = circuits
+
+	code := http.StatusOK
+	if !ready {
+		status, code = "unavailable", http.StatusServiceUnavailable
+	}
+	c.JSON(code, gin.H{"status": status, "checks": checks})
+}
+
+// checkDatabase 检查数据库连接
+func (h *HealthHandler) checkDatabase(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	sqlDB, err := h.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// checkStorage 检查对象存储是否可访问，探测对象不存在视为正常
+func (h *HealthHandler) checkStorage(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	if _, err := h.store.Stat(ctx, healthProbeKey); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	return nil
+}
@@ -1,63 +1,280 @@
 package handler
 
 import (
-	"io/ioutil"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"papergraph/model"
 	"papergraph/service"
 	"papergraph/utils"
 	"strconv"
-
-	"papergraph/config"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
-// UploadPaperHandler 论文上传接口
+// PaperHandler 论文上传和删除接口
+type PaperHandler struct {
+	svc    *service.PaperService
+	logger *zap.Logger
+}
+
+// NewPaperHandler 创建PaperHandler实例
+func NewPaperHandler(svc *service.PaperService, logger *zap.Logger) *PaperHandler {
+	return &PaperHandler{svc: svc, logger: logger}
+}
+
+// Upload 论文上传接口
 // 需登录，支持多部分表单上传PDF
-func UploadPaperHandler(c *gin.Context) {
-	userIDVal, exists := c.Get("user_id")
-	config.Logger.Info("论文上传请求", zap.Any("user_id_val", userIDVal), zap.Bool("exists", exists), zap.String("client_ip", c.ClientIP()))
-	if !exists {
-		config.Logger.Warn("未登录上传论文", zap.String("client_ip", c.ClientIP()))
+func (h *PaperHandler) Upload(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		h.logger.Warn("未登录上传论文", zap.String("client_ip", c.ClientIP()))
 		utils.Error(c, "未登录", 401)
 		return
 	}
-	userID, ok := userIDVal.(uint)
+	upload, forceFresh, err := readUploadForm(c)
+	if err != nil {
+		h.logger.Warn("上传文件校验失败", zap.Error(err), zap.String("client_ip", c.ClientIP()))
+		uploadError(c, err)
+		return
+	}
+	defer upload.Close()
+	paper, task, err := h.svc.UploadAndCreateTask(userID, upload, forceFresh)
+	if err != nil {
+		h.logger.Error("上传与任务创建失败", zap.Error(err), zap.String("client_ip", c.ClientIP()))
+		utils.Error(c, err.Error(), 400)
+		return
+	}
+	h.logger.Info("论文上传与任务创建成功", zap.Uint("user_id", userID), zap.Uint("paper_id", paper.ID), zap.Uint("task_id", task.ID), zap.String("client_ip", c.ClientIP()))
+	utils.Success(c, gin.H{"paper": paper, "task": task, "cached": task.Status == model.TaskStatusCompleted})
+}
+
+// ImportPaperRequest 按链接导入论文请求参数
+type ImportPaperRequest struct {
+	URL        string `json:"url" binding:"required,max=2048"` // PDF链接、arXiv ID/链接或DOI
+	ForceFresh bool   `json:"force_fresh"`                     // 是否跳过去重和结果缓存，强制重新分析
+}
+
+// ImportHandler 按链接导入论文接口
+type ImportHandler struct {
+	svc     *service.PaperImportService
+	timeout time.Duration
+	logger  *zap.Logger
+}
+
+// NewImportHandler 创建ImportHandler实例，timeout为单次导入的下载超时
+func NewImportHandler(svc *service.PaperImportService, timeout time.Duration, logger *zap.Logger) *ImportHandler {
+	return &ImportHandler{svc: svc, timeout: timeout, logger: logger}
+}
+
+// Import 按链接、arXiv ID或DOI导入论文并创建分析任务
+// POST /api/upload/url
+func (h *ImportHandler) Import(c *gin.Context) {
+	userID, ok := currentUserID(c)
 	if !ok {
-		// 兼容int类型
-		if idInt, ok2 := userIDVal.(int); ok2 {
-			userID = uint(idInt)
-		} else if idInt64, ok3 := userIDVal.(int64); ok3 {
-			userID = uint(idInt64)
-		} else if idStr, ok4 := userIDVal.(string); ok4 {
-			id, _ := strconv.Atoi(idStr)
-			userID = uint(id)
-		} else {
-			utils.Error(c, "用户ID类型错误", 500)
-			return
+		utils.Error(c, "未登录", 401)
+		return
+	}
+	var req ImportPaperRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, "请提供论文URL、arXiv ID或DOI", 400)
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+	paper, task, source, err := h.svc.Import(ctx, userID, req.URL, req.ForceFresh)
+	if err != nil {
+		h.logger.Warn("导入论文失败", zap.Error(err), zap.String("url", req.URL), zap.String("client_ip", c.ClientIP()))
+		utils.Error(c, err.Error(), 400)
+		return
+	}
+	h.logger.Info("论文导入与任务创建成功", zap.Uint("user_id", userID), zap.Uint("paper_id", paper.ID), zap.Uint("task_id", task.ID), zap.String("client_ip", c.ClientIP()))
+	utils.Success(c, gin.H{"paper": paper, "task": task, "source": source, "cached": task.Status == model.TaskStatusCompleted})
+}
+
+// readUploadForm 流式读取上传表单，file字段边接收边写入临时文件并校验，不在内存中缓存整个文件
+// Content-Length超过上限时不读取请求体直接拒绝；force_fresh=true时跳过去重和结果缓存，强制重新分析
+func readUploadForm(c *gin.Context) (*service.SpooledUpload, bool, error) {
+	limit := int64(service.MaxPDFSize + 1<<20) // 为表单其他字段和分隔符预留1MB
+	if c.Request.ContentLength > limit {
+		return nil, false, service.ErrFileTooLarge
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, false, errors.New("文件获取失败")
+	}
+	var upload *service.SpooledUpload
+	forceFresh := false
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
 		}
+		if err != nil {
+			upload.Close()
+			return nil, false, uploadReadError(err)
+		}
+		switch {
+		case part.FormName() == "file" && upload == nil:
+			upload, err = service.SpoolUpload(part.FileName(), part)
+			if err != nil {
+				return nil, false, uploadReadError(err)
+			}
+		case part.FormName() == "force_fresh":
+			value, _ := io.ReadAll(io.LimitReader(part, 16))
+			forceFresh, _ = strconv.ParseBool(string(value))
+		}
+		part.Close()
+	}
+	if upload == nil {
+		return nil, false, errors.New("文件获取失败")
+	}
+	return upload, forceFresh, nil
+}
+
+// uploadReadError 请求体超过上限时统一返回ErrFileTooLarge
+func uploadReadError(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return service.ErrFileTooLarge
+	}
+	return err
+}
+
+// uploadError 返回上传文件校验失败的响应，data.reason给出便于客户端识别的原因
+func uploadError(c *gin.Context, err error) {
+	code, reason := 400, ""
+	switch {
+	case errors.Is(err, service.ErrFileTooLarge):
+		code, reason = 413, "file_too_large"
+	case errors.Is(err, service.ErrFileEmpty):
+		code, reason = 415, "empty_file"
+	case errors.Is(err, utils.ErrNotPDF):
+		code, reason = 415, "not_pdf"
+	case errors.Is(err, utils.ErrPDFEncrypted):
+		code, reason = 422, "pdf_encrypted"
+	case errors.Is(err, utils.ErrPDFCorrupt):
+		code, reason = 422, "pdf_corrupt"
+	case errors.Is(err, utils.ErrPDFNoPages):
+		code, reason = 422, "pdf_no_pages"
+	}
+	if reason == "" {
+		utils.Error(c, err.Error(), code)
+		return
 	}
-	file, header, err := c.Request.FormFile("file")
+	utils.ErrorWithData(c, err.Error(), code, gin.H{"reason": reason})
+}
+
+// CreateUploadTicketRequest 申请直传票据请求参数
+type CreateUploadTicketRequest struct {
+	FileName    string `json:"file_name" binding:"required,max=256"` // 文件名
+	FileSize    int64  `json:"file_size" binding:"required"`         // 文件大小（字节）
+	ContentHash string `json:"content_hash" binding:"required"`      // 文件内容SHA-256（十六进制）
+}
+
+// UploadTicketHandler 浏览器直传相关接口
+type UploadTicketHandler struct {
+	svc    *service.UploadTicketService
+	logger *zap.Logger
+}
+
+// NewUploadTicketHandler 创建UploadTicketHandler实例
+func NewUploadTicketHandler(svc *service.UploadTicketService, logger *zap.Logger) *UploadTicketHandler {
+	return &UploadTicketHandler{svc: svc, logger: logger}
+}
+
+// Create 申请浏览器直传对象存储的上传票据
+// POST /api/upload/tickets
+// 返回限时上传地址，客户端按返回的method和headers上传文件后调用确认接口
+func (h *UploadTicketHandler) Create(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		utils.Error(c, "未登录", 401)
+		return
+	}
+	var req CreateUploadTicketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, "请提供文件名、文件大小和SHA-256", 400)
+		return
+	}
+	result, err := h.svc.CreateTicket(c.Request.Context(), userID, req.FileName, req.FileSize, req.ContentHash)
 	if err != nil {
-		config.Logger.Warn("文件获取失败", zap.Error(err), zap.String("client_ip", c.ClientIP()))
-		utils.Error(c, "文件获取失败", 400)
+		h.logger.Warn("申请上传票据失败", zap.Error(err), zap.Uint("user_id", userID), zap.String("client_ip", c.ClientIP()))
+		uploadError(c, err)
 		return
 	}
-	defer file.Close()
-	fileData, err := ioutil.ReadAll(file)
+	utils.Success(c, result)
+}
+
+// Complete 确认直传完成，校验文件后创建论文和分析任务
+// POST /api/upload/tickets/:token/complete
+// 请求体可选{"force_fresh": true}，跳过去重和结果缓存
+func (h *UploadTicketHandler) Complete(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		utils.Error(c, "未登录", 401)
+		return
+	}
+	var req struct {
+		ForceFresh bool `json:"force_fresh"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.Error(c, "请求参数错误", 400)
+			return
+		}
+	}
+	paper, task, err := h.svc.CompleteTicket(c.Request.Context(), userID, c.Param("token"), req.ForceFresh)
 	if err != nil {
-		config.Logger.Warn("文件读取失败", zap.Error(err), zap.String("client_ip", c.ClientIP()))
-		utils.Error(c, "文件读取失败", 400)
+		h.logger.Warn("确认直传失败", zap.Error(err), zap.Uint("user_id", userID), zap.String("client_ip", c.ClientIP()))
+		switch {
+		case errors.Is(err, service.ErrUploadTicketNotFound):
+			utils.Error(c, err.Error(), 404)
+		case errors.Is(err, service.ErrUploadTicketExpired):
+			utils.ErrorWithData(c, err.Error(), 410, gin.H{"reason": "ticket_expired"})
+		case errors.Is(err, service.ErrUploadObjectMissing):
+			utils.ErrorWithData(c, err.Error(), 409, gin.H{"reason": "object_missing"})
+		case errors.Is(err, service.ErrUploadSizeMismatch):
+			utils.ErrorWithData(c, err.Error(), 422, gin.H{"reason": "size_mismatch"})
+		case errors.Is(err, service.ErrUploadHashMismatch):
+			utils.ErrorWithData(c, err.Error(), 422, gin.H{"reason": "hash_mismatch"})
+		default:
+			uploadError(c, err)
+		}
 		return
 	}
-	fileSize := int64(len(fileData))
-	paperService := service.NewPaperService()
-	paper, task, err := paperService.UploadAndCreateTask(userID, header.Filename, fileData, fileSize)
+	h.logger.Info("直传论文与任务创建成功", zap.Uint("user_id", userID), zap.Uint("paper_id", paper.ID), zap.String("client_ip", c.ClientIP()))
+	utils.Success(c, gin.H{"paper": paper, "task": task, "cached": task != nil && task.Status == model.TaskStatusCompleted})
+}
+
+// Delete 删除论文及其任务、分析结果、评价、评论和存储的文件（仅上传者可操作）
+// DELETE /api/papers/:id
+func (h *PaperHandler) Delete(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		utils.Error(c, "未登录", 401)
+		return
+	}
+	paperID, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
-		config.Logger.Error("上传与任务创建失败", zap.Error(err), zap.String("client_ip", c.ClientIP()))
-		utils.Error(c, err.Error(), 400)
+		utils.Error(c, "论文ID参数错误", 400)
+		return
+	}
+	if err := h.svc.DeletePaper(c.Request.Context(), userID, uint(paperID)); err != nil {
+		h.logger.Warn("删除论文失败", zap.Error(err), zap.Uint64("paper_id", paperID), zap.String("client_ip", c.ClientIP()))
+		switch {
+		case errors.Is(err, service.ErrPaperNotFound):
+			utils.Error(c, err.Error(), 404)
+		case errors.Is(err, service.ErrPaperForbidden):
+			utils.Error(c, err.Error(), 403)
+		default:
+			utils.Error(c, "删除论文失败", 500)
+		}
 		return
 	}
-	config.Logger.Info("论文上传与任务创建成功", zap.Uint("user_id", userID), zap.Uint("paper_id", paper.ID), zap.Uint("task_id", task.ID), zap.String("client_ip", c.ClientIP()))
-	utils.Success(c, gin.H{"paper": paper, "task": task})
+	utils.Success(c, gin.H{"message": "论文已删除"})
 }
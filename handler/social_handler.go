@@ -2,7 +2,6 @@ package handler
 
 import (
 	"net/http"
-	"papergraph/config"
 	"papergraph/model"
 	"papergraph/service"
 	"strconv"
@@ -17,13 +16,15 @@ type SocialHandler struct {
 	socialService *service.SocialService
 	badgeService  *service.BadgeService
 	db            *gorm.DB
+	logger        *zap.Logger
 }
 
-func NewSocialHandler(socialService *service.SocialService, badgeService *service.BadgeService, db *gorm.DB) *SocialHandler {
+func NewSocialHandler(socialService *service.SocialService, badgeService *service.BadgeService, db *gorm.DB, logger *zap.Logger) *SocialHandler {
 	return &SocialHandler{
 		socialService: socialService,
 		badgeService:  badgeService,
 		db:            db,
+		logger:        logger,
 	}
 }
 
@@ -71,7 +72,7 @@ func (h *SocialHandler) UnfollowUser(c *gin.Context) {
 func (h *SocialHandler) GetFollowing(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	targetUserIDStr := c.Param("user_id")
-	config.Logger.Info("GetFollowing", zap.String("user_id", strconv.Itoa(int(userID))), zap.String("target_user_id", targetUserIDStr))
+	h.logger.Info("GetFollowing", zap.String("user_id", strconv.Itoa(int(userID))), zap.String("target_user_id", targetUserIDStr))
 	targetUserID, err := strconv.ParseUint(targetUserIDStr, 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "无效的用户ID"})
@@ -106,7 +107,7 @@ func (h *SocialHandler) GetFollowing(c *gin.Context) {
 func (h *SocialHandler) GetFollowers(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	targetUserIDStr := c.Param("user_id")
-	config.Logger.Info("GetFollowers", zap.String("user_id", strconv.Itoa(int(userID))), zap.String("target_user_id", targetUserIDStr))
+	h.logger.Info("GetFollowers", zap.String("user_id", strconv.Itoa(int(userID))), zap.String("target_user_id", targetUserIDStr))
 	targetUserID, err := strconv.ParseUint(targetUserIDStr, 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "无效的用户ID"})
@@ -141,7 +142,7 @@ func (h *SocialHandler) GetFollowers(c *gin.Context) {
 func (h *SocialHandler) GetUserActivityFeed(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	targetUserIDStr := c.Param("user_id")
-	config.Logger.Info("GetUserActivityFeed", zap.String("user_id", strconv.Itoa(int(userID))), zap.String("target_user_id", targetUserIDStr))
+	h.logger.Info("GetUserActivityFeed", zap.String("user_id", strconv.Itoa(int(userID))), zap.String("target_user_id", targetUserIDStr))
 	targetUserID, err := strconv.ParseUint(targetUserIDStr, 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "无效的用户ID"})
@@ -176,7 +177,7 @@ func (h *SocialHandler) GetUserActivityFeed(c *gin.Context) {
 func (h *SocialHandler) GetUserAnalysisFeed(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	targetUserIDStr := c.Param("user_id")
-	config.Logger.Info("GetUserAnalysisFeed", zap.String("user_id", strconv.Itoa(int(userID))), zap.String("target_user_id", targetUserIDStr))
+	h.logger.Info("GetUserAnalysisFeed", zap.String("user_id", strconv.Itoa(int(userID))), zap.String("target_user_id", targetUserIDStr))
 	targetUserID, err := strconv.ParseUint(targetUserIDStr, 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "无效的用户ID"})
@@ -211,7 +212,7 @@ func (h *SocialHandler) GetUserAnalysisFeed(c *gin.Context) {
 func (h *SocialHandler) GetUserBadges(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	targetUserIDStr := c.Param("user_id")
-	config.Logger.Info("GetUserBadges", zap.String("user_id", strconv.Itoa(int(userID))), zap.String("target_user_id", targetUserIDStr))
+	h.logger.Info("GetUserBadges", zap.String("user_id", strconv.Itoa(int(userID))), zap.String("target_user_id", targetUserIDStr))
 	targetUserID, err := strconv.ParseUint(targetUserIDStr, 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "无效的用户ID"})
@@ -231,7 +232,7 @@ func (h *SocialHandler) GetUserBadges(c *gin.Context) {
 func (h *SocialHandler) GetUserStats(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	targetUserIDStr := c.Param("user_id")
-	config.Logger.Info("GetUserStats", zap.String("user_id", strconv.Itoa(int(userID))), zap.String("target_user_id", targetUserIDStr))
+	h.logger.Info("GetUserStats", zap.String("user_id", strconv.Itoa(int(userID))), zap.String("target_user_id", targetUserIDStr))
 	targetUserID, err := strconv.ParseUint(targetUserIDStr, 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "无效的用户ID"})
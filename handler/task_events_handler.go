@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"papergraph/service"
+	"papergraph/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// taskEventsPollInterval SSE连接定期回查数据库的间隔
+// 任务可能由其他实例的工作池执行，本进程收不到事件，通过回查保证状态最终送达，同时作为心跳
+const taskEventsPollInterval = 5 * time.Second
+
+// TaskEventsHandler 任务进度事件推送
+type TaskEventsHandler struct {
+	events   *service.TaskEventBus
+	analysis *service.AnalysisService
+	logger   *zap.Logger
+	clock    service.Clock
+}
+
+// NewTaskEventsHandler 创建任务进度事件处理器
+func NewTaskEventsHandler(events *service.TaskEventBus, analysis *service.AnalysisService, logger *zap.Logger, clock service.Clock) *TaskEventsHandler {
+	return &TaskEventsHandler{events: events, analysis: analysis, logger: logger, clock: clock}
+}
+
+// Stream 以Server-Sent Events推送任务进度
+// GET /api/tasks/:id/events
+// 连接建立后先推送当前状态，之后推送每次状态变化，任务完成或失败后服务端关闭连接
+func (h *TaskEventsHandler) Stream(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		utils.Error(c, "未登录", 401)
+		return
+	}
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(c, "任务ID参数错误", 400)
+		return
+	}
+	task, err := h.analysis.GetAnalysisTaskDetail(uint(taskID))
+	if err != nil {
+		utils.Error(c, "任务不存在", 404)
+		return
+	}
+	if task.UserID != userID {
+		utils.Error(c, "无权查看该任务", 403)
+		return
+	}
+
+	// 先订阅再读取快照，避免两者之间的事件丢失
+	events, unsubscribe := h.events.Subscribe(task.ID)
+	defer unsubscribe()
+	task, err = h.analysis.GetAnalysisTaskDetail(task.ID)
+	if err != nil {
+		utils.Error(c, "任务不存在", 404)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭nginx缓冲
+	clearWriteDeadline(c)
+	h.logger.Info("任务事件订阅", zap.Uint("task_id", task.ID), zap.Uint("user_id", userID))
+
+	last := service.TaskEventFromTask(task, h.clock)
+	if !writeTaskEvent(c, last) || last.IsTerminal() {
+		return
+	}
+
+	ticker := time.NewTicker(taskEventsPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			// 客户端断开连接
+			h.logger.Info("任务事件订阅断开", zap.Uint("task_id", task.ID), zap.Uint("user_id", userID))
+			return
+		case event, ok := <-events:
+			if !ok {
+				// 服务正在退出，客户端会自动重连
+				return
+			}
+			last = event
+			if !writeTaskEvent(c, event) || event.IsTerminal() {
+				return
+			}
+		case <-ticker.C:
+			latest, err := h.analysis.GetAnalysisTaskDetail(task.ID)
+			if err != nil {
+				return
+			}
+			event := service.TaskEventFromTask(latest, h.clock)
+			if event.Status != last.Status || event.Stage != last.Stage || event.Progress != last.Progress {
+				last = event
+				if !writeTaskEvent(c, event) || event.IsTerminal() {
+					return
+				}
+				continue
+			}
+			// 心跳注释，防止代理因空闲断开连接
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// clearWriteDeadline 取消SSE连接的写超时，server.write_timeout只限制普通请求
+// 流式连接在客户端断开、任务结束或服务退出时结束
+func clearWriteDeadline(c *gin.Context) {
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+}
+
+// writeTaskEvent 写出一条SSE事件，事件名为任务阶段，写失败说明客户端已断开
+func writeTaskEvent(c *gin.Context, event service.TaskEvent) bool {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return false
+	}
+	name := event.Stage
+	if name == "" {
+		name = "status"
+	}
+	if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", name, data); err != nil {
+		return false
+	}
+	c.Writer.Flush()
+	return true
+}
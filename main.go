@@ -1,24 +1,234 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"papergraph/aitools"
 	"papergraph/config"
+	"papergraph/handler"
+	"papergraph/metrics"
 	"papergraph/router"
 	"papergraph/service"
+	"papergraph/storage"
+	"papergraph/utils"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 func main() {
-	// 初始化配置
-	config.Init()
-	defer config.Logger.Sync()
+	// 数据库迁移子命令：server migrate up|down|status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+
+	configFile := flag.String("config", "", "配置文件路径（YAML或TOML），为空时读取环境变量CONFIG_FILE或工作目录中的config.yaml")
+	profile := flag.String("profile", "", "运行环境：dev/test/prod，为空时读取环境变量APP_PROFILE，默认dev")
+	flag.Parse()
+
+	// 加载并校验配置
+	cfg, err := config.Load(*configFile, *profile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	logger, err := config.NewLogger(cfg.Log)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "日志初始化失败:", err)
+		os.Exit(1)
+	}
+	defer logger.Sync()
+	logger.Info("配置加载完成", zap.String("profile", cfg.Profile), zap.Stringer("config", cfg))
+	for _, warning := range cfg.Warnings() {
+		logger.Warn(warning)
+	}
+	if cfg.Profile == config.ProfileProd {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	db, err := config.InitDatabase(cfg, logger)
+	if err != nil {
+		logger.Fatal("初始化数据库失败", zap.Error(err))
+	}
+
+	// 初始化对象存储
+	store, err := newStorage(cfg.Storage)
+	if err != nil {
+		logger.Fatal("初始化对象存储失败", zap.Error(err))
+	}
+	logger.Info("对象存储初始化完成", zap.String("driver", cfg.Storage.Driver))
+
+	// 初始化大模型服务，同一提供方的模型共用一个熔断器
+	breakers := aitools.NewCircuitBreakers(cfg.LLM.CircuitFailureThreshold, cfg.LLM.CircuitCooldown)
+	newLLMProvider := llmProviderFactory(cfg, breakers)
+	provider, err := newLLMProvider(cfg.LLM.Provider, "")
+	if err != nil {
+		logger.Fatal("初始化大模型服务失败", zap.Error(err))
+	}
+	logger.Info("大模型服务初始化完成", zap.String("provider", provider.Name()), zap.String("model", provider.ModelName()))
+
+	// 初始化服务，分析工作池先创建，论文服务入队任务时通知它，执行流程依赖论文服务，最后启动工作池
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clock := service.SystemClock{}
+	defaultModel := service.AnalysisModel{Provider: provider.Name(), Name: provider.ModelName()}
+	if _, err := metrics.RegisterAnalysisQueue(metrics.Registry, db); err != nil {
+		logger.Fatal("注册分析队列指标失败", zap.Error(err))
+	}
+	taskEvents := service.NewTaskEventBus(clock)
+	worker := service.NewAnalysisWorker(db, logger, cfg.AnalysisWorker, taskEvents, clock)
+	paperSvc := service.NewPaperService(db, logger, store, worker, defaultModel, clock)
+	worker.Start(ctx, service.NewAnalysisPipeline(provider, newLLMProvider, paperSvc))
+
+	analysisSvc := service.NewAnalysisService(db, logger, worker, defaultModel, clock)
+	ticketSvc := service.NewUploadTicketService(db, logger, store, paperSvc, cfg.UploadTicket, clock)
+	subSvc := service.NewSubscriptionService(db)
+	badgeSvc := service.NewBadgeService(db)
+	socialSvc := service.NewSocialService(db)
+	activitySvc := service.NewUserActivityService(db)
+	chatSvc := service.NewChatService(db, logger, provider, paperSvc, cfg.Chat, clock)
 
-	// 初始化服务
-	subSvc := service.NewSubscriptionService(config.DB)
-	badgeSvc := service.NewBadgeService(config.DB)
-	activitySvc := service.NewUserActivityService(config.DB)
+	// 定期清理过期的直传票据和未确认的对象
+	ticketSvc.StartJanitor(ctx)
+	// 定期清理软删除的论文、无主记录和无主对象
+	service.NewStorageGC(db, logger, store, cfg.StorageGC, clock).Start(ctx)
 
 	// 初始化路由
-	r := router.InitRouter(subSvc, badgeSvc, activitySvc)
+	jwt := utils.NewJWT(cfg.JWT.Secret)
+	r := router.InitRouter(router.Handlers{
+		Auth:         handler.NewAuthHandler(service.NewUserService(db), jwt),
+		GoogleAuth:   handler.NewGoogleAuthHandler(service.NewGoogleOAuthService(db, logger, cfg.GoogleOAuth), jwt, logger),
+		Paper:        handler.NewPaperHandler(paperSvc, logger),
+		Import:       handler.NewImportHandler(service.NewPaperImportService(logger, paperSvc, cfg.Import), cfg.Import.Timeout, logger),
+		UploadTicket: handler.NewUploadTicketHandler(ticketSvc, logger),
+		Batch:        handler.NewBatchHandler(service.NewBatchService(db, logger, paperSvc, cfg.Batch, clock), cfg.Batch, logger),
+		Analysis:     handler.NewAnalysisHandler(analysisSvc, logger),
+		TaskEvents:   handler.NewTaskEventsHandler(taskEvents, analysisSvc, logger, clock),
+		Comment:      handler.NewCommentHandler(service.NewCommentService(db, logger, clock)),
+		Chat:         handler.NewChatHandler(chatSvc, logger),
+		Subscription: handler.NewSubscriptionHandler(subSvc),
+		Social:       handler.NewSocialHandler(socialSvc, badgeSvc, db, logger),
+		Evaluation:   handler.NewEvaluationHandler(db),
+		UserActivity: handler.NewUserActivityHandler(activitySvc),
+		Health:       handler.NewHealthHandler(db, store, breakers),
+	}, jwt, store)
 
 	// 启动服务
-	r.Run(":8080") // 默认8080端口
+	srv := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           r,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+	// SSE长连接不会自行结束，退出时关闭事件总线使其断开，否则Shutdown会一直等待
+	srv.RegisterOnShutdown(taskEvents.Close)
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info("HTTP服务启动", zap.String("addr", cfg.Server.Addr))
+		serveErr <- srv.ListenAndServe()
+	}()
+	select {
+	case err := <-serveErr:
+		logger.Fatal("服务启动失败", zap.Error(err))
+	case <-signalCtx.Done():
+	}
+	// 恢复默认信号处理，再次收到信号时立即退出
+	stopSignals()
+	shutdown(srv, worker, cancel, db, logger, cfg.Server.ShutdownTimeout)
+}
+
+// shutdown 优雅退出
+// HTTP服务停止接收新连接并等待处理中的请求，分析工作池停止领取新任务并等待执行中的任务，两者共用timeout；
+// 超时后断开剩余连接，中断的分析任务重新排队。最后停止后台清理任务并关闭数据库连接
+func shutdown(srv *http.Server, worker *service.AnalysisWorker, stopBackground context.CancelFunc, db *gorm.DB, logger *zap.Logger, timeout time.Duration) {
+	logger.Info("开始优雅退出", zap.Duration("timeout", timeout))
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := worker.Shutdown(ctx); err != nil {
+			logger.Warn("分析任务未在退出时限内完成，已重新排队", zap.Error(err))
+		}
+	}()
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.Warn("部分请求未在退出时限内完成，连接已断开", zap.Error(err))
+		srv.Close()
+	}
+	wg.Wait()
+	stopBackground()
+
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			logger.Warn("关闭数据库连接失败", zap.Error(err))
+		}
+	}
+	logger.Info("服务已退出")
+}
+
+// llmProviderFactory 返回按配置创建大模型服务提供方的函数，modelName非空时覆盖配置的模型
+// 创建的提供方带有熔断保护
+func llmProviderFactory(conf *config.Config, breakers *aitools.CircuitBreakers) service.ProviderFactory {
+	return func(name, modelName string) (aitools.LLMProvider, error) {
+		cfg := aitools.ProviderConfig{Provider: name}
+		switch name {
+		case aitools.ProviderGemini:
+			cfg.APIKey = conf.Gemini.APIKey
+			cfg.BaseURL = conf.Gemini.BaseURL
+			cfg.Model = conf.Gemini.Model
+		case aitools.ProviderQwen:
+			cfg.APIKey = conf.Qwen.APIKey
+			cfg.BaseURL = conf.Qwen.BaseURL
+			cfg.Model = conf.Qwen.Model
+		}
+		if modelName != "" {
+			cfg.Model = modelName
+		}
+		provider, err := aitools.NewProvider(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return breakers.Wrap(provider), nil
+	}
+}
+
+// newStorage 根据配置创建对象存储
+func newStorage(c config.StorageConfig) (storage.Storage, error) {
+	store, err := storage.New(storage.Config{
+		Driver:             c.Driver,
+		OSSEndpoint:        c.OSSEndpoint,
+		OSSAccessKeyID:     c.OSSAccessKeyID,
+		OSSAccessKeySecret: c.OSSAccessKeySecret,
+		OSSBucket:          c.OSSBucket,
+		LocalDir:           c.LocalDir,
+		LocalBaseURL:       c.LocalBaseURL,
+		LocalSigningKey:    c.LocalSigningKey,
+		S3Endpoint:         c.S3Endpoint,
+		S3Region:           c.S3Region,
+		S3AccessKeyID:      c.S3AccessKeyID,
+		S3SecretAccessKey:  c.S3SecretAccessKey,
+		S3Bucket:           c.S3Bucket,
+		S3UseSSL:           c.S3UseSSL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if local, ok := store.(*storage.LocalStorage); ok {
+		local.MaxPutBytes = service.MaxPDFSize
+	}
+	return store, nil
 }
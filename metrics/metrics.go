@@ -0,0 +1,93 @@
+// Package metrics Prometheus监控指标
+// 指标注册到独立的Registry，由/metrics接口导出；业务代码直接使用包内的指标变量记录
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "papergraph"
+
+// Registry 本服务的指标注册表，包含Go运行时和进程指标
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequestDuration HTTP请求耗时，route为路由模板，未匹配的路由记为unmatched
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP请求处理耗时",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"method", "route", "status"})
+
+	// AnalysisTaskDuration 分析任务单次执行耗时，不含被取消或退出时中断的执行
+	AnalysisTaskDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "analysis",
+		Name:      "task_duration_seconds",
+		Help:      "分析任务单次执行耗时",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 12), // 1秒到约34分钟
+	}, []string{"provider", "type"})
+
+	// AnalysisTaskFailures 分析任务执行失败次数，每次失败的执行都计入，包括之后会重试的
+	AnalysisTaskFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "analysis",
+		Name:      "task_failures_total",
+		Help:      "分析任务执行失败次数",
+	}, []string{"provider", "type"})
+
+	// LLMTokens 大模型token用量，以提供方报告的用量为准，未报告时按字符数估算，direction为input/output
+	LLMTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "tokens_total",
+		Help:      "大模型token用量",
+	}, []string{"provider", "direction"})
+
+	// UploadBytes 上传论文文件的大小，包括直接上传、直传、导入和批量上传
+	UploadBytes = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "paper",
+		Name:      "upload_size_bytes",
+		Help:      "上传论文文件的大小",
+		Buckets:   prometheus.ExponentialBuckets(64*1024, 2, 10), // 64KB到32MB
+	})
+
+	// SubscriptionPurchases 订阅购买次数
+	SubscriptionPurchases = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "subscription",
+		Name:      "purchases_total",
+		Help:      "订阅购买次数",
+	}, []string{"product"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestDuration,
+		AnalysisTaskDuration,
+		AnalysisTaskFailures,
+		LLMTokens,
+		UploadBytes,
+		SubscriptionPurchases,
+	)
+}
+
+// Handler 导出指标的HTTP处理器
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// AddLLMTokens 累加一次模型调用的token用量
+func AddLLMTokens(provider string, input, output int) {
+	LLMTokens.WithLabelValues(provider, "input").Add(float64(input))
+	LLMTokens.WithLabelValues(provider, "output").Add(float64(output))
+}
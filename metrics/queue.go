@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"papergraph/model"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+// AnalysisQueueCollector 分析队列长度，每次采集时从数据库统计，多实例部署时各实例导出的值相同
+type AnalysisQueueCollector struct {
+	db    atomic.Pointer[gorm.DB]
+	depth *prometheus.Desc
+}
+
+// NewAnalysisQueueCollector 创建分析队列长度采集器
+func NewAnalysisQueueCollector(db *gorm.DB) *AnalysisQueueCollector {
+	c := &AnalysisQueueCollector{
+		depth: prometheus.NewDesc(prometheus.BuildFQName(namespace, "analysis", "queue_depth"),
+			"分析任务队列长度，status为queued（排队中）或running（执行中）", []string{"status"}, nil),
+	}
+	c.db.Store(db)
+	return c
+}
+
+// SetDB 切换统计使用的数据库连接，之后的采集使用新连接
+func (c *AnalysisQueueCollector) SetDB(db *gorm.DB) {
+	c.db.Store(db)
+}
+
+// RegisterAnalysisQueue 将分析队列长度指标注册到reg
+// 已注册过时不报错，改为让已注册的采集器使用新的数据库连接，可以重复调用
+func RegisterAnalysisQueue(reg prometheus.Registerer, db *gorm.DB) (*AnalysisQueueCollector, error) {
+	c := NewAnalysisQueueCollector(db)
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if !errors.As(err, &are) {
+			return nil, err
+		}
+		existing, ok := are.ExistingCollector.(*AnalysisQueueCollector)
+		if !ok {
+			return nil, err
+		}
+		existing.SetDB(db)
+		return existing, nil
+	}
+	return c, nil
+}
+
+// Describe 实现prometheus.Collector
+func (c *AnalysisQueueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.depth
+}
+
+// Collect 实现prometheus.Collector，查询失败时跳过该指标
+func (c *AnalysisQueueCollector) Collect(ch chan<- prometheus.Metric) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	err := c.db.Load().Model(&model.AnalysisTask{}).Select("status, count(*) AS count").
+		Where("status IN ?", []string{model.TaskStatusQueued, model.TaskStatusRunning}).
+		Group("status").Scan(&rows).Error
+	if err != nil {
+		// 数据库不可用由/readyz报告，不影响其他指标的导出
+		return
+	}
+	counts := map[string]int64{}
+	for _, r := range rows {
+		counts[r.Status] = r.Count
+	}
+	ch <- prometheus.MustNewConstMetric(c.depth, prometheus.GaugeValue, float64(counts[model.TaskStatusQueued]), "queued")
+	ch <- prometheus.MustNewConstMetric(c.depth, prometheus.GaugeValue, float64(counts[model.TaskStatusRunning]), "running")
+}
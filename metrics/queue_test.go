@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"papergraph/config"
+	"papergraph/model"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	cfg := config.Default(config.ProfileTest)
+	db, err := config.InitDatabase(&cfg, zap.NewNop())
+	if err != nil {
+		t.Fatalf("初始化测试数据库失败: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// expectedQueueDepth 队列长度指标的期望导出内容
+func expectedQueueDepth(queued, running int) string {
+	return fmt.Sprintf(`# HELP papergraph_analysis_queue_depth 分析任务队列长度，status为queued（排队中）或running（执行中）
+# TYPE papergraph_analysis_queue_depth gauge
+papergraph_analysis_queue_depth{status="queued"} %d
+papergraph_analysis_queue_depth{status="running"} %d
+`, queued, running)
+}
+
+func TestRegisterAnalysisQueueTwice(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	first, second := newTestDB(t), newTestDB(t)
+	if err := second.Create(&model.AnalysisTask{Status: model.TaskStatusQueued}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	collector, err := RegisterAnalysisQueue(reg, first)
+	if err != nil {
+		t.Fatalf("注册失败: %v", err)
+	}
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expectedQueueDepth(0, 0))); err != nil {
+		t.Fatalf("第一个数据库没有排队任务: %v", err)
+	}
+
+	// 再次注册不应panic，已注册的采集器改用新的数据库
+	again, err := RegisterAnalysisQueue(reg, second)
+	if err != nil {
+		t.Fatalf("重复注册应成功: %v", err)
+	}
+	if again != collector {
+		t.Error("重复注册应返回已注册的采集器")
+	}
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expectedQueueDepth(1, 0))); err != nil {
+		t.Errorf("应统计新数据库中的排队任务: %v", err)
+	}
+}
@@ -14,9 +14,15 @@ const UserIDKey = "user_id"
 
 // AuthMiddleware JWT鉴权中间件
 // 校验Authorization头部的Bearer Token，将用户信息注入上下文
-func AuthMiddleware() gin.HandlerFunc {
+// 浏览器EventSource无法设置请求头，SSE请求允许通过access_token查询参数传递token
+func AuthMiddleware(jwt *utils.JWT) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" && strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+			if token := c.Query("access_token"); token != "" {
+				authHeader = "Bearer " + token
+			}
+		}
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "未提供有效的token"})
 			c.Abort()
@@ -24,7 +30,7 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 		
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		claims, err := utils.ParseToken(tokenString)
+		claims, err := jwt.ParseToken(tokenString)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "token无效或已过期"})
 			c.Abort()
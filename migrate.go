@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"papergraph/config"
+	"papergraph/migrations"
+)
+
+const migrateUsage = `用法: server migrate [-config 配置文件] [-profile dev|test|prod] <命令>
+
+命令:
+  up               执行全部未执行的迁移
+  down [-steps N]  回滚最近执行的N个迁移，默认1个
+  status           查看迁移执行状态
+`
+
+// runMigrate 执行migrate子命令，返回进程退出码
+func runMigrate(args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, migrateUsage) }
+	configFile := fs.String("config", "", "配置文件路径")
+	profile := fs.String("profile", "", "运行环境")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	command, rest := fs.Arg(0), fs.Args()[1:]
+
+	cfg, err := config.Load(*configFile, *profile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	db, err := config.OpenDatabase(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "数据库连接失败:", err)
+		return 1
+	}
+	migrator, err := migrations.New(db, cfg.Database.Driver)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up()
+		printMigrations("已执行", applied)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("数据库结构已是最新版本")
+		}
+	case "down":
+		downFlags := flag.NewFlagSet("down", flag.ContinueOnError)
+		steps := downFlags.Int("steps", 1, "回滚的迁移数量")
+		if err := downFlags.Parse(rest); err != nil {
+			return 2
+		}
+		reverted, err := migrator.Down(*steps)
+		printMigrations("已回滚", reverted)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(reverted) == 0 {
+			fmt.Println("没有可回滚的迁移")
+		}
+	case "status":
+		list, err := migrator.Status()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "版本\t名称\t状态\t执行时间")
+		for _, s := range list {
+			state, appliedAt := "未执行", ""
+			if s.Applied {
+				state, appliedAt = "已执行", s.AppliedAt.Local().Format(time.DateTime)
+			}
+			if s.ChecksumMismatch {
+				state = "已执行，文件已被修改"
+			}
+			if s.Unknown {
+				state = "已执行，程序中不存在"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+		}
+		w.Flush()
+		if err := migrator.Check(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "未知的migrate命令: %s\n", command)
+		fs.Usage()
+		return 2
+	}
+	return 0
+}
+
+// printMigrations 输出本次执行或回滚的迁移
+func printMigrations(action string, list []migrations.Migration) {
+	for _, m := range list {
+		fmt.Printf("%s %d_%s\n", action, m.Version, m.Name)
+	}
+}
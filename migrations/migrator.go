@@ -0,0 +1,212 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 数据库结构检查错误
+var (
+	ErrPendingMigrations = errors.New("数据库结构版本落后，请先执行 migrate up")
+	ErrChecksumMismatch  = errors.New("已执行的迁移文件被修改")
+	ErrUnknownMigration  = errors.New("数据库中存在当前程序不认识的迁移版本，请升级程序")
+)
+
+// createVersionTable 记录已执行迁移的表，MySQL和SQLite通用
+const createVersionTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+  version BIGINT NOT NULL PRIMARY KEY,
+  name VARCHAR(255) NOT NULL,
+  checksum VARCHAR(64) NOT NULL,
+  applied_at DATETIME NOT NULL
+)`
+
+// schemaMigration schema_migrations表中的一条记录
+type schemaMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// TableName 表名
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status 单个迁移的执行状态
+type Status struct {
+	Version          int64
+	Name             string
+	Applied          bool
+	AppliedAt        time.Time
+	ChecksumMismatch bool // 执行后迁移文件被修改
+	Unknown          bool // 数据库中已执行但程序中不存在的版本
+}
+
+// Migrator 执行和检查数据库迁移
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// New 创建Migrator实例，dialect为数据库类型：mysql/sqlite
+func New(db *gorm.DB, dialect string) (*Migrator, error) {
+	migrations, err := Load(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// applied 读取已执行的迁移，按版本号索引
+func (m *Migrator) applied() (map[int64]schemaMigration, error) {
+	if err := m.db.Exec(createVersionTable).Error; err != nil {
+		return nil, fmt.Errorf("创建schema_migrations表失败: %w", err)
+	}
+	var records []schemaMigration
+	if err := m.db.Order("version asc").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]schemaMigration, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// Status 返回全部迁移的执行状态，按版本号升序
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Status, 0, len(m.migrations))
+	known := make(map[int64]bool, len(m.migrations))
+	for _, mg := range m.migrations {
+		known[mg.Version] = true
+		s := Status{Version: mg.Version, Name: mg.Name}
+		if r, ok := applied[mg.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = r.AppliedAt
+			s.ChecksumMismatch = r.Checksum != mg.Checksum
+		}
+		list = append(list, s)
+	}
+	for version, r := range applied {
+		if !known[version] {
+			list = append(list, Status{Version: version, Name: r.Name, Applied: true, AppliedAt: r.AppliedAt, Unknown: true})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// Check 检查数据库结构是否为最新版本，存在未执行、被修改或不认识的迁移时返回错误
+func (m *Migrator) Check() error {
+	list, err := m.Status()
+	if err != nil {
+		return err
+	}
+	pending := 0
+	for _, s := range list {
+		switch {
+		case s.Unknown:
+			return fmt.Errorf("%w: %d_%s", ErrUnknownMigration, s.Version, s.Name)
+		case s.ChecksumMismatch:
+			return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, s.Version, s.Name)
+		case !s.Applied:
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%w（%d个未执行）", ErrPendingMigrations, pending)
+	}
+	return nil
+}
+
+// Up 按版本号顺序执行全部未执行的迁移，返回本次执行的迁移
+// 每个迁移在一个事务中执行并记录版本；MySQL的DDL会隐式提交，失败时可能部分生效，迁移脚本应可重复执行（如CREATE TABLE IF NOT EXISTS）
+func (m *Migrator) Up() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	for version, r := range applied {
+		if !m.known(version) {
+			return nil, fmt.Errorf("%w: %d_%s", ErrUnknownMigration, version, r.Name)
+		}
+	}
+	var done []Migration
+	for _, mg := range m.migrations {
+		if r, ok := applied[mg.Version]; ok {
+			if r.Checksum != mg.Checksum {
+				return done, fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, mg.Version, mg.Name)
+			}
+			continue
+		}
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := execScript(tx, mg.Up); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: mg.Version, Name: mg.Name, Checksum: mg.Checksum, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("执行迁移%d_%s失败: %w", mg.Version, mg.Name, err)
+		}
+		done = append(done, mg)
+	}
+	return done, nil
+}
+
+// Down 按版本号倒序回滚最近执行的steps个迁移，返回本次回滚的迁移
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	if steps <= 0 {
+		return nil, errors.New("回滚数量需大于0")
+	}
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		mg := m.migrations[i]
+		if _, ok := applied[mg.Version]; !ok {
+			continue
+		}
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := execScript(tx, mg.Down); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, mg.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("回滚迁移%d_%s失败: %w", mg.Version, mg.Name, err)
+		}
+		done = append(done, mg)
+	}
+	return done, nil
+}
+
+// known 程序中是否存在该版本的迁移
+func (m *Migrator) known(version int64) bool {
+	for _, mg := range m.migrations {
+		if mg.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// execScript 逐条执行迁移脚本中的SQL
+func execScript(tx *gorm.DB, script string) error {
+	for _, stmt := range splitStatements(script) {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package migrations
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"papergraph/model"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB 打开空的SQLite内存数据库
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:?_pragma=foreign_keys(1)"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// tables 返回数据库中的业务表，不含迁移记录表和SQLite内部表
+func tables(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+	var names []string
+	if err := db.Raw("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name != 'schema_migrations' ORDER BY name").Scan(&names).Error; err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestUpStatusDownUp(t *testing.T) {
+	db := newTestDB(t)
+	m, err := New(db, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := len(m.migrations)
+	if total == 0 {
+		t.Fatal("应至少有一个迁移")
+	}
+	if err := m.Check(); !errors.Is(err, ErrPendingMigrations) {
+		t.Fatalf("未迁移的数据库应返回ErrPendingMigrations，实际为%v", err)
+	}
+
+	done, err := m.Up()
+	if err != nil || len(done) != total {
+		t.Fatalf("应执行全部%d个迁移，实际为%d, err=%v", total, len(done), err)
+	}
+	status, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range status {
+		if !s.Applied || s.ChecksumMismatch || s.Unknown || s.AppliedAt.IsZero() {
+			t.Errorf("迁移%d_%s状态错误: %+v", s.Version, s.Name, s)
+		}
+	}
+	if err := m.Check(); err != nil {
+		t.Fatalf("迁移后检查应通过: %v", err)
+	}
+	if done, err := m.Up(); err != nil || len(done) != 0 {
+		t.Errorf("重复执行不应再执行迁移，实际为%d, err=%v", len(done), err)
+	}
+	migrated := tables(t, db)
+
+	last := m.migrations[total-1]
+	done, err = m.Down(1)
+	if err != nil || len(done) != 1 || done[0].Version != last.Version {
+		t.Fatalf("应回滚最后一个迁移，实际为%v, err=%v", done, err)
+	}
+	status, _ = m.Status()
+	if status[total-1].Applied || !status[0].Applied {
+		t.Errorf("只有最后一个迁移应变为未执行: %+v", status)
+	}
+	if err := m.Check(); !errors.Is(err, ErrPendingMigrations) {
+		t.Errorf("回滚后检查应返回ErrPendingMigrations，实际为%v", err)
+	}
+
+	if done, err := m.Down(total + 1); err != nil || len(done) != total-1 {
+		t.Fatalf("应回滚剩余的%d个迁移，实际为%d, err=%v", total-1, len(done), err)
+	}
+	if left := tables(t, db); len(left) != 0 {
+		t.Errorf("全部回滚后不应留下业务表，实际为%v", left)
+	}
+	if _, err := m.Down(0); err == nil {
+		t.Error("回滚数量为0时应返回错误")
+	}
+
+	if done, err := m.Up(); err != nil || len(done) != total {
+		t.Fatalf("回滚后应能重新执行全部迁移，实际为%d, err=%v", len(done), err)
+	}
+	if again := tables(t, db); strings.Join(again, ",") != strings.Join(migrated, ",") {
+		t.Errorf("重新迁移后的表与首次迁移不一致: %v != %v", again, migrated)
+	}
+}
+
+func TestEditedMigrationIsDetected(t *testing.T) {
+	db := newTestDB(t)
+	m, err := New(db, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟已执行的迁移文件被修改后重新发布
+	edited := &Migrator{db: db, migrations: append([]Migration(nil), m.migrations...)}
+	first := &edited.migrations[0]
+	first.Up += "\n-- edited\n"
+	sum := sha256.Sum256([]byte(first.Up))
+	first.Checksum = hex.EncodeToString(sum[:])
+
+	status, err := edited.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status[0].ChecksumMismatch || status[1].ChecksumMismatch {
+		t.Errorf("只有被修改的迁移应标记为不一致: %+v", status)
+	}
+	if err := edited.Check(); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("检查应返回ErrChecksumMismatch，实际为%v", err)
+	}
+	if _, err := edited.Up(); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("执行迁移应返回ErrChecksumMismatch，实际为%v", err)
+	}
+}
+
+func TestUnknownMigrationIsRejected(t *testing.T) {
+	db := newTestDB(t)
+	m, err := New(db, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	// 新版本程序执行过的迁移，旧版本程序不认识
+	if err := db.Create(&schemaMigration{Version: 9999, Name: "future", Checksum: "x"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Check(); !errors.Is(err, ErrUnknownMigration) {
+		t.Errorf("检查应返回ErrUnknownMigration，实际为%v", err)
+	}
+	if _, err := m.Up(); !errors.Is(err, ErrUnknownMigration) {
+		t.Errorf("执行迁移应返回ErrUnknownMigration，实际为%v", err)
+	}
+}
+
+// models 全部持久化的模型，新增模型时需同时新增迁移并加入此列表
+var models = []any{
+	&model.User{}, &model.PasswordResetToken{}, &model.Paper{}, &model.PaperContent{},
+	&model.AnalysisTask{}, &model.AnalysisResult{}, &model.UploadBatch{}, &model.UploadBatchItem{}, &model.UploadTicket{},
+	&model.ChatThread{}, &model.ChatMessage{}, &model.ChatUsage{}, &model.Comment{},
+	&model.Product{}, &model.UserSubscription{}, &model.PaymentRecord{},
+	&model.BadgeTemplate{}, &model.UserBadge{}, &model.UserStats{}, &model.UserActivity{}, &model.UserFollow{}, &model.TaskReaction{},
+	&model.PaperEvaluation{}, &model.EvaluationDimension{}, &model.EvaluationMetric{}, &model.EvaluationComment{}, &model.EvaluationLike{},
+	&model.Email{}, &model.EmailAnalysis{}, &model.EmailDraft{}, &model.EmailFilter{},
+}
+
+func TestMigratedSchemaMatchesModels(t *testing.T) {
+	db := newTestDB(t)
+	m, err := New(db, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	var modelTables []string
+	for _, v := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(v); err != nil {
+			t.Fatalf("解析模型%T失败: %v", v, err)
+		}
+		table := stmt.Schema.Table
+		modelTables = append(modelTables, table)
+		if !db.Migrator().HasTable(table) {
+			t.Errorf("迁移缺少模型%T的表%s", v, table)
+			continue
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !db.Migrator().HasColumn(v, field.DBName) {
+				t.Errorf("表%s缺少模型%T的字段%s", table, v, field.DBName)
+			}
+		}
+		for _, idx := range stmt.Schema.ParseIndexes() {
+			if !db.Migrator().HasIndex(v, idx.Name) {
+				t.Errorf("表%s缺少模型%T的索引%s", table, v, idx.Name)
+			}
+		}
+	}
+	sort.Strings(modelTables)
+	if migrated := tables(t, db); strings.Join(migrated, ",") != strings.Join(modelTables, ",") {
+		t.Errorf("迁移创建的表与模型不一致:\n迁移: %v\n模型: %v", migrated, modelTables)
+	}
+}
+
+// createTablePattern 迁移脚本中创建的表名
+var createTablePattern = regexp.MustCompile("(?i)CREATE TABLE IF NOT EXISTS `(\\w+)`")
+
+func TestDialectsCreateSameTables(t *testing.T) {
+	created := map[string]string{}
+	for _, dialect := range []string{"mysql", "sqlite"} {
+		list, err := Load(dialect)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, mg := range list {
+			for _, match := range createTablePattern.FindAllStringSubmatch(mg.Up, -1) {
+				names = append(names, match[1])
+			}
+		}
+		sort.Strings(names)
+		created[dialect] = strings.Join(names, ",")
+	}
+	if created["mysql"] != created["sqlite"] {
+		t.Errorf("MySQL和SQLite的迁移创建的表不一致:\nmysql:  %s\nsqlite: %s", created["mysql"], created["sqlite"])
+	}
+}
@@ -0,0 +1,544 @@
+-- 基线结构：与引入迁移前AutoMigrate创建的表结构一致，已有数据库执行时跳过已存在的表
+
+CREATE TABLE IF NOT EXISTS `users` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `email` varchar(128),
+  `password` varchar(255),
+  `gmail` varchar(128),
+  `name` varchar(64),
+  `avatar` varchar(256),
+  `institution` varchar(128),
+  `position` varchar(64),
+  `field` varchar(64),
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `last_login` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `free_trial_count` bigint DEFAULT 3,
+  `auth_provider` varchar(20) DEFAULT 'email',
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_users_email` (`email`),
+  UNIQUE INDEX `idx_users_gmail` (`gmail`),
+  INDEX `idx_users_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `papers` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned,
+  `file_name` varchar(256),
+  `oss_path` varchar(512),
+  `file_size` bigint,
+  `page_count` bigint,
+  `content_hash` varchar(64),
+  `status` varchar(32),
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_papers_user_id` (`user_id`),
+  INDEX `idx_papers_content_hash` (`content_hash`),
+  INDEX `idx_papers_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `paper_contents` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `paper_id` bigint unsigned,
+  `title` varchar(512),
+  `abstract` text,
+  `pages` longtext,
+  `sections` text,
+  `references` longtext,
+  `char_count` bigint,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_paper_contents_paper_id` (`paper_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `chat_threads` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned,
+  `paper_id` bigint unsigned,
+  `title` varchar(128),
+  `message_count` bigint DEFAULT 0,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_chat_threads_user_id` (`user_id`),
+  INDEX `idx_chat_threads_paper_id` (`paper_id`),
+  INDEX `idx_chat_threads_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `chat_messages` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `thread_id` bigint unsigned,
+  `user_id` bigint unsigned,
+  `role` varchar(16),
+  `content` text,
+  `citations` text,
+  `provider` varchar(32),
+  `model` varchar(64),
+  `created_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_chat_messages_thread_id` (`thread_id`),
+  INDEX `idx_chat_messages_user_id` (`user_id`),
+  INDEX `idx_chat_messages_created_at` (`created_at`),
+  INDEX `idx_chat_messages_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `analysis_tasks` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned,
+  `paper_id` bigint unsigned,
+  `type` varchar(16) DEFAULT 'analysis',
+  `paper_ids` text,
+  `status` varchar(32),
+  `stage` varchar(32),
+  `progress` bigint,
+  `attempts` bigint DEFAULT 0,
+  `last_error` text,
+  `worker_id` varchar(64),
+  `next_run_at` datetime(3) NULL,
+  `started_at` datetime(3) NULL,
+  `heartbeat_at` datetime(3) NULL,
+  `provider` varchar(32),
+  `model` varchar(64),
+  `force_fresh` boolean DEFAULT false,
+  `is_public` boolean DEFAULT false,
+  `suggest_score` bigint,
+  `like_count` bigint,
+  `read_count` bigint,
+  `created_at` datetime(3) NULL,
+  `finished_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_analysis_tasks_user_id` (`user_id`),
+  INDEX `idx_analysis_tasks_paper_id` (`paper_id`),
+  INDEX `idx_analysis_tasks_status` (`status`),
+  INDEX `idx_analysis_tasks_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `analysis_results` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `task_id` bigint unsigned,
+  `version` bigint DEFAULT 1,
+  `provider` varchar(32),
+  `content` text,
+  `analysis` text,
+  `comparison` text,
+  `model` varchar(64),
+  `prompt_version` varchar(32),
+  `usage` text,
+  `content_hash` varchar(64),
+  `cached_from_id` bigint unsigned,
+  `created_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_analysis_results_task_id` (`task_id`),
+  INDEX `idx_analysis_results_content_hash` (`content_hash`),
+  INDEX `idx_analysis_results_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `upload_batches` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned,
+  `name` varchar(256),
+  `total_files` bigint,
+  `accepted_files` bigint,
+  `charged_trials` bigint,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_upload_batches_user_id` (`user_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `upload_batch_items` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `batch_id` bigint unsigned,
+  `file_name` varchar(256),
+  `paper_id` bigint unsigned,
+  `task_id` bigint unsigned,
+  `error` varchar(512),
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_upload_batch_items_batch_id` (`batch_id`),
+  INDEX `idx_upload_batch_items_task_id` (`task_id`),
+  CONSTRAINT `fk_upload_batches_items` FOREIGN KEY (`batch_id`) REFERENCES `upload_batches`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `upload_tickets` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `token` varchar(64),
+  `user_id` bigint unsigned,
+  `file_name` varchar(256),
+  `object_key` varchar(512),
+  `file_size` bigint,
+  `content_hash` varchar(64),
+  `status` varchar(16),
+  `error` varchar(512),
+  `paper_id` bigint unsigned,
+  `task_id` bigint unsigned,
+  `expires_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_upload_tickets_token` (`token`),
+  INDEX `idx_upload_tickets_user_id` (`user_id`),
+  INDEX `idx_ticket_status_expires` (`status`,`expires_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `comments` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `task_id` bigint unsigned,
+  `user_id` bigint unsigned,
+  `content` text,
+  `parent_id` bigint unsigned,
+  `created_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_comments_task_id` (`task_id`),
+  INDEX `idx_comments_user_id` (`user_id`),
+  INDEX `idx_comments_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `products` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `name` longtext,
+  `price` double,
+  `duration` bigint,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `user_subscriptions` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned,
+  `product_id` bigint unsigned,
+  `start_time` datetime(3) NULL,
+  `end_time` datetime(3) NULL,
+  `status` longtext,
+  PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `payment_records` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned,
+  `product_id` bigint unsigned,
+  `amount` double,
+  `pay_method` longtext,
+  `pay_time` datetime(3) NULL,
+  `status` longtext,
+  `trade_no` longtext,
+  PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `badge_templates` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `type` varchar(32),
+  `name` varchar(64),
+  `description` text,
+  `icon` varchar(128),
+  `condition` text,
+  `level` bigint,
+  `category` varchar(32),
+  `created_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_badge_templates_type` (`type`),
+  INDEX `idx_badge_templates_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `user_badges` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned,
+  `badge_type` varchar(32),
+  `name` varchar(64),
+  `description` varchar(256),
+  `icon` varchar(128),
+  `level` bigint,
+  `created_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_user_badges_user_id` (`user_id`),
+  INDEX `idx_user_badges_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `user_stats` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned,
+  `analysis_count` bigint DEFAULT 0,
+  `public_analysis_count` bigint DEFAULT 0,
+  `like_count` bigint DEFAULT 0,
+  `comment_count` bigint DEFAULT 0,
+  `follower_count` bigint DEFAULT 0,
+  `following_count` bigint DEFAULT 0,
+  `share_count` bigint DEFAULT 0,
+  `total_score` bigint DEFAULT 0,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_user_stats_user_id` (`user_id`),
+  INDEX `idx_user_stats_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `user_activities` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `event_type` varchar(32) NOT NULL,
+  `target_type` varchar(32) NOT NULL,
+  `target_id` bigint unsigned NOT NULL,
+  `title` varchar(256),
+  `content` text,
+  `metadata` text,
+  `visibility` varchar(16) DEFAULT 'public',
+  `like_count` bigint DEFAULT 0,
+  `comment_count` bigint DEFAULT 0,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_user_activities_user_id` (`user_id`),
+  INDEX `idx_user_activities_event_type` (`event_type`),
+  INDEX `idx_user_activities_target_type` (`target_type`),
+  INDEX `idx_user_activities_target_id` (`target_id`),
+  INDEX `idx_user_activities_visibility` (`visibility`),
+  INDEX `idx_user_activities_deleted_at` (`deleted_at`),
+  CONSTRAINT `fk_user_activities_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `user_follows` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `follower_id` bigint unsigned,
+  `following_id` bigint unsigned,
+  `created_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_user_follows_follower_id` (`follower_id`),
+  INDEX `idx_user_follows_following_id` (`following_id`),
+  INDEX `idx_user_follows_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `task_reactions` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `task_id` bigint unsigned,
+  `user_id` bigint unsigned,
+  `reaction_type` varchar(32),
+  `created_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_task_reactions_task_id` (`task_id`),
+  INDEX `idx_task_reactions_user_id` (`user_id`),
+  INDEX `idx_task_reactions_reaction_type` (`reaction_type`),
+  INDEX `idx_task_reactions_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `paper_evaluations` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `analysis_id` bigint unsigned,
+  `user_id` bigint unsigned,
+  `paper_id` bigint unsigned,
+  `source` varchar(16) DEFAULT 'user',
+  `overall_score` decimal(5,2),
+  `summary` text,
+  `recommendation` text,
+  `originality_score` decimal(5,2),
+  `depth_score` decimal(5,2),
+  `logic_score` decimal(5,2),
+  `evidence_score` decimal(5,2),
+  `language_score` decimal(5,2),
+  `value_score` decimal(5,2),
+  `content_score` decimal(5,2),
+  `structure_score` decimal(5,2),
+  `method_score` decimal(5,2),
+  `is_public` boolean DEFAULT false,
+  `is_verified` boolean DEFAULT false,
+  `like_count` bigint DEFAULT 0,
+  `comment_count` bigint DEFAULT 0,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_paper_evaluations_analysis_id` (`analysis_id`),
+  INDEX `idx_paper_evaluations_user_id` (`user_id`),
+  INDEX `idx_paper_evaluations_paper_id` (`paper_id`),
+  INDEX `idx_paper_evaluations_deleted_at` (`deleted_at`),
+  CONSTRAINT `fk_paper_evaluations_paper` FOREIGN KEY (`paper_id`) REFERENCES `papers`(`id`),
+  CONSTRAINT `fk_paper_evaluations_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `evaluation_dimensions` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `evaluation_id` bigint unsigned,
+  `dimension_key` varchar(32),
+  `dimension_name` varchar(64),
+  `score` decimal(5,2),
+  `description` text,
+  `evidence` text,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_evaluation_dimensions_evaluation_id` (`evaluation_id`),
+  INDEX `idx_evaluation_dimensions_deleted_at` (`deleted_at`),
+  CONSTRAINT `fk_paper_evaluations_dimensions` FOREIGN KEY (`evaluation_id`) REFERENCES `paper_evaluations`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `evaluation_metrics` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `dimension_id` bigint unsigned,
+  `metric_key` varchar(32),
+  `metric_name` varchar(64),
+  `score` decimal(5,2),
+  `description` text,
+  `evidence` text,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_evaluation_metrics_dimension_id` (`dimension_id`),
+  INDEX `idx_evaluation_metrics_deleted_at` (`deleted_at`),
+  CONSTRAINT `fk_evaluation_dimensions_metrics` FOREIGN KEY (`dimension_id`) REFERENCES `evaluation_dimensions`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `evaluation_comments` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `evaluation_id` bigint unsigned,
+  `user_id` bigint unsigned,
+  `content` text,
+  `like_count` bigint DEFAULT 0,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_evaluation_comments_evaluation_id` (`evaluation_id`),
+  INDEX `idx_evaluation_comments_user_id` (`user_id`),
+  INDEX `idx_evaluation_comments_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `evaluation_likes` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `evaluation_id` bigint unsigned,
+  `user_id` bigint unsigned,
+  `created_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_evaluation_likes_evaluation_id` (`evaluation_id`),
+  INDEX `idx_evaluation_likes_user_id` (`user_id`),
+  INDEX `idx_evaluation_likes_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `emails` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `gmail_id` varchar(255),
+  `thread_id` varchar(255),
+  `subject` varchar(500),
+  `from_email` varchar(255),
+  `from_name` varchar(255),
+  `to_email` varchar(255),
+  `to_name` varchar(255),
+  `date_sent` datetime(3) NULL,
+  `body` text,
+  `body_html` longtext,
+  `snippet` varchar(1000),
+  `size_estimate` bigint,
+  `label_ids` text,
+  `user_id` bigint unsigned,
+  `is_analyzed` boolean DEFAULT false,
+  `analysis_result` text,
+  `category` varchar(100),
+  `priority` varchar(50),
+  `tags` text,
+  `is_read` boolean DEFAULT false,
+  `is_important` boolean DEFAULT false,
+  `is_archived` boolean DEFAULT false,
+  `word_count` bigint,
+  `attachment_count` bigint,
+  PRIMARY KEY (`id`),
+  INDEX `idx_emails_deleted_at` (`deleted_at`),
+  UNIQUE INDEX `idx_emails_gmail_id` (`gmail_id`),
+  INDEX `idx_emails_thread_id` (`thread_id`),
+  INDEX `idx_emails_from_email` (`from_email`),
+  INDEX `idx_emails_to_email` (`to_email`),
+  INDEX `idx_emails_date_sent` (`date_sent`),
+  INDEX `idx_emails_user_id` (`user_id`),
+  CONSTRAINT `fk_emails_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `email_analyses` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `email_id` bigint unsigned,
+  `sentiment` varchar(50),
+  `sentiment_score` double,
+  `language` varchar(10),
+  `keywords` text,
+  `summary` text,
+  `topics` text,
+  `entities` text,
+  `generated_draft` text,
+  `suggestions` text,
+  `confidence` double,
+  `quality` bigint,
+  PRIMARY KEY (`id`),
+  INDEX `idx_email_analyses_deleted_at` (`deleted_at`),
+  INDEX `idx_email_analyses_email_id` (`email_id`),
+  CONSTRAINT `fk_email_analyses_email` FOREIGN KEY (`email_id`) REFERENCES `emails`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `email_drafts` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `user_id` bigint unsigned,
+  `original_email_id` bigint unsigned,
+  `to_email` varchar(255),
+  `subject` varchar(500),
+  `body` text,
+  `body_html` longtext,
+  `prompt` text,
+  `ai_model` varchar(100),
+  `status` varchar(50) DEFAULT 'draft',
+  `is_sent` boolean DEFAULT false,
+  `sent_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_email_drafts_deleted_at` (`deleted_at`),
+  INDEX `idx_email_drafts_user_id` (`user_id`),
+  INDEX `idx_email_drafts_original_email_id` (`original_email_id`),
+  CONSTRAINT `fk_email_drafts_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`),
+  CONSTRAINT `fk_email_drafts_original_email` FOREIGN KEY (`original_email_id`) REFERENCES `emails`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `email_filters` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `user_id` bigint unsigned,
+  `name` varchar(255),
+  `description` text,
+  `query` text,
+  `category` varchar(100),
+  `priority` varchar(50),
+  `from_contains` varchar(255),
+  `subject_contains` varchar(255),
+  `body_contains` varchar(255),
+  `auto_read` boolean DEFAULT false,
+  `auto_important` boolean DEFAULT false,
+  `auto_archive` boolean DEFAULT false,
+  `auto_analyze` boolean DEFAULT true,
+  `is_active` boolean DEFAULT true,
+  `match_count` bigint,
+  `last_used` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_email_filters_deleted_at` (`deleted_at`),
+  INDEX `idx_email_filters_user_id` (`user_id`),
+  CONSTRAINT `fk_email_filters_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+);
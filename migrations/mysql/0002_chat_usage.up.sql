@@ -0,0 +1,10 @@
+-- 每日提问次数计数，提问前原子预占额度
+CREATE TABLE IF NOT EXISTS `chat_usages` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned,
+  `day` varchar(10),
+  `used` bigint DEFAULT 0,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_chat_usages_user_day` (`user_id`,`day`)
+);
@@ -0,0 +1,12 @@
+-- 密码重置令牌，基线结构遗漏了该表
+CREATE TABLE IF NOT EXISTS `password_reset_tokens` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `token` varchar(255) NOT NULL,
+  `expires_at` datetime(3) NOT NULL,
+  `created_at` datetime(3) NULL,
+  `used_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_password_reset_tokens_token` (`token`),
+  INDEX `idx_password_reset_tokens_used_at` (`used_at`)
+);
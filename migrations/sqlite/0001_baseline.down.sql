@@ -0,0 +1,30 @@
+-- 删除基线创建的全部表，按依赖关系逆序删除
+DROP TABLE IF EXISTS `email_filters`;
+DROP TABLE IF EXISTS `email_drafts`;
+DROP TABLE IF EXISTS `email_analyses`;
+DROP TABLE IF EXISTS `emails`;
+DROP TABLE IF EXISTS `evaluation_likes`;
+DROP TABLE IF EXISTS `evaluation_comments`;
+DROP TABLE IF EXISTS `evaluation_metrics`;
+DROP TABLE IF EXISTS `evaluation_dimensions`;
+DROP TABLE IF EXISTS `paper_evaluations`;
+DROP TABLE IF EXISTS `task_reactions`;
+DROP TABLE IF EXISTS `user_follows`;
+DROP TABLE IF EXISTS `user_activities`;
+DROP TABLE IF EXISTS `user_stats`;
+DROP TABLE IF EXISTS `user_badges`;
+DROP TABLE IF EXISTS `badge_templates`;
+DROP TABLE IF EXISTS `payment_records`;
+DROP TABLE IF EXISTS `user_subscriptions`;
+DROP TABLE IF EXISTS `products`;
+DROP TABLE IF EXISTS `comments`;
+DROP TABLE IF EXISTS `upload_tickets`;
+DROP TABLE IF EXISTS `upload_batch_items`;
+DROP TABLE IF EXISTS `upload_batches`;
+DROP TABLE IF EXISTS `analysis_results`;
+DROP TABLE IF EXISTS `analysis_tasks`;
+DROP TABLE IF EXISTS `chat_messages`;
+DROP TABLE IF EXISTS `chat_threads`;
+DROP TABLE IF EXISTS `paper_contents`;
+DROP TABLE IF EXISTS `papers`;
+DROP TABLE IF EXISTS `users`;
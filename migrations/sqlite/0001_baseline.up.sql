@@ -0,0 +1,515 @@
+-- 基线结构：与引入迁移前AutoMigrate创建的表结构一致，已有数据库执行时跳过已存在的表和索引
+
+CREATE TABLE IF NOT EXISTS `users` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `email` text,
+  `password` text,
+  `gmail` text,
+  `name` text,
+  `avatar` text,
+  `institution` text,
+  `position` text,
+  `field` text,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `last_login` datetime,
+  `deleted_at` datetime,
+  `free_trial_count` integer DEFAULT 3,
+  `auth_provider` text DEFAULT "email"
+);
+CREATE INDEX IF NOT EXISTS `idx_users_deleted_at` ON `users`(`deleted_at`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_users_gmail` ON `users`(`gmail`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_users_email` ON `users`(`email`);
+
+CREATE TABLE IF NOT EXISTS `papers` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `user_id` integer,
+  `file_name` text,
+  `oss_path` text,
+  `file_size` integer,
+  `page_count` integer,
+  `content_hash` text,
+  `status` text,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `deleted_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_papers_deleted_at` ON `papers`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_papers_content_hash` ON `papers`(`content_hash`);
+CREATE INDEX IF NOT EXISTS `idx_papers_user_id` ON `papers`(`user_id`);
+
+CREATE TABLE IF NOT EXISTS `paper_contents` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `paper_id` integer,
+  `title` text,
+  `abstract` text,
+  `pages` longtext,
+  `sections` text,
+  `references` longtext,
+  `char_count` integer,
+  `created_at` datetime,
+  `updated_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_paper_contents_paper_id` ON `paper_contents`(`paper_id`);
+
+CREATE TABLE IF NOT EXISTS `chat_threads` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `user_id` integer,
+  `paper_id` integer,
+  `title` text,
+  `message_count` integer DEFAULT 0,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `deleted_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_chat_threads_deleted_at` ON `chat_threads`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_chat_threads_paper_id` ON `chat_threads`(`paper_id`);
+CREATE INDEX IF NOT EXISTS `idx_chat_threads_user_id` ON `chat_threads`(`user_id`);
+
+CREATE TABLE IF NOT EXISTS `chat_messages` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `thread_id` integer,
+  `user_id` integer,
+  `role` text,
+  `content` text,
+  `citations` text,
+  `provider` text,
+  `model` text,
+  `created_at` datetime,
+  `deleted_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_chat_messages_deleted_at` ON `chat_messages`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_chat_messages_created_at` ON `chat_messages`(`created_at`);
+CREATE INDEX IF NOT EXISTS `idx_chat_messages_user_id` ON `chat_messages`(`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_chat_messages_thread_id` ON `chat_messages`(`thread_id`);
+
+CREATE TABLE IF NOT EXISTS `analysis_tasks` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `user_id` integer,
+  `paper_id` integer,
+  `type` text DEFAULT "analysis",
+  `paper_ids` text,
+  `status` text,
+  `stage` text,
+  `progress` integer,
+  `attempts` integer DEFAULT 0,
+  `last_error` text,
+  `worker_id` text,
+  `next_run_at` datetime,
+  `started_at` datetime,
+  `heartbeat_at` datetime,
+  `provider` text,
+  `model` text,
+  `force_fresh` numeric DEFAULT false,
+  `is_public` numeric DEFAULT false,
+  `suggest_score` integer,
+  `like_count` integer,
+  `read_count` integer,
+  `created_at` datetime,
+  `finished_at` datetime,
+  `deleted_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_analysis_tasks_deleted_at` ON `analysis_tasks`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_analysis_tasks_status` ON `analysis_tasks`(`status`);
+CREATE INDEX IF NOT EXISTS `idx_analysis_tasks_paper_id` ON `analysis_tasks`(`paper_id`);
+CREATE INDEX IF NOT EXISTS `idx_analysis_tasks_user_id` ON `analysis_tasks`(`user_id`);
+
+CREATE TABLE IF NOT EXISTS `analysis_results` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `task_id` integer,
+  `version` integer DEFAULT 1,
+  `provider` text,
+  `content` text,
+  `analysis` text,
+  `comparison` text,
+  `model` text,
+  `prompt_version` text,
+  `usage` text,
+  `content_hash` text,
+  `cached_from_id` integer,
+  `created_at` datetime,
+  `deleted_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_analysis_results_deleted_at` ON `analysis_results`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_analysis_results_content_hash` ON `analysis_results`(`content_hash`);
+CREATE INDEX IF NOT EXISTS `idx_analysis_results_task_id` ON `analysis_results`(`task_id`);
+
+CREATE TABLE IF NOT EXISTS `upload_batches` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `user_id` integer,
+  `name` text,
+  `total_files` integer,
+  `accepted_files` integer,
+  `charged_trials` integer,
+  `created_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_upload_batches_user_id` ON `upload_batches`(`user_id`);
+
+CREATE TABLE IF NOT EXISTS `upload_batch_items` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `batch_id` integer,
+  `file_name` text,
+  `paper_id` integer,
+  `task_id` integer,
+  `error` text,
+  `created_at` datetime,
+  CONSTRAINT `fk_upload_batches_items` FOREIGN KEY (`batch_id`) REFERENCES `upload_batches`(`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_upload_batch_items_task_id` ON `upload_batch_items`(`task_id`);
+CREATE INDEX IF NOT EXISTS `idx_upload_batch_items_batch_id` ON `upload_batch_items`(`batch_id`);
+
+CREATE TABLE IF NOT EXISTS `upload_tickets` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `token` text,
+  `user_id` integer,
+  `file_name` text,
+  `object_key` text,
+  `file_size` integer,
+  `content_hash` text,
+  `status` text,
+  `error` text,
+  `paper_id` integer,
+  `task_id` integer,
+  `expires_at` datetime,
+  `created_at` datetime,
+  `updated_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_ticket_status_expires` ON `upload_tickets`(`status`,`expires_at`);
+CREATE INDEX IF NOT EXISTS `idx_upload_tickets_user_id` ON `upload_tickets`(`user_id`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_upload_tickets_token` ON `upload_tickets`(`token`);
+
+CREATE TABLE IF NOT EXISTS `comments` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `task_id` integer,
+  `user_id` integer,
+  `content` text,
+  `parent_id` integer,
+  `created_at` datetime,
+  `deleted_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_comments_deleted_at` ON `comments`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_comments_user_id` ON `comments`(`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_comments_task_id` ON `comments`(`task_id`);
+
+CREATE TABLE IF NOT EXISTS `products` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `name` text,
+  `price` real,
+  `duration` integer,
+  `created_at` datetime
+);
+
+CREATE TABLE IF NOT EXISTS `user_subscriptions` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `user_id` integer,
+  `product_id` integer,
+  `start_time` datetime,
+  `end_time` datetime,
+  `status` text
+);
+
+CREATE TABLE IF NOT EXISTS `payment_records` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `user_id` integer,
+  `product_id` integer,
+  `amount` real,
+  `pay_method` text,
+  `pay_time` datetime,
+  `status` text,
+  `trade_no` text
+);
+
+CREATE TABLE IF NOT EXISTS `badge_templates` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `type` text,
+  `name` text,
+  `description` text,
+  `icon` text,
+  `condition` text,
+  `level` integer,
+  `category` text,
+  `created_at` datetime,
+  `deleted_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_badge_templates_deleted_at` ON `badge_templates`(`deleted_at`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_badge_templates_type` ON `badge_templates`(`type`);
+
+CREATE TABLE IF NOT EXISTS `user_badges` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `user_id` integer,
+  `badge_type` text,
+  `name` text,
+  `description` text,
+  `icon` text,
+  `level` integer,
+  `created_at` datetime,
+  `deleted_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_user_badges_deleted_at` ON `user_badges`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_user_badges_user_id` ON `user_badges`(`user_id`);
+
+CREATE TABLE IF NOT EXISTS `user_stats` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `user_id` integer,
+  `analysis_count` integer DEFAULT 0,
+  `public_analysis_count` integer DEFAULT 0,
+  `like_count` integer DEFAULT 0,
+  `comment_count` integer DEFAULT 0,
+  `follower_count` integer DEFAULT 0,
+  `following_count` integer DEFAULT 0,
+  `share_count` integer DEFAULT 0,
+  `total_score` integer DEFAULT 0,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `deleted_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_user_stats_deleted_at` ON `user_stats`(`deleted_at`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_user_stats_user_id` ON `user_stats`(`user_id`);
+
+CREATE TABLE IF NOT EXISTS `user_activities` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `user_id` integer NOT NULL,
+  `event_type` text NOT NULL,
+  `target_type` text NOT NULL,
+  `target_id` integer NOT NULL,
+  `title` text,
+  `content` text,
+  `metadata` text,
+  `visibility` text DEFAULT "public",
+  `like_count` integer DEFAULT 0,
+  `comment_count` integer DEFAULT 0,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `deleted_at` datetime,
+  CONSTRAINT `fk_user_activities_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_user_activities_deleted_at` ON `user_activities`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_user_activities_visibility` ON `user_activities`(`visibility`);
+CREATE INDEX IF NOT EXISTS `idx_user_activities_target_id` ON `user_activities`(`target_id`);
+CREATE INDEX IF NOT EXISTS `idx_user_activities_target_type` ON `user_activities`(`target_type`);
+CREATE INDEX IF NOT EXISTS `idx_user_activities_event_type` ON `user_activities`(`event_type`);
+CREATE INDEX IF NOT EXISTS `idx_user_activities_user_id` ON `user_activities`(`user_id`);
+
+CREATE TABLE IF NOT EXISTS `user_follows` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `follower_id` integer,
+  `following_id` integer,
+  `created_at` datetime,
+  `deleted_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_user_follows_deleted_at` ON `user_follows`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_user_follows_following_id` ON `user_follows`(`following_id`);
+CREATE INDEX IF NOT EXISTS `idx_user_follows_follower_id` ON `user_follows`(`follower_id`);
+
+CREATE TABLE IF NOT EXISTS `task_reactions` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `task_id` integer,
+  `user_id` integer,
+  `reaction_type` text,
+  `created_at` datetime,
+  `deleted_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_task_reactions_deleted_at` ON `task_reactions`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_task_reactions_reaction_type` ON `task_reactions`(`reaction_type`);
+CREATE INDEX IF NOT EXISTS `idx_task_reactions_user_id` ON `task_reactions`(`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_task_reactions_task_id` ON `task_reactions`(`task_id`);
+
+CREATE TABLE IF NOT EXISTS `paper_evaluations` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `analysis_id` integer,
+  `user_id` integer,
+  `paper_id` integer,
+  `source` text DEFAULT "user",
+  `overall_score` decimal(5,2),
+  `summary` text,
+  `recommendation` text,
+  `originality_score` decimal(5,2),
+  `depth_score` decimal(5,2),
+  `logic_score` decimal(5,2),
+  `evidence_score` decimal(5,2),
+  `language_score` decimal(5,2),
+  `value_score` decimal(5,2),
+  `content_score` decimal(5,2),
+  `structure_score` decimal(5,2),
+  `method_score` decimal(5,2),
+  `is_public` numeric DEFAULT false,
+  `is_verified` numeric DEFAULT false,
+  `like_count` integer DEFAULT 0,
+  `comment_count` integer DEFAULT 0,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `deleted_at` datetime,
+  CONSTRAINT `fk_paper_evaluations_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`),
+  CONSTRAINT `fk_paper_evaluations_paper` FOREIGN KEY (`paper_id`) REFERENCES `papers`(`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_paper_evaluations_deleted_at` ON `paper_evaluations`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_paper_evaluations_paper_id` ON `paper_evaluations`(`paper_id`);
+CREATE INDEX IF NOT EXISTS `idx_paper_evaluations_user_id` ON `paper_evaluations`(`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_paper_evaluations_analysis_id` ON `paper_evaluations`(`analysis_id`);
+
+CREATE TABLE IF NOT EXISTS `evaluation_dimensions` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `evaluation_id` integer,
+  `dimension_key` text,
+  `dimension_name` text,
+  `score` decimal(5,2),
+  `description` text,
+  `evidence` text,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `deleted_at` datetime,
+  CONSTRAINT `fk_paper_evaluations_dimensions` FOREIGN KEY (`evaluation_id`) REFERENCES `paper_evaluations`(`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_evaluation_dimensions_deleted_at` ON `evaluation_dimensions`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_evaluation_dimensions_evaluation_id` ON `evaluation_dimensions`(`evaluation_id`);
+
+CREATE TABLE IF NOT EXISTS `evaluation_metrics` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `dimension_id` integer,
+  `metric_key` text,
+  `metric_name` text,
+  `score` decimal(5,2),
+  `description` text,
+  `evidence` text,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `deleted_at` datetime,
+  CONSTRAINT `fk_evaluation_dimensions_metrics` FOREIGN KEY (`dimension_id`) REFERENCES `evaluation_dimensions`(`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_evaluation_metrics_deleted_at` ON `evaluation_metrics`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_evaluation_metrics_dimension_id` ON `evaluation_metrics`(`dimension_id`);
+
+CREATE TABLE IF NOT EXISTS `evaluation_comments` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `evaluation_id` integer,
+  `user_id` integer,
+  `content` text,
+  `like_count` integer DEFAULT 0,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `deleted_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_evaluation_comments_deleted_at` ON `evaluation_comments`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_evaluation_comments_user_id` ON `evaluation_comments`(`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_evaluation_comments_evaluation_id` ON `evaluation_comments`(`evaluation_id`);
+
+CREATE TABLE IF NOT EXISTS `evaluation_likes` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `evaluation_id` integer,
+  `user_id` integer,
+  `created_at` datetime,
+  `deleted_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_evaluation_likes_deleted_at` ON `evaluation_likes`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_evaluation_likes_user_id` ON `evaluation_likes`(`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_evaluation_likes_evaluation_id` ON `evaluation_likes`(`evaluation_id`);
+
+CREATE TABLE IF NOT EXISTS `emails` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `deleted_at` datetime,
+  `gmail_id` text,
+  `thread_id` text,
+  `subject` text,
+  `from_email` text,
+  `from_name` text,
+  `to_email` text,
+  `to_name` text,
+  `date_sent` datetime,
+  `body` text,
+  `body_html` longtext,
+  `snippet` text,
+  `size_estimate` integer,
+  `label_ids` text,
+  `user_id` integer,
+  `is_analyzed` numeric DEFAULT false,
+  `analysis_result` text,
+  `category` text,
+  `priority` text,
+  `tags` text,
+  `is_read` numeric DEFAULT false,
+  `is_important` numeric DEFAULT false,
+  `is_archived` numeric DEFAULT false,
+  `word_count` integer,
+  `attachment_count` integer,
+  CONSTRAINT `fk_emails_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_emails_user_id` ON `emails`(`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_emails_date_sent` ON `emails`(`date_sent`);
+CREATE INDEX IF NOT EXISTS `idx_emails_to_email` ON `emails`(`to_email`);
+CREATE INDEX IF NOT EXISTS `idx_emails_from_email` ON `emails`(`from_email`);
+CREATE INDEX IF NOT EXISTS `idx_emails_thread_id` ON `emails`(`thread_id`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_emails_gmail_id` ON `emails`(`gmail_id`);
+CREATE INDEX IF NOT EXISTS `idx_emails_deleted_at` ON `emails`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `email_analyses` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `deleted_at` datetime,
+  `email_id` integer,
+  `sentiment` text,
+  `sentiment_score` real,
+  `language` text,
+  `keywords` text,
+  `summary` text,
+  `topics` text,
+  `entities` text,
+  `generated_draft` text,
+  `suggestions` text,
+  `confidence` real,
+  `quality` integer,
+  CONSTRAINT `fk_email_analyses_email` FOREIGN KEY (`email_id`) REFERENCES `emails`(`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_email_analyses_email_id` ON `email_analyses`(`email_id`);
+CREATE INDEX IF NOT EXISTS `idx_email_analyses_deleted_at` ON `email_analyses`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `email_drafts` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `deleted_at` datetime,
+  `user_id` integer,
+  `original_email_id` integer,
+  `to_email` text,
+  `subject` text,
+  `body` text,
+  `body_html` longtext,
+  `prompt` text,
+  `ai_model` text,
+  `status` text DEFAULT "draft",
+  `is_sent` numeric DEFAULT false,
+  `sent_at` datetime,
+  CONSTRAINT `fk_email_drafts_original_email` FOREIGN KEY (`original_email_id`) REFERENCES `emails`(`id`),
+  CONSTRAINT `fk_email_drafts_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_email_drafts_original_email_id` ON `email_drafts`(`original_email_id`);
+CREATE INDEX IF NOT EXISTS `idx_email_drafts_user_id` ON `email_drafts`(`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_email_drafts_deleted_at` ON `email_drafts`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `email_filters` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `deleted_at` datetime,
+  `user_id` integer,
+  `name` text,
+  `description` text,
+  `query` text,
+  `category` text,
+  `priority` text,
+  `from_contains` text,
+  `subject_contains` text,
+  `body_contains` text,
+  `auto_read` numeric DEFAULT false,
+  `auto_important` numeric DEFAULT false,
+  `auto_archive` numeric DEFAULT false,
+  `auto_analyze` numeric DEFAULT true,
+  `is_active` numeric DEFAULT true,
+  `match_count` integer,
+  `last_used` datetime,
+  CONSTRAINT `fk_email_filters_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_email_filters_user_id` ON `email_filters`(`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_email_filters_deleted_at` ON `email_filters`(`deleted_at`);
@@ -0,0 +1,9 @@
+-- 每日提问次数计数，提问前原子预占额度
+CREATE TABLE IF NOT EXISTS `chat_usages` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `user_id` integer,
+  `day` text,
+  `used` integer DEFAULT 0,
+  `updated_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_chat_usages_user_day` ON `chat_usages`(`user_id`,`day`);
@@ -0,0 +1,11 @@
+-- 密码重置令牌，基线结构遗漏了该表
+CREATE TABLE IF NOT EXISTS `password_reset_tokens` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `user_id` integer NOT NULL,
+  `token` text NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `created_at` datetime,
+  `used_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_password_reset_tokens_token` ON `password_reset_tokens`(`token`);
+CREATE INDEX IF NOT EXISTS `idx_password_reset_tokens_used_at` ON `password_reset_tokens`(`used_at`);
@@ -1,33 +1,86 @@
 package model
 
 import (
+	"papergraph/aitools"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// 分析任务状态
+// 任务以analysis_tasks表作为持久化队列，由后台工作池按状态流转
+const (
+	TaskStatusQueued    = "排队中" // 等待工作池领取
+	TaskStatusRunning   = "进行中" // 工作池正在执行
+	TaskStatusCompleted = "已完成" // 分析完成
+	TaskStatusFailed    = "失败"  // 重试次数耗尽
+	TaskStatusCanceled  = "已取消" // 用户取消
+)
+
+// 分析任务阶段，用于前端展示实时进度
+const (
+	TaskStageQueued      = "queued"      // 排队等待
+	TaskStageStarting    = "starting"    // 已被领取，准备执行
+	TaskStageDownloading = "downloading" // 从对象存储取回PDF
+	TaskStageExtracting  = "extracting"  // 本地提取PDF文本
+	TaskStageUploading   = "uploading"   // 上传PDF到模型服务
+	TaskStageAnalyzing   = "analyzing"   // 模型分析中
+	TaskStageSaving      = "saving"      // 保存分析结果
+	TaskStageCompleted   = "completed"   // 已完成
+	TaskStageFailed      = "failed"      // 已失败
+	TaskStageCanceled    = "canceled"    // 已取消
+)
+
+// 分析任务类型
+const (
+	TaskTypeAnalysis   = "analysis"   // 单篇论文分析
+	TaskTypeComparison = "comparison" // 多篇论文对比分析，论文列表见PaperIDs，PaperID为0
+)
+
 // AnalysisTask 分析任务模型
 // 记录每次论文分析的任务信息
 type AnalysisTask struct {
-	ID           uint           `gorm:"primaryKey" json:"id"`           // 主键ID
-	UserID       uint           `gorm:"index" json:"user_id"`           // 用户ID
-	PaperID      uint           `gorm:"index" json:"paper_id"`          // 论文ID
-	Status       string         `gorm:"size:32" json:"status"`          // 状态（进行中/完成/失败）
-	IsPublic     bool           `gorm:"default:false" json:"is_public"` // 是否公开
-	SuggestScore int            `json:"suggest_score"`                  // 阅读原文建议强度
-	LikeCount    int            `json:"like_count"`                     // 点赞数
-	ReadCount    int            `json:"read_count"`                     // 阅读数
-	CreatedAt    time.Time      `json:"created_at"`                     // 创建时间
-	FinishedAt   *time.Time     `json:"finished_at"`                    // 完成时间
-	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`                 // 软删除
+	ID           uint           `gorm:"primaryKey" json:"id"`                                 // 主键ID
+	UserID       uint           `gorm:"index" json:"user_id"`                                 // 用户ID
+	PaperID      uint           `gorm:"index" json:"paper_id"`                                // 论文ID
+	Type         string         `gorm:"size:16;default:'analysis'" json:"type"`               // 任务类型（analysis/comparison）
+	PaperIDs     []uint         `gorm:"serializer:json;type:text" json:"paper_ids,omitempty"` // 对比分析的论文ID，按对比编号顺序
+	Status       string         `gorm:"size:32;index" json:"status"`                          // 状态（排队中/进行中/已完成/失败/已取消）
+	Stage        string         `gorm:"size:32" json:"stage"`                                 // 当前执行阶段
+	Progress     int            `json:"progress"`                                             // 进度百分比（0-100）
+	Attempts     int            `gorm:"default:0" json:"attempts"`                            // 已执行次数
+	LastError    string         `gorm:"type:text" json:"last_error"`                          // 最近一次失败原因
+	WorkerID     string         `gorm:"size:64" json:"-"`                                     // 领取该任务的工作池实例
+	NextRunAt    *time.Time     `json:"next_run_at"`                                          // 最早可执行时间（失败重试退避）
+	StartedAt    *time.Time     `json:"started_at"`                                           // 最近一次开始执行时间
+	HeartbeatAt  *time.Time     `json:"-"`                                                    // 最近一次心跳时间
+	Provider     string         `gorm:"size:32" json:"provider"`                              // 指定的模型服务提供方，为空时使用默认配置
+	Model        string         `gorm:"size:64" json:"model"`                                 // 指定的模型名称，为空时使用提供方默认模型
+	ForceFresh   bool           `gorm:"default:false" json:"force_fresh"`                     // 是否跳过分析结果缓存，强制调用模型重新分析
+	IsPublic     bool           `gorm:"default:false" json:"is_public"`                       // 是否公开
+	SuggestScore int            `json:"suggest_score"`                                        // 阅读原文建议强度
+	LikeCount    int            `json:"like_count"`                                           // 点赞数
+	ReadCount    int            `json:"read_count"`                                           // 阅读数
+	CreatedAt    time.Time      `json:"created_at"`                                           // 创建时间
+	FinishedAt   *time.Time     `json:"finished_at"`                                          // 完成时间
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`                                       // 软删除
 }
 
 // AnalysisResult 分析结果模型
 // 记录Gemini分析的结果内容
 type AnalysisResult struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`     // 主键ID
-	TaskID    uint           `gorm:"index" json:"task_id"`     // 分析任务ID
-	Content   string         `gorm:"type:text" json:"content"` // 分析内容
-	CreatedAt time.Time      `json:"created_at"`               // 创建时间
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`           // 软删除
+	ID            uint                     `gorm:"primaryKey" json:"id"`                                  // 主键ID
+	TaskID        uint                     `gorm:"index" json:"task_id"`                                  // 分析任务ID
+	Version       int                      `gorm:"default:1" json:"version"`                              // 结果版本，同一任务每次重新分析递增
+	Provider      string                   `gorm:"size:32" json:"provider"`                               // 分析所用模型服务提供方
+	Content       string                   `gorm:"type:text" json:"content"`                              // 分析内容摘要
+	Analysis      *aitools.PaperAnalysis   `gorm:"serializer:json;type:text" json:"analysis"`             // 结构化分析报告
+	Comparison    *aitools.PaperComparison `gorm:"serializer:json;type:text" json:"comparison,omitempty"` // 多篇论文对比结果，仅对比任务有值
+	Model         string                   `gorm:"size:64" json:"model"`                                  // 分析所用模型
+	PromptVersion string                   `gorm:"size:32" json:"prompt_version"`                         // 分析prompt版本
+	Usage         *aitools.AnalysisUsage   `gorm:"serializer:json;type:text" json:"usage"`                // 分析模式与token使用情况
+	ContentHash   string                   `gorm:"size:64;index" json:"content_hash"`                     // 论文文件SHA-256，与提供方、模型、prompt版本共同作为缓存键
+	CachedFromID  *uint                    `json:"cached_from_id"`                                        // 复用缓存时指向最初生成该结果的记录
+	CreatedAt     time.Time                `json:"created_at"`                                            // 创建时间
+	DeletedAt     gorm.DeletedAt           `gorm:"index" json:"-"`                                        // 软删除
 }
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// UploadBatch 批量上传记录
+// 一次上传的zip或多个PDF文件，每个文件对应一个UploadBatchItem
+type UploadBatch struct {
+	ID            uint              `gorm:"primaryKey" json:"id"`                      // 主键ID
+	UserID        uint              `gorm:"index" json:"user_id"`                      // 上传用户ID
+	Name          string            `gorm:"size:256" json:"name"`                      // 批次名称，默认为zip文件名
+	TotalFiles    int               `json:"total_files"`                               // 上传的文件总数
+	AcceptedFiles int               `json:"accepted_files"`                            // 已创建分析任务的文件数
+	ChargedTrials int               `json:"charged_trials"`                            // 扣减的免费试用次数，订阅用户为0
+	CreatedAt     time.Time         `json:"created_at"`                                // 创建时间
+	Items         []UploadBatchItem `gorm:"foreignKey:BatchID" json:"items,omitempty"` // 批次中的文件
+}
+
+// UploadBatchItem 批量上传中的单个文件
+// 校验或创建任务失败的文件记录失败原因，PaperID和TaskID为0
+// 重复上传时TaskID指向已有任务，因此任务进度通过本表关联而不是在任务上记录批次
+type UploadBatchItem struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`      // 主键ID
+	BatchID   uint      `gorm:"index" json:"batch_id"`     // 批次ID
+	FileName  string    `gorm:"size:256" json:"file_name"` // 文件名（zip内的路径）
+	PaperID   uint      `json:"paper_id"`                  // 论文ID
+	TaskID    uint      `gorm:"index" json:"task_id"`      // 分析任务ID
+	Error     string    `gorm:"size:512" json:"error"`     // 失败原因
+	CreatedAt time.Time `json:"created_at"`                // 创建时间
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 论文问答消息角色
+const (
+	ChatRoleUser      = "user"      // 用户提问
+	ChatRoleAssistant = "assistant" // 模型回答
+)
+
+// ChatThread 论文问答会话
+// 一个会话围绕一篇论文进行多轮问答
+type ChatThread struct {
+	ID           uint           `gorm:"primaryKey" json:"id"`           // 主键ID
+	UserID       uint           `gorm:"index" json:"user_id"`           // 用户ID
+	PaperID      uint           `gorm:"index" json:"paper_id"`          // 论文ID
+	Title        string         `gorm:"size:128" json:"title"`          // 会话标题，取第一个问题的开头
+	MessageCount int            `gorm:"default:0" json:"message_count"` // 消息数
+	CreatedAt    time.Time      `json:"created_at"`                     // 创建时间
+	UpdatedAt    time.Time      `json:"updated_at"`                     // 最近一次问答时间
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`                 // 软删除
+}
+
+// ChatMessage 论文问答消息
+type ChatMessage struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`                       // 主键ID
+	ThreadID  uint           `gorm:"index" json:"thread_id"`                     // 会话ID
+	UserID    uint           `gorm:"index" json:"user_id"`                       // 提问用户ID，用于统计配额
+	Role      string         `gorm:"size:16" json:"role"`                        // 角色（user/assistant）
+	Content   string         `gorm:"type:text" json:"content"`                   // 消息内容
+	Citations []int          `gorm:"serializer:json;type:text" json:"citations"` // 回答引用的论文页码
+	Provider  string         `gorm:"size:32" json:"provider,omitempty"`          // 回答所用模型服务提供方
+	Model     string         `gorm:"size:64" json:"model,omitempty"`             // 回答所用模型
+	CreatedAt time.Time      `gorm:"index" json:"created_at"`                    // 创建时间
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`                             // 软删除
+}
+
+// ChatUsage 用户每日提问次数
+// 提问前以条件更新原子预占额度，并发提问也不会超出配额；回答失败时退还
+type ChatUsage struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`                                    // 主键ID
+	UserID    uint      `gorm:"uniqueIndex:idx_chat_usages_user_day" json:"user_id"`     // 用户ID
+	Day       string    `gorm:"size:10;uniqueIndex:idx_chat_usages_user_day" json:"day"` // 日期（2006-01-02），按服务所在时区
+	Used      int       `gorm:"default:0" json:"used"`                                   // 已使用的提问次数
+	UpdatedAt time.Time `json:"updated_at"`                                              // 更新时间
+}
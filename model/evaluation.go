@@ -13,6 +13,7 @@ type PaperEvaluation struct {
 	AnalysisID   uint           `gorm:"index" json:"analysis_id"`        // 分析结果ID
 	UserID       uint           `gorm:"index" json:"user_id"`           // 评价用户ID
 	PaperID      uint           `gorm:"index" json:"paper_id"`          // 论文ID
+	Source       string         `gorm:"size:16;default:'user'" json:"source"` // 评价来源（user/ai）
 	
 	// 总体评价
 	OverallScore float64        `gorm:"type:decimal(5,2)" json:"overall_score"` // 总体评分 (0-10)
@@ -24,7 +25,7 @@ type PaperEvaluation struct {
 	DepthScore          float64 `gorm:"type:decimal(5,2)" json:"depth_score"`          // 深度洞察评分
 	LogicScore          float64 `gorm:"type:decimal(5,2)" json:"logic_score"`          // 逻辑严谨评分
 	EvidenceScore       float64 `gorm:"type:decimal(5,2)" json:"evidence_score"`       // 证据支撑评分
-	LanguageScore       float64 `gorm:"type:decimal(5,2)" json:"language_score"`       // 语言表达评分
+	LanguageScore       *float64 `gorm:"type:decimal(5,2)" json:"language_score"`      // 语言表达评分，为空表示未评估（AI评价不评估语言表达）
 	ValueScore          float64 `gorm:"type:decimal(5,2)" json:"value_score"`          // 学术价值评分
 	
 	// 分类评分
@@ -41,8 +42,18 @@ type PaperEvaluation struct {
 	CreatedAt    time.Time      `json:"created_at"`                        // 创建时间
 	UpdatedAt    time.Time      `json:"updated_at"`                        // 更新时间
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`                    // 软删除
+
+	Dimensions []EvaluationDimension `gorm:"foreignKey:EvaluationID" json:"dimensions,omitempty"` // 维度详情
+	User       *User                 `gorm:"foreignKey:UserID" json:"user,omitempty"`             // 评价用户
+	Paper      *Paper                `gorm:"foreignKey:PaperID" json:"paper,omitempty"`           // 被评价论文
 }
 
+// 评价来源
+const (
+	EvaluationSourceUser = "user" // 用户手动评价
+	EvaluationSourceAI   = "ai"   // 根据AI分析结果自动生成
+)
+
 // EvaluationDimension 评价维度详情模型
 // 记录每个维度的详细评价和证据
 type EvaluationDimension struct {
@@ -57,6 +68,8 @@ type EvaluationDimension struct {
 	CreatedAt    time.Time      `json:"created_at"`                     // 创建时间
 	UpdatedAt    time.Time      `json:"updated_at"`                     // 更新时间
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`                 // 软删除
+
+	Metrics []EvaluationMetric `gorm:"foreignKey:DimensionID" json:"metrics,omitempty"` // 维度下的指标
 }
 
 // EvaluationMetric 评价指标模型
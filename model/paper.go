@@ -1,6 +1,7 @@
 package model
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -9,13 +10,42 @@ import (
 // Paper 论文模型，记录用户上传的论文PDF信息
 // 包含OSS存储路径、文件大小、状态等
 type Paper struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`      // 主键ID
-	UserID    uint           `gorm:"index" json:"user_id"`      // 上传用户ID
-	FileName  string         `gorm:"size:256" json:"file_name"` // 文件名
-	OSSPath   string         `gorm:"size:512" json:"oss_path"`  // OSS存储路径
-	FileSize  int64          `json:"file_size"`                 // 文件大小（字节）
-	Status    string         `gorm:"size:32" json:"status"`     // 状态（已上传/分析中/已完成/失败）
-	CreatedAt time.Time      `json:"created_at"`                // 上传时间
-	UpdatedAt time.Time      `json:"updated_at"`                // 更新时间
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`            // 软删除
+	ID          uint           `gorm:"primaryKey" json:"id"`              // 主键ID
+	UserID      uint           `gorm:"index" json:"user_id"`              // 上传用户ID
+	FileName    string         `gorm:"size:256" json:"file_name"`         // 文件名
+	OSSPath     string         `gorm:"size:512" json:"oss_path"`          // OSS存储路径
+	FileSize    int64          `json:"file_size"`                         // 文件大小（字节）
+	PageCount   int            `json:"page_count"`                        // 页数
+	ContentHash string         `gorm:"size:64;index" json:"content_hash"` // 文件内容SHA-256
+	Status      string         `gorm:"size:32" json:"status"`             // 状态（已上传/分析中/已完成/失败）
+	CreatedAt   time.Time      `json:"created_at"`                        // 上传时间
+	UpdatedAt   time.Time      `json:"updated_at"`                        // 更新时间
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`                    // 软删除
+}
+
+// PaperSection 论文章节
+type PaperSection struct {
+	Title string `json:"title"` // 章节标题
+	Level int    `json:"level"` // 层级，1为一级标题
+	Page  int    `json:"page"`  // 所在页码，从1开始
+}
+
+// PaperContent 论文本地提取内容
+// 由PDF逐页提取文本并识别标题、摘要、章节和参考文献，用于校验上传、缩短prompt和检索
+type PaperContent struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`                            // 主键ID
+	PaperID    uint           `gorm:"uniqueIndex" json:"paper_id"`                     // 论文ID
+	Title      string         `gorm:"size:512" json:"title"`                           // 识别出的标题
+	Abstract   string         `gorm:"type:text" json:"abstract"`                       // 识别出的摘要
+	Pages      []string       `gorm:"serializer:json;type:longtext" json:"pages"`      // 逐页文本
+	Sections   []PaperSection `gorm:"serializer:json;type:text" json:"sections"`       // 章节目录
+	References []string       `gorm:"serializer:json;type:longtext" json:"references"` // 参考文献条目
+	CharCount  int            `json:"char_count"`                                      // 正文字符数
+	CreatedAt  time.Time      `json:"created_at"`                                      // 创建时间
+	UpdatedAt  time.Time      `json:"updated_at"`                                      // 更新时间
+}
+
+// FullText 拼接逐页文本
+func (c *PaperContent) FullText() string {
+	return strings.Join(c.Pages, "\n\n")
 }
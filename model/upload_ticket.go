@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// 直传票据状态
+const (
+	UploadTicketPending   = "pending"   // 已签发，等待客户端上传并确认
+	UploadTicketCompleted = "completed" // 已确认并创建论文和分析任务
+	UploadTicketRejected  = "rejected"  // 上传的文件未通过校验，对象已删除
+	UploadTicketExpired   = "expired"   // 超时未确认，对象已清理
+)
+
+// UploadTicket 浏览器直传对象存储的上传票据
+// 签发时生成限时上传地址和对象路径，客户端上传完成后凭Token确认；超时未确认的票据由清理任务删除已上传的对象
+type UploadTicket struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`                                  // 主键ID
+	Token       string    `gorm:"size:64;uniqueIndex" json:"token"`                      // 票据凭证
+	UserID      uint      `gorm:"index" json:"user_id"`                                  // 申请用户ID
+	FileName    string    `gorm:"size:256" json:"file_name"`                             // 文件名
+	ObjectKey   string    `gorm:"size:512" json:"object_key"`                            // 客户端上传的暂存对象路径，确认后删除
+	FileSize    int64     `json:"file_size"`                                             // 申请时声明的文件大小（字节）
+	ContentHash string    `gorm:"size:64" json:"content_hash"`                           // 申请时声明的文件SHA-256
+	Status      string    `gorm:"size:16;index:idx_ticket_status_expires" json:"status"` // 票据状态
+	Error       string    `gorm:"size:512" json:"error,omitempty"`                       // 校验失败原因
+	PaperID     uint      `json:"paper_id"`                                              // 确认后创建的论文ID
+	TaskID      uint      `json:"task_id"`                                               // 确认后创建的分析任务ID
+	ExpiresAt   time.Time `gorm:"index:idx_ticket_status_expires" json:"expires_at"`     // 上传地址和票据的过期时间
+	CreatedAt   time.Time `json:"created_at"`                                            // 创建时间
+	UpdatedAt   time.Time `json:"updated_at"`                                            // 更新时间
+}
@@ -1,89 +1,133 @@
 package router
 
 import (
-	"papergraph/config"
 	"papergraph/handler"
+	"papergraph/metrics"
 	"papergraph/middleware"
-	"papergraph/service"
+	"papergraph/storage"
+	"papergraph/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-// InitRouter 初始化路由，支持注入订阅服务、奖章服务和用户活动服务
-func InitRouter(subSvc *service.SubscriptionService, badgeSvc *service.BadgeService, activitySvc *service.UserActivityService) *gin.Engine {
+// Handlers 路由使用的接口处理器，由main创建并注入依赖
+type Handlers struct {
+	Auth         *handler.AuthHandler
+	GoogleAuth   *handler.GoogleAuthHandler
+	Paper        *handler.PaperHandler
+	Import       *handler.ImportHandler
+	UploadTicket *handler.UploadTicketHandler
+	Batch        *handler.BatchHandler
+	Analysis     *handler.AnalysisHandler
+	TaskEvents   *handler.TaskEventsHandler
+	Comment      *handler.CommentHandler
+	Chat         *handler.ChatHandler
+	Subscription *handler.SubscriptionHandler
+	Social       *handler.SocialHandler
+	Evaluation   *handler.EvaluationHandler
+	UserActivity *handler.UserActivityHandler
+	Health       *handler.HealthHandler
+}
+
+// InitRouter 初始化路由，jwt用于校验登录令牌，store为本地存储时同时处理签名上传和下载地址
+func InitRouter(h Handlers, jwt *utils.JWT, store storage.Storage) *gin.Engine {
 	r := gin.Default()
+	r.Use(middleware.Metrics())
+
+	// 健康检查和监控指标，供负载均衡、容器编排和Prometheus使用，/metrics不应暴露到公网
+	r.GET("/healthz", h.Health.Healthz)
+	r.GET("/readyz", h.Health.Readyz)
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
 
 	// 1. VUE静态资源服务，服务前端构建产物（assets、favicon等）
 	r.Static("/assets", "./app/static/assets")               // VUE构建产物的静态资源
 	r.StaticFile("/favicon.ico", "./app/static/favicon.ico") // 网站图标
 
-	// 创建用户服务
-	userService := service.NewUserService(config.DB)
-	authHandler := handler.NewAuthHandler(userService)
+	// 本地存储的签名地址由本服务处理，签名校验在LocalStorage中完成
+	if local, ok := store.(*storage.LocalStorage); ok {
+		r.Any(storage.LocalRoutePrefix+"*key", gin.WrapH(local))
+	}
 
 	// 认证相关路由（无需认证）
-	r.POST("/api/auth", authHandler.Register)
-	r.POST("/api/auth/login", authHandler.Login)
-	r.POST("/api/forgot-password", authHandler.ForgotPassword)
+	r.POST("/api/auth", h.Auth.Register)
+	r.POST("/api/auth/login", h.Auth.Login)
+	r.POST("/api/forgot-password", h.Auth.ForgotPassword)
 
 	// Google登录相关路由
-	r.GET("/login/google", handler.GoogleLoginHandler)
-	r.GET("/auth/google/callback", handler.GoogleCallbackHandler)
+	r.GET("/login/google", h.GoogleAuth.Login)
+	r.GET("/auth/google/callback", h.GoogleAuth.Callback)
 
 	// 受保护的API
-	auth := r.Group("/api", middleware.AuthMiddleware())
-	auth.GET("/me", authHandler.GetMe)
-	auth.POST("/upload", handler.UploadPaperHandler)
-	auth.POST("/start_analysis", handler.StartAnalysisHandler)
-	auth.GET("/tasks", handler.GetUserTasksHandler)
-	auth.GET("/task_detail", handler.GetTaskDetailHandler)
-	auth.GET("/analysis_result", handler.GetAnalysisResultHandler)
-	auth.GET("/active_tasks", handler.GetUserActiveTasksHandler)
-	auth.POST("/set_public", handler.SetTaskPublicStatusHandler)
-	auth.POST("/like", handler.LikeTaskHandler)
-	auth.POST("/unlike", handler.UnlikeTaskHandler)
-	auth.POST("/comment", handler.AddCommentHandler)
-	r.GET("/comments", handler.GetCommentsHandler)
-	r.GET("/public_feed", handler.GetPublicFeedHandler)
+	auth := r.Group("/api", middleware.AuthMiddleware(jwt))
+	auth.GET("/me", h.Auth.GetMe)
+	auth.POST("/upload", h.Paper.Upload)
+	auth.POST("/upload/url", h.Import.Import)
+	auth.POST("/upload/tickets", h.UploadTicket.Create)
+	auth.POST("/upload/tickets/:token/complete", h.UploadTicket.Complete)
+	auth.POST("/batches", h.Batch.Create)
+	auth.GET("/batches", h.Batch.List)
+	auth.GET("/batches/:id", h.Batch.Get)
+	auth.GET("/batches/:id/export", h.Batch.Export)
+	auth.POST("/start_analysis", h.Analysis.StartAnalysis)
+	auth.GET("/tasks", h.Analysis.GetUserTasks)
+	auth.GET("/task_detail", h.Analysis.GetTaskDetail)
+	auth.GET("/analysis_result", h.Analysis.GetAnalysisResult)
+	auth.GET("/active_tasks", h.Analysis.GetUserActiveTasks)
+	auth.GET("/tasks/:id/events", h.TaskEvents.Stream)
+	auth.GET("/tasks/:id/results", h.Analysis.ListAnalysisResults)
+	auth.POST("/tasks/:id/cancel", h.Analysis.CancelTask)
+	auth.POST("/tasks/:id/retry", h.Analysis.RetryTask)
+	auth.POST("/tasks/:id/reanalyze", h.Analysis.ReanalyzeTask)
+	auth.POST("/comparisons", h.Analysis.CreateComparison)
+	auth.POST("/set_public", h.Analysis.SetTaskPublicStatus)
+	auth.POST("/like", h.Analysis.LikeTask)
+	auth.POST("/unlike", h.Analysis.UnlikeTask)
+	auth.POST("/comment", h.Comment.AddComment)
+	r.GET("/comments", h.Comment.GetComments)
+	r.GET("/public_feed", h.Analysis.GetPublicFeed)
+
+	auth.DELETE("/papers/:id", h.Paper.Delete)
+
+	// 论文问答接口
+	auth.POST("/papers/:id/chat", h.Chat.Ask)
+	auth.GET("/papers/:id/chat/threads", h.Chat.ListThreads)
+	auth.GET("/chat/threads/:id/messages", h.Chat.ListMessages)
+	auth.GET("/chat/quota", h.Chat.GetQuota)
 
 	// 订阅相关接口
-	subHandler := handler.NewSubscriptionHandler(subSvc)
-	auth.GET("/subscription/products", subHandler.ListProducts)
-	auth.POST("/subscription/buy", subHandler.BuySubscription)
-	auth.GET("/subscription/free_trial_count", subHandler.GetFreeTrialCount)
-	auth.POST("/subscription/decrement_trial", subHandler.DecrementFreeTrial)
-	auth.GET("/subscription/payment_records", subHandler.ListPaymentRecords)
-	auth.GET("/subscription/user_subscriptions", subHandler.ListUserSubscriptions)
+	auth.GET("/subscription/products", h.Subscription.ListProducts)
+	auth.POST("/subscription/buy", h.Subscription.BuySubscription)
+	auth.GET("/subscription/free_trial_count", h.Subscription.GetFreeTrialCount)
+	auth.POST("/subscription/decrement_trial", h.Subscription.DecrementFreeTrial)
+	auth.GET("/subscription/payment_records", h.Subscription.ListPaymentRecords)
+	auth.GET("/subscription/user_subscriptions", h.Subscription.ListUserSubscriptions)
 
 	// 社交和奖章相关接口
-	socialHandler := handler.NewSocialHandler(nil, badgeSvc, config.DB)
-	auth.GET("/user/:user_id/badges", socialHandler.GetUserBadges)
-	auth.GET("/user/:user_id/stats", socialHandler.GetUserStats)
-	auth.POST("/task/react", socialHandler.ReactToTask)
+	auth.GET("/user/:user_id/badges", h.Social.GetUserBadges)
+	auth.GET("/user/:user_id/stats", h.Social.GetUserStats)
+	auth.POST("/task/react", h.Social.ReactToTask)
 
 	// 评价相关接口
-	evalHandler := handler.NewEvaluationHandler(config.DB)
-	auth.POST("/evaluations", evalHandler.CreateEvaluation)
-	auth.GET("/evaluations/my", evalHandler.GetMyEvaluations)
-	auth.PUT("/evaluations/:id", evalHandler.UpdateEvaluation)
-	auth.DELETE("/evaluations/:id", evalHandler.DeleteEvaluation)
-	auth.POST("/evaluations/:id/like", evalHandler.LikeEvaluation)
+	auth.POST("/evaluations", h.Evaluation.CreateEvaluation)
+	auth.GET("/evaluations/my", h.Evaluation.GetMyEvaluations)
+	auth.PUT("/evaluations/:id", h.Evaluation.UpdateEvaluation)
+	auth.DELETE("/evaluations/:id", h.Evaluation.DeleteEvaluation)
+	auth.POST("/evaluations/:id/like", h.Evaluation.LikeEvaluation)
 
 	// 公开评价接口（无需认证）
-	r.GET("/evaluations/:id", evalHandler.GetEvaluation)
-	r.GET("/papers/:paperId/evaluations", evalHandler.GetEvaluationsByPaper)
-	r.GET("/papers/:paperId/evaluations/statistics", evalHandler.GetEvaluationStatistics)
-	r.GET("/evaluations/top", evalHandler.GetTopEvaluations)
-	r.GET("/evaluations/search", evalHandler.SearchEvaluations)
+	r.GET("/evaluations/:id", h.Evaluation.GetEvaluation)
+	r.GET("/papers/:paperId/evaluations", h.Evaluation.GetEvaluationsByPaper)
+	r.GET("/papers/:paperId/evaluations/statistics", h.Evaluation.GetEvaluationStatistics)
+	r.GET("/evaluations/top", h.Evaluation.GetTopEvaluations)
+	r.GET("/evaluations/search", h.Evaluation.SearchEvaluations)
 
 	// 用户活动事件接口
-	activityHandler := handler.NewUserActivityHandler(activitySvc)
-	activityHandler.RegisterRoutes(auth)
+	h.UserActivity.RegisterRoutes(auth)
 
 	// 公开的用户活动事件接口（无需认证）
-	r.GET("/users/:user_id/activities", activityHandler.GetUserActivities)
-	r.GET("/users/:user_id/activities/stats", activityHandler.GetUserActivityStats)
-	r.GET("/feed", activityHandler.GetFeed)
+	r.GET("/users/:user_id/activities", h.UserActivity.GetUserActivities)
+	r.GET("/users/:user_id/activities/stats", h.UserActivity.GetUserActivityStats)
+	r.GET("/feed", h.UserActivity.GetFeed)
 
 	// 2. SPA fallback：所有未命中后端API的路由都返回index.html，由VUE前端路由处理
 	r.NoRoute(func(c *gin.Context) {
@@ -0,0 +1,279 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"papergraph/aitools"
+	"papergraph/config"
+	"papergraph/handler"
+	"papergraph/model"
+	"papergraph/service"
+	"papergraph/storage"
+	"papergraph/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// fakeDispatcher 记录通知、取消和推送的事件，代替分析工作池
+type fakeDispatcher struct {
+	mu       sync.Mutex
+	notified int
+	canceled []uint
+	events   []service.TaskEvent
+}
+
+func (d *fakeDispatcher) Notify() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.notified++
+}
+
+func (d *fakeDispatcher) Cancel(taskID uint) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.canceled = append(d.canceled, taskID)
+	return false
+}
+
+func (d *fakeDispatcher) Publish(event service.TaskEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.events = append(d.events, event)
+}
+
+// testApp 一个独立的服务实例：内存数据库、本地存储、假模型和假工作池
+type testApp struct {
+	router *gin.Engine
+	db     *gorm.DB
+	jwt    *utils.JWT
+	tasks  *fakeDispatcher
+}
+
+// newTestApp 按main的方式装配服务和接口处理器，外部依赖替换为测试实现
+func newTestApp(t *testing.T) *testApp {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default(config.ProfileTest)
+	logger := zap.NewNop()
+	db, err := config.InitDatabase(&cfg, logger)
+	if err != nil {
+		t.Fatalf("初始化测试数据库失败: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	store, err := storage.NewLocalStorage(t.TempDir(), "http://localhost", "test-signing-key")
+	if err != nil {
+		t.Fatalf("创建本地存储失败: %v", err)
+	}
+
+	clock := service.SystemClock{}
+	provider := aitools.NewFakeProvider()
+	defaultModel := service.AnalysisModel{Provider: provider.Name(), Name: provider.ModelName()}
+	tasks := &fakeDispatcher{}
+	taskEvents := service.NewTaskEventBus(clock)
+	paperSvc := service.NewPaperService(db, logger, store, tasks, defaultModel, clock)
+	analysisSvc := service.NewAnalysisService(db, logger, tasks, defaultModel, clock)
+	jwt := utils.NewJWT("test-secret")
+	r := InitRouter(Handlers{
+		Auth:         handler.NewAuthHandler(service.NewUserService(db), jwt),
+		GoogleAuth:   handler.NewGoogleAuthHandler(service.NewGoogleOAuthService(db, logger, cfg.GoogleOAuth), jwt, logger),
+		Paper:        handler.NewPaperHandler(paperSvc, logger),
+		Import:       handler.NewImportHandler(service.NewPaperImportService(logger, paperSvc, cfg.Import), cfg.Import.Timeout, logger),
+		UploadTicket: handler.NewUploadTicketHandler(service.NewUploadTicketService(db, logger, store, paperSvc, cfg.UploadTicket, clock), logger),
+		Batch:        handler.NewBatchHandler(service.NewBatchService(db, logger, paperSvc, cfg.Batch, clock), cfg.Batch, logger),
+		Analysis:     handler.NewAnalysisHandler(analysisSvc, logger),
+		TaskEvents:   handler.NewTaskEventsHandler(taskEvents, analysisSvc, logger, clock),
+		Comment:      handler.NewCommentHandler(service.NewCommentService(db, logger, clock)),
+		Chat:         handler.NewChatHandler(service.NewChatService(db, logger, provider, paperSvc, cfg.Chat, clock), logger),
+		Subscription: handler.NewSubscriptionHandler(service.NewSubscriptionService(db)),
+		Social:       handler.NewSocialHandler(service.NewSocialService(db), service.NewBadgeService(db), db, logger),
+		Evaluation:   handler.NewEvaluationHandler(db),
+		UserActivity: handler.NewUserActivityHandler(service.NewUserActivityService(db)),
+		Health:       handler.NewHealthHandler(db, store, aitools.NewCircuitBreakers(cfg.LLM.CircuitFailureThreshold, cfg.LLM.CircuitCooldown)),
+	}, jwt, store)
+	return &testApp{router: r, db: db, jwt: jwt, tasks: tasks}
+}
+
+// seedTask 创建用户、论文和排队中的分析任务，返回用户的登录令牌和任务
+func (a *testApp) seedTask(t *testing.T, email string) (string, *model.AnalysisTask) {
+	t.Helper()
+	user := &model.User{Email: email, Gmail: email, Name: "tester"}
+	if err := a.db.Create(user).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	paper := &model.Paper{UserID: user.ID, FileName: "paper.pdf", Status: "分析中"}
+	if err := a.db.Create(paper).Error; err != nil {
+		t.Fatal(err)
+	}
+	task := &model.AnalysisTask{UserID: user.ID, PaperID: paper.ID, Status: model.TaskStatusQueued, Stage: model.TaskStageQueued}
+	if err := a.db.Create(task).Error; err != nil {
+		t.Fatal(err)
+	}
+	token, err := a.jwt.GenerateToken(user.ID, user.Email)
+	if err != nil {
+		t.Fatalf("生成令牌失败: %v", err)
+	}
+	return token, task
+}
+
+// do 发送请求，form非空时以表单提交，返回HTTP状态码和解析后的响应
+func (a *testApp) do(t *testing.T, method, path, token string, form url.Values) (int, utils.Response, json.RawMessage) {
+	t.Helper()
+	var req *http.Request
+	if form != nil {
+		req = httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		req = httptest.NewRequest(method, path, nil)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+	var body struct {
+		utils.Response
+		Data json.RawMessage `json:"data"`
+	}
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("解析响应失败: %v, body=%s", err, rec.Body.String())
+		}
+	}
+	return rec.Code, body.Response, body.Data
+}
+
+func TestProtectedRoutesRequireToken(t *testing.T) {
+	app := newTestApp(t)
+	if code, _, _ := app.do(t, http.MethodGet, "/api/tasks", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("未登录访问应返回401，实际为%d", code)
+	}
+	if code, _, _ := app.do(t, http.MethodGet, "/api/tasks", "invalid", nil); code != http.StatusUnauthorized {
+		t.Errorf("无效令牌应返回401，实际为%d", code)
+	}
+}
+
+func TestTaskRoutesUseInjectedServices(t *testing.T) {
+	app := newTestApp(t)
+	token, task := app.seedTask(t, "owner@example.com")
+	taskID := strconv.FormatUint(uint64(task.ID), 10)
+
+	_, resp, data := app.do(t, http.MethodGet, "/api/tasks", token, nil)
+	var tasks []model.AnalysisTask
+	if resp.Code != 0 || json.Unmarshal(data, &tasks) != nil || len(tasks) != 1 || tasks[0].ID != task.ID {
+		t.Fatalf("应返回当前用户的任务，实际为code=%d, data=%s", resp.Code, data)
+	}
+
+	// Feed只展示已完成的公开任务
+	completed := &model.AnalysisTask{UserID: task.UserID, PaperID: task.PaperID, Status: model.TaskStatusCompleted, Stage: model.TaskStageCompleted}
+	if err := app.db.Create(completed).Error; err != nil {
+		t.Fatal(err)
+	}
+	completedID := strconv.FormatUint(uint64(completed.ID), 10)
+	if _, resp, _ := app.do(t, http.MethodPost, "/api/set_public", token, url.Values{"task_id": {completedID}, "is_public": {"1"}}); resp.Code != 0 {
+		t.Fatalf("设置公开失败: %s", resp.Message)
+	}
+	_, _, data = app.do(t, http.MethodGet, "/public_feed", "", nil)
+	if err := json.Unmarshal(data, &tasks); err != nil || len(tasks) != 1 || tasks[0].ID != completed.ID || !tasks[0].IsPublic {
+		t.Errorf("公开后应出现在Feed中，实际为%s", data)
+	}
+
+	if _, resp, _ := app.do(t, http.MethodPost, "/api/comment", token, url.Values{"task_id": {taskID}, "content": {"很有启发"}}); resp.Code != 0 {
+		t.Fatalf("评论失败: %s", resp.Message)
+	}
+	_, _, data = app.do(t, http.MethodGet, "/comments?task_id="+taskID, "", nil)
+	var comments []model.Comment
+	if err := json.Unmarshal(data, &comments); err != nil || len(comments) != 1 || comments[0].UserID != task.UserID {
+		t.Errorf("评论应归属当前用户，实际为%s", data)
+	}
+
+	// 取消任务通过注入的工作池中断执行并推送事件
+	if _, resp, _ := app.do(t, http.MethodPost, "/api/tasks/"+taskID+"/cancel", token, url.Values{}); resp.Code != 0 {
+		t.Fatalf("取消任务失败: %s", resp.Message)
+	}
+	if len(app.tasks.canceled) != 1 || app.tasks.canceled[0] != task.ID {
+		t.Errorf("取消任务应通知工作池，实际为%v", app.tasks.canceled)
+	}
+	if len(app.tasks.events) != 1 || app.tasks.events[0].Status != model.TaskStatusCanceled {
+		t.Errorf("取消任务应推送取消事件，实际为%v", app.tasks.events)
+	}
+}
+
+func TestInstancesAreIsolated(t *testing.T) {
+	first, second := newTestApp(t), newTestApp(t)
+	token, _ := first.seedTask(t, "first@example.com")
+	// 两个实例的数据库各自独立，相同的用户ID在第二个实例中没有任务
+	if _, err := second.jwt.ParseToken(token); err != nil {
+		t.Fatalf("两个实例使用相同的测试密钥，令牌应可互认: %v", err)
+	}
+	_, resp, data := second.do(t, http.MethodGet, "/api/tasks", token, nil)
+	var tasks []model.AnalysisTask
+	if resp.Code != 0 || json.Unmarshal(data, &tasks) != nil || len(tasks) != 0 {
+		t.Errorf("第二个实例不应看到第一个实例的任务，实际为code=%d, data=%s", resp.Code, data)
+	}
+}
+
+// upload 以multipart表单上传文件
+func (a *testApp) upload(t *testing.T, token string, content []byte) (utils.Response, map[string]string) {
+	t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	part, err := w.CreateFormFile("file", "paper.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	w.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/upload", &buf)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+	var body struct {
+		utils.Response
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("解析响应失败: %v, body=%s", err, rec.Body.String())
+	}
+	return body.Response, body.Data
+}
+
+func TestUploadRejectsInvalidPDFs(t *testing.T) {
+	app := newTestApp(t)
+	token, _ := app.seedTask(t, "upload@example.com")
+	corrupt := []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\n")
+	tests := []struct {
+		name    string
+		content []byte
+		code    int
+		reason  string
+	}{
+		{"空文件", nil, 415, "empty_file"},
+		{"文件头错误", []byte("<html>not a pdf</html>"), 415, "not_pdf"},
+		{"结构损坏", corrupt, 422, "pdf_corrupt"},
+		{"超过大小上限", append([]byte("%PDF-1.4\n"), make([]byte, service.MaxPDFSize)...), 413, "file_too_large"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, data := app.upload(t, token, tt.content)
+			if resp.Code != tt.code || data["reason"] != tt.reason {
+				t.Errorf("期望code=%d reason=%s，实际为code=%d reason=%s", tt.code, tt.reason, resp.Code, data["reason"])
+			}
+		})
+	}
+}
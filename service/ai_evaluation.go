@@ -0,0 +1,198 @@
+package service
+
+import (
+	"math"
+	"strings"
+
+	"papergraph/aitools"
+	"papergraph/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// aiRatingScale AI分析评分（1-5）换算为评价分数（0-10）的倍数
+const aiRatingScale = 2
+
+// aiMetric 由分析报告中某一项评分生成的评价指标
+type aiMetric struct {
+	key      string
+	rating   int
+	evidence []string
+}
+
+// aiDimension 评价维度及其指标来源
+type aiDimension struct {
+	key     string
+	metrics []aiMetric
+}
+
+// aiDimensions 将分析报告的七项内容质量评分映射到评价维度
+// 分析报告不评估语言表达，因此不生成language维度，AI评价的LanguageScore为空表示不适用
+func aiDimensions(a *aitools.PaperAnalysis) []aiDimension {
+	q := a.ContentQuality
+	return []aiDimension{
+		{"originality", []aiMetric{
+			{"innovation", q.Innovation.Rating, []string{q.Innovation.NewIdeasMethodsModels}},
+			{"comparison", q.Innovation.Rating, []string{q.Innovation.BreakthroughsOrImprovements}},
+		}},
+		{"depth", []aiMetric{
+			{"analysis", q.DataAnalysisDepthBreadth.Rating, []string{q.DataAnalysisDepthBreadth.DataMiningConducted, q.DataAnalysisDepthBreadth.StatisticalToolsAndVisualizationsUsed}},
+			{"insight", q.FutureResearchInspiration.Rating, []string{q.FutureResearchInspiration.OffersNewInsightsOrDirections, q.FutureResearchInspiration.RaisesOpenQuestionsOrFutureTopics}},
+		}},
+		{"logic", []aiMetric{
+			{"coherence", q.MethodologyRigor.Rating, []string{q.MethodologyRigor.ExperimentalDesignReasonable}},
+			{"structure", q.MethodologyRigor.Rating, []string{q.MethodologyRigor.ControlVariablesAndStatisticsConsidered}},
+		}},
+		{"evidence", []aiMetric{
+			{"data", q.MethodologyRigor.Rating, []string{q.MethodologyRigor.DataSourcesReliableAndSampleSizeSufficient}},
+			{"citation", q.ResultsValidityReproducibility.Rating, []string{q.ResultsValidityReproducibility.ResultsClearAndCredible, q.ResultsValidityReproducibility.DetailsForReproductionProvided}},
+		}},
+		{"value", []aiMetric{
+			{"contribution", q.ResearchQuestionImportance.Rating, []string{q.ResearchQuestionImportance.AddressesValuableQuestion, q.ResearchQuestionImportance.SignificanceInTheoryOrPractice}},
+			{"application", q.PracticalApplicationValue.Rating, []string{q.PracticalApplicationValue.SolvesRealWorldProblem, q.PracticalApplicationValue.EngineeringOrCommercialPotential}},
+		}},
+	}
+}
+
+// BuildAIEvaluation 根据分析结果生成AI评价（含维度和指标），不写入数据库
+func BuildAIEvaluation(task *model.AnalysisTask, result *model.AnalysisResult) *model.PaperEvaluation {
+	a := result.Analysis
+	names := model.GetDimensionKeyToName()
+	metricNames := model.GetMetricKeyToName()
+
+	evaluation := &model.PaperEvaluation{
+		AnalysisID: result.ID,
+		UserID:     task.UserID,
+		PaperID:    task.PaperID,
+		Source:     model.EvaluationSourceAI,
+		Summary:    joinNonEmpty("\n\n", a.Summary.KeyFindings, a.Summary.Conclusion),
+		IsPublic:   task.IsPublic,
+	}
+
+	scores := make(map[string]float64)
+	for _, d := range aiDimensions(a) {
+		dimension := model.EvaluationDimension{DimensionKey: d.key, DimensionName: names[d.key]}
+		var total float64
+		var evidence []string
+		for _, m := range d.metrics {
+			score := float64(m.rating * aiRatingScale)
+			text := joinNonEmpty("\n", m.evidence...)
+			total += score
+			if text != "" {
+				evidence = append(evidence, text)
+			}
+			dimension.Metrics = append(dimension.Metrics, model.EvaluationMetric{
+				MetricKey:   m.key,
+				MetricName:  metricNames[d.key][m.key],
+				Score:       score,
+				Description: model.GetScoreDescription(score),
+				Evidence:    text,
+			})
+		}
+		dimension.Score = roundScore(total / float64(len(d.metrics)))
+		dimension.Description = model.GetScoreDescription(dimension.Score)
+		dimension.Evidence = strings.Join(evidence, "\n")
+		scores[d.key] = dimension.Score
+		evaluation.Dimensions = append(evaluation.Dimensions, dimension)
+	}
+
+	// 总体评分只由分析报告的评分计算，不含未评估的语言表达
+	var total float64
+	ratings := a.Ratings()
+	for _, r := range ratings {
+		total += float64(r.Rating * aiRatingScale)
+	}
+	evaluation.OverallScore = roundScore(total / float64(len(ratings)))
+	evaluation.Recommendation = model.GetRecommendation(evaluation.OverallScore)
+	evaluation.OriginalityScore = scores["originality"]
+	evaluation.DepthScore = scores["depth"]
+	evaluation.LogicScore = scores["logic"]
+	evaluation.EvidenceScore = scores["evidence"]
+	evaluation.ValueScore = scores["value"]
+	evaluation.ContentScore = roundScore((scores["originality"] + scores["depth"] + scores["value"]) / 3)
+	evaluation.StructureScore = scores["logic"]
+	evaluation.MethodScore = scores["evidence"]
+	return evaluation
+}
+
+// CreateAIEvaluation 在一个事务中为分析结果创建AI评价、维度和指标，并回填任务的阅读建议强度
+// 同一任务之前版本结果生成的AI评价连同维度、指标会被物理删除，保证每个任务只有一份AI评价参与排行和统计
+func (s *EvaluationService) CreateAIEvaluation(task *model.AnalysisTask, result *model.AnalysisResult) (*model.PaperEvaluation, error) {
+	if result.Analysis == nil {
+		return nil, nil
+	}
+	evaluation := BuildAIEvaluation(task, result)
+	suggestScore := int(math.Round(evaluation.OverallScore))
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := deletePreviousAIEvaluations(tx, task.ID, result.ID); err != nil {
+			return err
+		}
+
+		if err := tx.Omit(clause.Associations).Create(evaluation).Error; err != nil {
+			return err
+		}
+		for i := range evaluation.Dimensions {
+			dimension := &evaluation.Dimensions[i]
+			dimension.EvaluationID = evaluation.ID
+			if err := tx.Omit(clause.Associations).Create(dimension).Error; err != nil {
+				return err
+			}
+			for j := range dimension.Metrics {
+				dimension.Metrics[j].DimensionID = dimension.ID
+			}
+			if err := tx.Create(&dimension.Metrics).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&model.AnalysisTask{}).Where("id = ?", task.ID).
+			Update("suggest_score", suggestScore).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	task.SuggestScore = suggestScore
+	return evaluation, nil
+}
+
+// deletePreviousAIEvaluations 物理删除任务之前版本结果生成的AI评价及其维度、指标、评论和点赞
+// 包括早期版本软删除后遗留的记录
+func deletePreviousAIEvaluations(tx *gorm.DB, taskID, resultID uint) error {
+	tx = tx.Unscoped().Session(&gorm.Session{})
+	previous := tx.Model(&model.AnalysisResult{}).Select("id").Where("task_id = ? AND id <> ?", taskID, resultID)
+	var evaluationIDs []uint
+	if err := tx.Model(&model.PaperEvaluation{}).
+		Where("source = ? AND analysis_id IN (?)", model.EvaluationSourceAI, previous).
+		Pluck("id", &evaluationIDs).Error; err != nil {
+		return err
+	}
+	if len(evaluationIDs) == 0 {
+		return nil
+	}
+	dimensions := tx.Model(&model.EvaluationDimension{}).Select("id").Where("evaluation_id IN ?", evaluationIDs)
+	if err := tx.Where("dimension_id IN (?)", dimensions).Delete(&model.EvaluationMetric{}).Error; err != nil {
+		return err
+	}
+	for _, child := range []any{&model.EvaluationDimension{}, &model.EvaluationComment{}, &model.EvaluationLike{}} {
+		if err := tx.Where("evaluation_id IN ?", evaluationIDs).Delete(child).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Where("id IN ?", evaluationIDs).Delete(&model.PaperEvaluation{}).Error
+}
+
+// roundScore 分数保留两位小数，与decimal(5,2)列一致
+func roundScore(score float64) float64 {
+	return math.Round(score*100) / 100
+}
+
+// joinNonEmpty 连接去除首尾空白后的非空文本
+func joinNonEmpty(sep string, texts ...string) string {
+	var parts []string
+	for _, text := range texts {
+		if text = strings.TrimSpace(text); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, sep)
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"papergraph/aitools"
+	"papergraph/model"
+)
+
+func TestCreateAIEvaluationReplacesPrevious(t *testing.T) {
+	db := newTestDB(t)
+	user := createTestUser(t, db, "ai-eval@example.com")
+	paper := &model.Paper{UserID: user.ID, FileName: "paper.pdf"}
+	if err := db.Create(paper).Error; err != nil {
+		t.Fatal(err)
+	}
+	task := &model.AnalysisTask{UserID: user.ID, PaperID: paper.ID, Status: model.TaskStatusCompleted}
+	if err := db.Create(task).Error; err != nil {
+		t.Fatal(err)
+	}
+	analysis, _, err := aitools.NewFakeProvider().AnalyzePaper(context.Background(), &aitools.PaperInput{FileName: paper.FileName})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evaluations := NewEvaluationService(db)
+	var first *model.PaperEvaluation
+	for version := 1; version <= 2; version++ {
+		result := &model.AnalysisResult{TaskID: task.ID, Version: version, Analysis: analysis}
+		if err := db.Create(result).Error; err != nil {
+			t.Fatal(err)
+		}
+		evaluation, err := evaluations.CreateAIEvaluation(task, result)
+		if err != nil {
+			t.Fatalf("第%d次生成AI评价失败: %v", version, err)
+		}
+		if first == nil {
+			first = evaluation
+		}
+		if evaluation.LanguageScore != nil {
+			t.Errorf("AI评价不评估语言表达，LanguageScore应为空，实际为%v", *evaluation.LanguageScore)
+		}
+	}
+
+	var evaluationCount, dimensionCount, metricCount int64
+	db.Unscoped().Model(&model.PaperEvaluation{}).Where("paper_id = ?", paper.ID).Count(&evaluationCount)
+	db.Unscoped().Model(&model.EvaluationDimension{}).Where("evaluation_id = ?", first.ID).Count(&dimensionCount)
+	db.Unscoped().Model(&model.EvaluationMetric{}).
+		Where("dimension_id IN (?)", db.Model(&model.EvaluationDimension{}).Select("id").Where("evaluation_id = ?", first.ID)).
+		Count(&metricCount)
+	if evaluationCount != 1 {
+		t.Errorf("每个任务应只保留一份AI评价，实际为%d", evaluationCount)
+	}
+	if dimensionCount != 0 || metricCount != 0 {
+		t.Errorf("之前的AI评价的维度和指标应被删除，实际剩余%d个维度、%d个指标", dimensionCount, metricCount)
+	}
+}
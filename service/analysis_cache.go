@@ -0,0 +1,54 @@
+package service
+
+import (
+	"time"
+
+	"papergraph/aitools"
+	"papergraph/model"
+
+	"gorm.io/gorm"
+)
+
+// AnalysisModel 默认的模型服务提供方和模型名称，上传时按提供方和模型名称查找可复用的分析结果
+// 为空时上传不查找缓存，由工作池执行任务时再查找
+type AnalysisModel struct {
+	Provider string
+	Name     string
+}
+
+// findCachedResult 按（内容哈希, 提供方, 模型, prompt版本）查找可复用的分析结果，不存在时返回nil
+// 不同提供方可能使用同名模型（如代理服务），提供方不同的结果不复用；excludeTaskID非0时排除该任务自身的结果
+func findCachedResult(db *gorm.DB, contentHash, provider, modelName string, excludeTaskID uint) (*model.AnalysisResult, error) {
+	if contentHash == "" || provider == "" || modelName == "" {
+		return nil, nil
+	}
+	var result model.AnalysisResult
+	err := db.Where("content_hash = ? AND provider = ? AND model = ? AND prompt_version = ? AND task_id <> ?",
+		contentHash, provider, modelName, aitools.PaperAnalysisPromptVersion, excludeTaskID).
+		Order("id desc").Limit(1).Find(&result).Error
+	if err != nil {
+		return nil, err
+	}
+	if result.ID == 0 || result.Analysis == nil {
+		return nil, nil
+	}
+	return &result, nil
+}
+
+// cachedResultCopy 复制缓存的分析结果用于新任务，不计token用量
+func cachedResultCopy(src *model.AnalysisResult, now time.Time) *model.AnalysisResult {
+	origin := src.ID
+	if src.CachedFromID != nil {
+		origin = *src.CachedFromID
+	}
+	return &model.AnalysisResult{
+		Provider:      src.Provider,
+		Content:       src.Content,
+		Analysis:      src.Analysis,
+		Model:         src.Model,
+		PromptVersion: src.PromptVersion,
+		ContentHash:   src.ContentHash,
+		CachedFromID:  &origin,
+		CreatedAt:     now,
+	}
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"papergraph/aitools"
+	"papergraph/metrics"
+	"papergraph/model"
+	"strings"
+	"sync"
+)
+
+// ProviderFactory 按名称和模型创建模型服务提供方，modelName为空时使用提供方默认模型
+type ProviderFactory func(provider, modelName string) (aitools.LLMProvider, error)
+
+// AnalysisPipeline 论文分析执行流程
+// 从对象存储取回PDF，交给配置的大模型服务分析，返回结构化分析结果
+type AnalysisPipeline struct {
+	provider aitools.LLMProvider
+	factory  ProviderFactory
+	papers   *PaperService // 读取论文文件和提取的文本
+}
+
+// NewAnalysisPipeline 创建论文分析执行流程
+// provider为默认模型服务；任务指定了提供方或模型时通过factory创建
+func NewAnalysisPipeline(provider aitools.LLMProvider, factory ProviderFactory, papers *PaperService) *AnalysisPipeline {
+	return &AnalysisPipeline{provider: provider, factory: factory, papers: papers}
+}
+
+// providerFor 返回任务使用的模型服务提供方
+func (p *AnalysisPipeline) providerFor(task *model.AnalysisTask) (aitools.LLMProvider, error) {
+	if task.Provider == "" && task.Model == "" {
+		return p.provider, nil
+	}
+	if p.factory == nil {
+		return nil, fmt.Errorf("不支持为任务指定模型")
+	}
+	name := task.Provider
+	if name == "" {
+		name = p.provider.Name()
+	}
+	return p.factory(name, task.Model)
+}
+
+// RunAnalysisTask 执行单个分析任务，由分析工作池调用
+func (p *AnalysisPipeline) RunAnalysisTask(ctx context.Context, task *model.AnalysisTask, report TaskProgressFunc) (*model.AnalysisResult, error) {
+	provider, err := p.providerFor(task)
+	if err != nil {
+		return nil, fmt.Errorf("创建模型服务失败: %w", err)
+	}
+	report = forwardProgress(report)
+	start := p.papers.clock.Now()
+	var result *model.AnalysisResult
+	if task.Type == model.TaskTypeComparison {
+		result, err = p.runComparison(ctx, task, provider, report)
+	} else {
+		result, err = p.runAnalysis(ctx, task, provider, report)
+	}
+	// 用户取消或退出时中断的执行不计入耗时和失败次数
+	if ctx.Err() == nil {
+		labels := []string{provider.Name(), task.Type}
+		metrics.AnalysisTaskDuration.WithLabelValues(labels...).Observe(p.papers.clock.Now().Sub(start).Seconds())
+		if err != nil {
+			metrics.AnalysisTaskFailures.WithLabelValues(labels...).Inc()
+		}
+	}
+	return result, err
+}
+
+// runAnalysis 执行单篇论文分析任务，相同文件已分析过时复用结果
+func (p *AnalysisPipeline) runAnalysis(ctx context.Context, task *model.AnalysisTask, provider aitools.LLMProvider, report TaskProgressFunc) (*model.AnalysisResult, error) {
+	var paper model.Paper
+	if err := p.papers.db.First(&paper, task.PaperID).Error; err != nil {
+		return nil, fmt.Errorf("论文不存在: %w", err)
+	}
+
+	// 相同文件已用同一提供方、模型和prompt版本分析过时直接复用结果
+	if !task.ForceFresh {
+		cached, err := findCachedResult(p.papers.db, paper.ContentHash, provider.Name(), provider.ModelName(), task.ID)
+		if err != nil {
+			return nil, fmt.Errorf("查询缓存结果失败: %w", err)
+		}
+		if cached != nil {
+			report(model.TaskStageSaving, 90)
+			return cachedResultCopy(cached, p.papers.clock.Now()), nil
+		}
+	}
+
+	report(model.TaskStageDownloading, 5)
+	data, err := p.papers.readFile(ctx, paper.OSSPath)
+	if err != nil {
+		return nil, fmt.Errorf("下载论文失败: %w", err)
+	}
+
+	// 本地提取文本，提取失败（如扫描件）时仍交给模型直接分析PDF
+	report(model.TaskStageExtracting, 8)
+	input := &aitools.PaperInput{PDF: data, FileName: paper.FileName}
+	if content, err := p.papers.GetOrExtractContent(ctx, &paper, data); err == nil && content.CharCount > 0 {
+		input.Text = content.FullText()
+		input.Pages = content.Pages
+	}
+
+	// 需要上传PDF时，上传进度映射到任务整体进度的10%-40%，上传完成后进入分析阶段；
+	// 按文本分析时不会有上传进度，调用模型前直接进入分析阶段
+	if aitools.UploadsPDF(input) {
+		report(model.TaskStageUploading, 10)
+	}
+	// 长文档分块分析时，分块进度映射到50%-85%
+	opts := aitools.LongDocOptions{
+		OnChunk: func(done, total int) {
+			report(model.TaskStageAnalyzing, 50+done*35/total)
+		},
+		OnAnalyzing: func() {
+			report(model.TaskStageAnalyzing, 50)
+		},
+	}
+	input.OnProgress = func(current, total int64) bool {
+		if total > 0 && current >= total {
+			report(model.TaskStageAnalyzing, 50)
+		} else if total > 0 {
+			report(model.TaskStageUploading, 10+int(current*30/total))
+		}
+		return ctx.Err() == nil
+	}
+	analysis, usage, err := aitools.AnalyzePaperAuto(ctx, provider, input, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s分析失败: %w", provider.Name(), err)
+	}
+	// usage中为提供方报告的用量，未报告时为估算值
+	metrics.AddLLMTokens(provider.Name(), usage.InputTokens, usage.OutputTokens)
+	// 记录实际使用的模型，缓存只命中同一模型生成的结果
+	modelName := usage.Model
+	if modelName == "" {
+		modelName = provider.ModelName()
+	}
+
+	report(model.TaskStageSaving, 90)
+	return &model.AnalysisResult{
+		TaskID:        task.ID,
+		Content:       analysisDigest(analysis),
+		Analysis:      analysis,
+		Provider:      provider.Name(),
+		Model:         modelName,
+		PromptVersion: aitools.PaperAnalysisPromptVersion,
+		Usage:         usage,
+		ContentHash:   paper.ContentHash,
+		CreatedAt:     p.papers.clock.Now(),
+	}, nil
+}
+
+// runComparison 执行多篇论文对比任务：依次取回各篇论文，交给模型对比分析
+func (p *AnalysisPipeline) runComparison(ctx context.Context, task *model.AnalysisTask, provider aitools.LLMProvider, report TaskProgressFunc) (*model.AnalysisResult, error) {
+	n := len(task.PaperIDs)
+	inputs := make([]*aitools.PaperInput, 0, n)
+	for i, paperID := range task.PaperIDs {
+		var paper model.Paper
+		if err := p.papers.db.First(&paper, paperID).Error; err != nil {
+			return nil, fmt.Errorf("论文%d不存在: %w", i+1, err)
+		}
+		report(model.TaskStageDownloading, 2+i*8/n)
+		data, err := p.papers.readFile(ctx, paper.OSSPath)
+		if err != nil {
+			return nil, fmt.Errorf("下载论文%d失败: %w", i+1, err)
+		}
+		input := &aitools.PaperInput{PDF: data, FileName: paper.FileName}
+		if content, err := p.papers.GetOrExtractContent(ctx, &paper, data); err == nil && content.CharCount > 0 {
+			input.Text = content.FullText()
+			input.Pages = content.Pages
+		}
+		inputs = append(inputs, input)
+	}
+
+	// 需要上传时，各篇论文的上传进度依次映射到任务整体进度的10%-40%；全部上传完成或不需要上传时由模型服务通知进入分析阶段
+	for i, input := range inputs {
+		input.OnProgress = func(current, total int64) bool {
+			if total > 0 {
+				report(model.TaskStageUploading, 10+(i*30+int(current*30/total))/n)
+			}
+			return ctx.Err() == nil
+		}
+	}
+	opts := aitools.CompareOptions{
+		OnAnalyzing: func() {
+			report(model.TaskStageAnalyzing, 50)
+		},
+	}
+	comparison, usage, err := aitools.ComparePapers(ctx, provider, inputs, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s对比分析失败: %w", provider.Name(), err)
+	}
+	if usage.Reported() {
+		metrics.AddLLMTokens(provider.Name(), usage.InputTokens, usage.OutputTokens)
+	} else {
+		inputTokens := 0
+		for _, input := range inputs {
+			inputTokens += aitools.EstimateTokens(input.Text)
+		}
+		metrics.AddLLMTokens(provider.Name(), inputTokens, aitools.EstimateTokens(comparisonDigest(comparison)))
+	}
+	modelName := usage.Model
+	if modelName == "" {
+		modelName = provider.ModelName()
+	}
+
+	report(model.TaskStageSaving, 90)
+	return &model.AnalysisResult{
+		TaskID:        task.ID,
+		Content:       comparisonDigest(comparison),
+		Comparison:    comparison,
+		Provider:      provider.Name(),
+		Model:         modelName,
+		PromptVersion: aitools.ComparisonPromptVersion,
+		CreatedAt:     p.papers.clock.Now(),
+	}, nil
+}
+
+// forwardProgress 包装进度回调，忽略小于已上报进度的上报，推送的进度不会回退
+func forwardProgress(report TaskProgressFunc) TaskProgressFunc {
+	var mu sync.Mutex
+	last := 0
+	return func(stage string, progress int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if progress < last {
+			return
+		}
+		last = progress
+		report(stage, progress)
+	}
+}
+
+// comparisonDigest 由对比结果生成纯文本摘要
+func comparisonDigest(c *aitools.PaperComparison) string {
+	return joinNonEmpty("\n\n", c.Topic, c.Summary, c.Recommendation)
+}
+
+// analysisDigest 由结构化分析生成纯文本摘要，兼容只读取content字段的旧客户端
+func analysisDigest(a *aitools.PaperAnalysis) string {
+	var parts []string
+	for _, text := range []string{a.BasicInfo.Title, a.Summary.Purpose, a.Summary.KeyFindings, a.Summary.Conclusion} {
+		if text = strings.TrimSpace(text); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
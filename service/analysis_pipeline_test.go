@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"papergraph/aitools"
+	"papergraph/config"
+	"papergraph/model"
+
+	"go.uber.org/zap"
+)
+
+func TestAnalysisWorkerRunsPipeline(t *testing.T) {
+	db := newTestDB(t)
+	user := createTestUser(t, db, "pipeline@example.com")
+	conf := config.Default(config.ProfileTest)
+	worker := NewAnalysisWorker(db, zap.NewNop(), conf.AnalysisWorker, NewTaskEventBus(SystemClock{}), SystemClock{})
+	papers := newTestPaperService(t, db, worker)
+
+	paper, task, err := papers.UploadAndCreateTask(user.ID, spoolTestPDF(t, "pipeline.pdf", "Graph neural networks for citation analysis"), false)
+	if err != nil {
+		t.Fatalf("上传论文失败: %v", err)
+	}
+	if task.Status != model.TaskStatusQueued {
+		t.Fatalf("新上传的论文应排队等待分析，实际为%s", task.Status)
+	}
+
+	// 先订阅再启动工作池，确保收到领取和完成事件
+	events, unsubscribe := worker.Events().Subscribe(task.ID)
+	defer unsubscribe()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	worker.Start(ctx, NewAnalysisPipeline(aitools.NewFakeProvider(), nil, papers))
+	defer worker.Shutdown(context.Background())
+
+	timeout := time.After(10 * time.Second)
+	claimed := false
+	for done := false; !done; {
+		select {
+		case event := <-events:
+			switch event.Status {
+			case model.TaskStatusRunning:
+				claimed = true
+			case model.TaskStatusCompleted:
+				done = true
+			case model.TaskStatusFailed:
+				t.Fatalf("分析任务失败: %s", event.Error)
+			}
+		case <-timeout:
+			t.Fatal("等待分析任务完成超时")
+		}
+	}
+	if !claimed {
+		t.Error("任务完成前应推送领取事件")
+	}
+
+	var saved model.AnalysisTask
+	if err := db.First(&saved, task.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if saved.Status != model.TaskStatusCompleted || saved.Progress != 100 || saved.Attempts != 1 || saved.FinishedAt == nil {
+		t.Errorf("任务状态错误: status=%s, progress=%d, attempts=%d", saved.Status, saved.Progress, saved.Attempts)
+	}
+
+	var result model.AnalysisResult
+	if err := db.Where("task_id = ?", task.ID).First(&result).Error; err != nil {
+		t.Fatalf("应保存分析结果: %v", err)
+	}
+	if result.Provider != aitools.ProviderFake || result.Model != aitools.FakeModel || result.ContentHash != paper.ContentHash {
+		t.Errorf("分析结果的缓存键错误: provider=%s, model=%s, content_hash=%s", result.Provider, result.Model, result.ContentHash)
+	}
+	if result.Analysis == nil || result.Analysis.BasicInfo.Title != "pipeline" {
+		t.Errorf("分析内容错误: %+v", result.Analysis)
+	}
+
+	var evaluation model.PaperEvaluation
+	if err := db.Where("paper_id = ? AND source = ?", paper.ID, model.EvaluationSourceAI).First(&evaluation).Error; err != nil {
+		t.Fatalf("应根据分析结果生成AI评价: %v", err)
+	}
+	if evaluation.AnalysisID != result.ID || evaluation.OverallScore <= 0 {
+		t.Errorf("AI评价错误: analysis_id=%d, overall_score=%v", evaluation.AnalysisID, evaluation.OverallScore)
+	}
+
+	var updated model.Paper
+	if err := db.First(&updated, paper.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status != "已完成" {
+		t.Errorf("分析完成后论文状态应为已完成，实际为%s", updated.Status)
+	}
+}
+
+// textOnlyProvider 按文本分析、从不上传PDF的假模型，不调用上传进度回调
+type textOnlyProvider struct {
+	*aitools.FakeProvider
+}
+
+func (p textOnlyProvider) AnalyzePaper(ctx context.Context, input *aitools.PaperInput) (*aitools.PaperAnalysis, aitools.Usage, error) {
+	text := *input
+	text.OnProgress = nil
+	return p.FakeProvider.AnalyzePaper(ctx, &text)
+}
+
+// stageReport 记录的一次进度上报
+type stageReport struct {
+	Stage    string
+	Progress int
+}
+
+// usableTestText 足够长、可以代替PDF原文分析的测试文本
+var usableTestText = strings.Repeat("Graph neural networks for citation analysis. ", 20)
+
+func TestRunAnalysisReportsAnalyzingWithoutUpload(t *testing.T) {
+	db := newTestDB(t)
+	user := createTestUser(t, db, "text-only@example.com")
+	papers := newTestPaperService(t, db, nil)
+	_, task, err := papers.UploadAndCreateTask(user.ID, spoolTestPDF(t, "text.pdf", usableTestText), false)
+	if err != nil {
+		t.Fatalf("上传论文失败: %v", err)
+	}
+
+	var reports []stageReport
+	pipeline := NewAnalysisPipeline(textOnlyProvider{aitools.NewFakeProvider()}, nil, papers)
+	if _, err := pipeline.RunAnalysisTask(context.Background(), task, func(stage string, progress int) {
+		reports = append(reports, stageReport{stage, progress})
+	}); err != nil {
+		t.Fatalf("分析失败: %v", err)
+	}
+
+	analyzing := false
+	for _, r := range reports {
+		if r.Stage == model.TaskStageUploading {
+			t.Errorf("按文本分析时不应进入上传阶段，实际上报为%v", reports)
+		}
+		if r.Stage == model.TaskStageAnalyzing {
+			analyzing = true
+		}
+	}
+	if !analyzing {
+		t.Errorf("调用模型前应进入分析阶段，实际上报为%v", reports)
+	}
+}
+
+// uploadingComparer 模拟逐篇上传PDF后再对比的模型服务
+type uploadingComparer struct {
+	*aitools.FakeProvider
+}
+
+func (p uploadingComparer) ComparePapers(ctx context.Context, inputs []*aitools.PaperInput, opts aitools.CompareOptions) (*aitools.PaperComparison, aitools.Usage, error) {
+	for _, input := range inputs {
+		total := int64(len(input.PDF))
+		input.OnProgress(total/2, total)
+		input.OnProgress(total, total)
+	}
+	opts.OnAnalyzing()
+	return p.FakeProvider.ComparePapers(ctx, inputs, aitools.CompareOptions{})
+}
+
+func TestRunComparisonProgressNeverDecreases(t *testing.T) {
+	db := newTestDB(t)
+	user := createTestUser(t, db, "compare@example.com")
+	papers := newTestPaperService(t, db, nil)
+	var paperIDs []uint
+	for _, name := range []string{"a.pdf", "b.pdf"} {
+		paper, _, err := papers.UploadAndCreateTask(user.ID, spoolTestPDF(t, name, "Paper "+name), false)
+		if err != nil {
+			t.Fatalf("上传论文失败: %v", err)
+		}
+		paperIDs = append(paperIDs, paper.ID)
+	}
+	task := &model.AnalysisTask{UserID: user.ID, Type: model.TaskTypeComparison, PaperIDs: paperIDs, Status: model.TaskStatusRunning}
+	if err := db.Create(task).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var reports []stageReport
+	pipeline := NewAnalysisPipeline(uploadingComparer{aitools.NewFakeProvider()}, nil, papers)
+	if _, err := pipeline.RunAnalysisTask(context.Background(), task, func(stage string, progress int) {
+		reports = append(reports, stageReport{stage, progress})
+	}); err != nil {
+		t.Fatalf("对比分析失败: %v", err)
+	}
+
+	uploaded, analyzing := false, false
+	for i, r := range reports {
+		if i > 0 && r.Progress < reports[i-1].Progress {
+			t.Errorf("进度不应回退，实际上报为%v", reports)
+		}
+		switch r.Stage {
+		case model.TaskStageUploading:
+			uploaded = true
+			if analyzing {
+				t.Errorf("进入分析阶段后不应回到上传阶段，实际上报为%v", reports)
+			}
+		case model.TaskStageAnalyzing:
+			analyzing = true
+		}
+	}
+	if !uploaded || !analyzing {
+		t.Errorf("应依次经过上传和分析阶段，实际上报为%v", reports)
+	}
+}
@@ -3,65 +3,63 @@ package service
 import (
 	"errors"
 	"fmt"
-	"papergraph/config"
+	"papergraph/aitools"
 	"papergraph/model"
+	"slices"
 	"time"
 
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // AnalysisService 分析任务相关业务逻辑
-type AnalysisService struct{}
+type AnalysisService struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	tasks  TaskDispatcher // 任务入队时通知分析工作池，可为nil
+	model  AnalysisModel  // 默认模型，用户可选择其提供方
+	clock  Clock
+}
 
 // NewAnalysisService 创建AnalysisService实例
-func NewAnalysisService() *AnalysisService {
-	return &AnalysisService{}
+func NewAnalysisService(db *gorm.DB, logger *zap.Logger, tasks TaskDispatcher, model AnalysisModel, clock Clock) *AnalysisService {
+	return &AnalysisService{db: db, logger: logger, tasks: tasks, model: model, clock: clock}
 }
 
-// StartAnalysisTask 启动分析任务，调用Gemini分析（mock），保存结果
+// StartAnalysisTask 将分析任务加入后台队列，由分析工作池异步执行
 func (s *AnalysisService) StartAnalysisTask(taskID uint) error {
-	config.Logger.Info("启动分析任务", zap.Uint("task_id", taskID))
-	db := config.DB
+	s.logger.Info("启动分析任务", zap.Uint("task_id", taskID))
+	db := s.db
 	var task model.AnalysisTask
 	if err := db.First(&task, taskID).Error; err != nil {
-		config.Logger.Error("任务不存在", zap.Error(err), zap.Uint("task_id", taskID))
+		s.logger.Error("任务不存在", zap.Error(err), zap.Uint("task_id", taskID))
 		return errors.New("任务不存在")
 	}
-	if task.Status != "进行中" {
-		config.Logger.Warn("任务状态异常", zap.String("status", task.Status), zap.Uint("task_id", taskID))
+	switch task.Status {
+	case model.TaskStatusQueued:
+		// 已在队列中，唤醒工作池即可
+	case model.TaskStatusRunning:
+		return errors.New("任务正在分析中")
+	case model.TaskStatusCompleted:
+		return errors.New("任务已完成")
+	case model.TaskStatusCanceled:
+		return errors.New("任务已取消，请使用重试")
+	default:
+		s.logger.Warn("任务状态异常", zap.String("status", task.Status), zap.Uint("task_id", taskID))
 		return errors.New("任务状态异常")
 	}
-	// mock分析过程（实际应调用Gemini API）
-	resultContent := fmt.Sprintf("[Mock分析结果] 论文ID: %d, 分析时间: %s", task.PaperID, time.Now().Format(time.RFC3339))
-	// 保存分析结果
-	result := model.AnalysisResult{
-		TaskID:    task.ID,
-		Content:   resultContent,
-		CreatedAt: time.Now(),
-	}
-	if err := db.Create(&result).Error; err != nil {
-		config.Logger.Error("保存分析结果失败", zap.Error(err), zap.Uint("task_id", taskID))
-		return err
-	}
-	// 更新任务状态
-	finishTime := time.Now()
-	task.Status = "已完成"
-	task.FinishedAt = &finishTime
-	if err := db.Save(&task).Error; err != nil {
-		config.Logger.Error("更新任务状态失败", zap.Error(err), zap.Uint("task_id", taskID))
-		return err
-	}
-	config.Logger.Info("分析任务完成", zap.Uint("task_id", taskID))
+	notifyTasks(s.tasks)
+	s.logger.Info("分析任务已加入队列", zap.Uint("task_id", taskID))
 	return nil
 }
 
 // GetUserAnalysisTasks 获取用户历史分析任务，按时间倒序
 func (s *AnalysisService) GetUserAnalysisTasks(userID uint) ([]model.AnalysisTask, error) {
-	config.Logger.Info("获取用户历史分析任务", zap.Uint("user_id", userID))
-	db := config.DB
+	s.logger.Info("获取用户历史分析任务", zap.Uint("user_id", userID))
+	db := s.db
 	var tasks []model.AnalysisTask
 	if err := db.Where("user_id = ?", userID).Order("created_at desc").Find(&tasks).Error; err != nil {
-		config.Logger.Error("查询历史任务失败", zap.Error(err), zap.Uint("user_id", userID))
+		s.logger.Error("查询历史任务失败", zap.Error(err), zap.Uint("user_id", userID))
 		return nil, err
 	}
 	return tasks, nil
@@ -69,68 +67,287 @@ func (s *AnalysisService) GetUserAnalysisTasks(userID uint) ([]model.AnalysisTas
 
 // GetAnalysisTaskDetail 获取单个分析任务详情
 func (s *AnalysisService) GetAnalysisTaskDetail(taskID uint) (*model.AnalysisTask, error) {
-	config.Logger.Info("获取分析任务详情", zap.Uint("task_id", taskID))
-	db := config.DB
+	s.logger.Info("获取分析任务详情", zap.Uint("task_id", taskID))
+	db := s.db
 	var task model.AnalysisTask
 	if err := db.First(&task, taskID).Error; err != nil {
-		config.Logger.Error("查询任务详情失败", zap.Error(err), zap.Uint("task_id", taskID))
+		s.logger.Error("查询任务详情失败", zap.Error(err), zap.Uint("task_id", taskID))
 		return nil, err
 	}
 	return &task, nil
 }
 
-// GetAnalysisResult 获取分析结果
+// GetAnalysisResult 获取分析结果，包含结构化分析报告
 func (s *AnalysisService) GetAnalysisResult(taskID uint) (*model.AnalysisResult, error) {
-	config.Logger.Info("获取分析结果", zap.Uint("task_id", taskID))
-	db := config.DB
+	s.logger.Info("获取分析结果", zap.Uint("task_id", taskID))
+	db := s.db
 	var result model.AnalysisResult
-	if err := db.Where("task_id = ?", taskID).First(&result).Error; err != nil {
-		config.Logger.Error("查询分析结果失败", zap.Error(err), zap.Uint("task_id", taskID))
+	if err := db.Where("task_id = ?", taskID).Order("id desc").First(&result).Error; err != nil {
+		s.logger.Error("查询分析结果失败", zap.Error(err), zap.Uint("task_id", taskID))
 		return nil, err
 	}
 	return &result, nil
 }
 
-// GetUserActiveTasks 获取用户正在分析的任务，最多2个，按创建时间倒序
+// GetUserActiveTasks 获取用户排队中和正在分析的任务，最多2个，按创建时间倒序
+// 任务的stage、progress、attempts由分析工作池实时更新
 func (s *AnalysisService) GetUserActiveTasks(userID uint) ([]model.AnalysisTask, error) {
-	config.Logger.Info("获取用户正在分析的任务", zap.Uint("user_id", userID))
-	db := config.DB
+	s.logger.Info("获取用户正在分析的任务", zap.Uint("user_id", userID))
+	db := s.db
 	var tasks []model.AnalysisTask
-	if err := db.Where("user_id = ? AND status = ?", userID, "进行中").Order("created_at desc").Limit(2).Find(&tasks).Error; err != nil {
-		config.Logger.Error("查询进行中任务失败", zap.Error(err), zap.Uint("user_id", userID))
+	if err := db.Where("user_id = ? AND status IN ?", userID, []string{model.TaskStatusQueued, model.TaskStatusRunning}).
+		Order("created_at desc").Limit(2).Find(&tasks).Error; err != nil {
+		s.logger.Error("查询进行中任务失败", zap.Error(err), zap.Uint("user_id", userID))
 		return nil, err
 	}
 	return tasks, nil
 }
 
+// CancelTask 取消排队中或正在分析的任务（仅本人可操作）
+// 正在执行的任务会中断模型调用，已产生的中间结果不会保存
+func (s *AnalysisService) CancelTask(userID, taskID uint) error {
+	s.logger.Info("取消分析任务", zap.Uint("user_id", userID), zap.Uint("task_id", taskID))
+	task, err := s.getOwnTask(userID, taskID)
+	if err != nil {
+		return err
+	}
+	if task.Status != model.TaskStatusQueued && task.Status != model.TaskStatusRunning {
+		return errors.New("只能取消排队中或分析中的任务")
+	}
+	now := s.clock.Now()
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.AnalysisTask{}).
+			Where("id = ? AND status IN ?", task.ID, []string{model.TaskStatusQueued, model.TaskStatusRunning}).
+			Updates(map[string]interface{}{
+				"status":      model.TaskStatusCanceled,
+				"stage":       model.TaskStageCanceled,
+				"finished_at": now,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errors.New("任务状态已变化，请刷新后重试")
+		}
+		return updateTaskPaperStatus(tx, task, "已取消", now)
+	})
+	if err != nil {
+		s.logger.Error("取消分析任务失败", zap.Error(err), zap.Uint("task_id", taskID))
+		return err
+	}
+	cancelRunningTask(s.tasks, task.ID)
+	s.logger.Info("分析任务已取消", zap.Uint("task_id", taskID))
+	return nil
+}
+
+// RetryTask 重新执行失败或已取消的任务（仅本人可操作），沿用原有模型设置
+func (s *AnalysisService) RetryTask(userID, taskID uint) error {
+	s.logger.Info("重试分析任务", zap.Uint("user_id", userID), zap.Uint("task_id", taskID))
+	task, err := s.getOwnTask(userID, taskID)
+	if err != nil {
+		return err
+	}
+	if task.Status != model.TaskStatusFailed && task.Status != model.TaskStatusCanceled {
+		return errors.New("只能重试失败或已取消的任务")
+	}
+	return s.requeueTask(task, []string{model.TaskStatusFailed, model.TaskStatusCanceled}, task.Provider, task.Model, task.ForceFresh)
+}
+
+// ReanalyzeTask 使用指定的模型服务提供方和模型重新分析（仅本人可操作）
+// 之前的分析结果会保留为历史版本；provider、modelName为空时使用默认配置；重新分析不复用缓存结果
+func (s *AnalysisService) ReanalyzeTask(userID, taskID uint, provider, modelName string) error {
+	s.logger.Info("重新分析任务", zap.Uint("user_id", userID), zap.Uint("task_id", taskID),
+		zap.String("provider", provider), zap.String("model", modelName))
+	if provider != "" && !s.isSelectableProvider(provider) {
+		return errors.New("不支持的模型服务提供方")
+	}
+	task, err := s.getOwnTask(userID, taskID)
+	if err != nil {
+		return err
+	}
+	finished := []string{model.TaskStatusCompleted, model.TaskStatusFailed, model.TaskStatusCanceled}
+	if !slices.Contains(finished, task.Status) {
+		return errors.New("任务正在进行中，请等待完成或先取消")
+	}
+	return s.requeueTask(task, finished, provider, modelName, true)
+}
+
+// ListAnalysisResults 获取任务的全部分析结果版本，按版本倒序
+// 任务所有者或公开任务可查看
+func (s *AnalysisService) ListAnalysisResults(userID, taskID uint) ([]model.AnalysisResult, error) {
+	s.logger.Info("获取分析结果版本列表", zap.Uint("user_id", userID), zap.Uint("task_id", taskID))
+	db := s.db
+	var task model.AnalysisTask
+	if err := db.First(&task, taskID).Error; err != nil {
+		return nil, errors.New("任务不存在")
+	}
+	if task.UserID != userID && !task.IsPublic {
+		return nil, errors.New("无权查看")
+	}
+	var results []model.AnalysisResult
+	if err := db.Where("task_id = ?", taskID).Order("version desc, id desc").Find(&results).Error; err != nil {
+		s.logger.Error("查询分析结果版本失败", zap.Error(err), zap.Uint("task_id", taskID))
+		return nil, err
+	}
+	return results, nil
+}
+
+// CreateComparisonTask 创建多篇论文对比任务
+// paperIDs按对比编号顺序排列，每篇论文需为本人上传或已公开分析；provider、modelName为空时使用默认配置
+func (s *AnalysisService) CreateComparisonTask(userID uint, paperIDs []uint, provider, modelName string) (*model.AnalysisTask, error) {
+	s.logger.Info("创建对比分析任务", zap.Uint("user_id", userID), zap.Uints("paper_ids", paperIDs),
+		zap.String("provider", provider), zap.String("model", modelName))
+	if len(paperIDs) < aitools.MinComparePapers || len(paperIDs) > aitools.MaxComparePapers {
+		return nil, fmt.Errorf("对比论文数量需在%d-%d篇之间", aitools.MinComparePapers, aitools.MaxComparePapers)
+	}
+	if provider != "" && !s.isSelectableProvider(provider) {
+		return nil, errors.New("不支持的模型服务提供方")
+	}
+	db := s.db
+	seen := make(map[uint]bool)
+	for _, paperID := range paperIDs {
+		if seen[paperID] {
+			return nil, errors.New("对比论文不能重复")
+		}
+		seen[paperID] = true
+		var paper model.Paper
+		if err := db.First(&paper, paperID).Error; err != nil {
+			return nil, fmt.Errorf("论文%d不存在", paperID)
+		}
+		ok, err := paperAccessible(s.db, userID, &paper)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("无权访问论文%d", paperID)
+		}
+	}
+
+	task := &model.AnalysisTask{
+		UserID:    userID,
+		Type:      model.TaskTypeComparison,
+		PaperIDs:  paperIDs,
+		Status:    model.TaskStatusQueued,
+		Stage:     model.TaskStageQueued,
+		Provider:  provider,
+		Model:     modelName,
+		CreatedAt: s.clock.Now(),
+	}
+	if err := db.Create(task).Error; err != nil {
+		s.logger.Error("创建对比分析任务失败", zap.Error(err), zap.Uint("user_id", userID))
+		return nil, err
+	}
+	publishTaskQueued(s.tasks, task.ID)
+	notifyTasks(s.tasks)
+	s.logger.Info("对比分析任务已加入队列", zap.Uint("task_id", task.ID))
+	return task, nil
+}
+
+// getOwnTask 查询任务并校验归属
+func (s *AnalysisService) getOwnTask(userID, taskID uint) (*model.AnalysisTask, error) {
+	var task model.AnalysisTask
+	if err := s.db.First(&task, taskID).Error; err != nil {
+		s.logger.Warn("任务不存在", zap.Error(err), zap.Uint("task_id", taskID))
+		return nil, errors.New("任务不存在")
+	}
+	if task.UserID != userID {
+		s.logger.Warn("无权操作", zap.Uint("user_id", userID), zap.Uint("task_id", taskID))
+		return nil, errors.New("无权操作")
+	}
+	return &task, nil
+}
+
+// requeueTask 将已结束的任务重新放入队列，重置执行次数
+// forceFresh为true时跳过分析结果缓存
+func (s *AnalysisService) requeueTask(task *model.AnalysisTask, fromStatus []string, provider, modelName string, forceFresh bool) error {
+	now := s.clock.Now()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.AnalysisTask{}).
+			Where("id = ? AND status IN ?", task.ID, fromStatus).
+			Updates(map[string]interface{}{
+				"status":      model.TaskStatusQueued,
+				"stage":       model.TaskStageQueued,
+				"progress":    0,
+				"attempts":    0,
+				"last_error":  "",
+				"worker_id":   "",
+				"next_run_at": nil,
+				"finished_at": nil,
+				"provider":    provider,
+				"model":       modelName,
+				"force_fresh": forceFresh,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errors.New("任务状态已变化，请刷新后重试")
+		}
+		return updateTaskPaperStatus(tx, task, "分析中", now)
+	})
+	if err != nil {
+		s.logger.Error("任务重新入队失败", zap.Error(err), zap.Uint("task_id", task.ID))
+		return err
+	}
+	publishTaskQueued(s.tasks, task.ID)
+	notifyTasks(s.tasks)
+	s.logger.Info("任务已重新加入分析队列", zap.Uint("task_id", task.ID))
+	return nil
+}
+
+// updateTaskPaperStatus 随任务状态更新论文状态
+// 对比任务的PaperID为0，参与对比的论文各有自己的分析任务，其状态不随对比任务变化
+func updateTaskPaperStatus(tx *gorm.DB, task *model.AnalysisTask, status string, now time.Time) error {
+	if task.Type == model.TaskTypeComparison {
+		return nil
+	}
+	return tx.Model(&model.Paper{}).Where("id = ?", task.PaperID).
+		Updates(map[string]interface{}{"status": status, "updated_at": now}).Error
+}
+
+// isSelectableProvider 用户可选择的模型服务提供方：真实模型服务和当前默认配置
+func (s *AnalysisService) isSelectableProvider(provider string) bool {
+	return provider == aitools.ProviderGemini || provider == aitools.ProviderQwen || provider == s.model.Provider
+}
+
 // SetTaskPublicStatus 设置分析任务公开/私有状态（仅本人可操作）
 func (s *AnalysisService) SetTaskPublicStatus(userID, taskID uint, isPublic bool) error {
-	config.Logger.Info("切换任务公开/私有状态", zap.Uint("user_id", userID), zap.Uint("task_id", taskID), zap.Bool("is_public", isPublic))
-	db := config.DB
+	s.logger.Info("切换任务公开/私有状态", zap.Uint("user_id", userID), zap.Uint("task_id", taskID), zap.Bool("is_public", isPublic))
+	db := s.db
 	var task model.AnalysisTask
 	if err := db.First(&task, taskID).Error; err != nil {
-		config.Logger.Error("任务不存在", zap.Error(err), zap.Uint("task_id", taskID))
+		s.logger.Error("任务不存在", zap.Error(err), zap.Uint("task_id", taskID))
 		return err
 	}
 	if task.UserID != userID {
-		config.Logger.Warn("无权操作", zap.Uint("user_id", userID), zap.Uint("task_id", taskID))
+		s.logger.Warn("无权操作", zap.Uint("user_id", userID), zap.Uint("task_id", taskID))
 		return errors.New("无权操作")
 	}
-	task.IsPublic = isPublic
-	if err := db.Save(&task).Error; err != nil {
-		config.Logger.Error("切换公开状态失败", zap.Error(err), zap.Uint("task_id", taskID))
+	// 只更新公开状态，避免覆盖工作池同时写入的状态、进度和心跳
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&task).UpdateColumn("is_public", isPublic).Error; err != nil {
+			return err
+		}
+		// AI评价跟随任务的公开状态
+		results := tx.Model(&model.AnalysisResult{}).Select("id").Where("task_id = ?", taskID)
+		return tx.Model(&model.PaperEvaluation{}).
+			Where("source = ? AND analysis_id IN (?)", model.EvaluationSourceAI, results).
+			Update("is_public", isPublic).Error
+	})
+	if err != nil {
+		s.logger.Error("切换公开状态失败", zap.Error(err), zap.Uint("task_id", taskID))
 		return err
 	}
-	config.Logger.Info("切换公开状态成功", zap.Uint("task_id", taskID), zap.Bool("is_public", isPublic))
+	s.logger.Info("切换公开状态成功", zap.Uint("task_id", taskID), zap.Bool("is_public", isPublic))
 	return nil
 }
 
 // GetPublicFeed 获取公开分析任务Feed，支持按时间/点赞/建议强度排序
 func (s *AnalysisService) GetPublicFeed(orderBy string) ([]model.AnalysisTask, error) {
-	config.Logger.Info("获取公开Feed", zap.String("order_by", orderBy))
-	db := config.DB
+	s.logger.Info("获取公开Feed", zap.String("order_by", orderBy))
+	db := s.db
 	var tasks []model.AnalysisTask
-	query := db.Where("is_public = ? AND status = ?", true, "已完成")
+	query := db.Where("is_public = ? AND status = ?", true, model.TaskStatusCompleted)
 	switch orderBy {
 	case "like":
 		query = query.Order("like_count desc")
@@ -140,7 +357,7 @@ func (s *AnalysisService) GetPublicFeed(orderBy string) ([]model.AnalysisTask, e
 		query = query.Order("finished_at desc")
 	}
 	if err := query.Find(&tasks).Error; err != nil {
-		config.Logger.Error("查询公开Feed失败", zap.Error(err))
+		s.logger.Error("查询公开Feed失败", zap.Error(err))
 		return nil, err
 	}
 	return tasks, nil
@@ -148,38 +365,35 @@ func (s *AnalysisService) GetPublicFeed(orderBy string) ([]model.AnalysisTask, e
 
 // LikeTask 点赞分析任务（+1，幂等）
 func (s *AnalysisService) LikeTask(taskID uint) error {
-	config.Logger.Info("点赞分析任务", zap.Uint("task_id", taskID))
-	db := config.DB
+	s.logger.Info("点赞分析任务", zap.Uint("task_id", taskID))
+	db := s.db
 	var task model.AnalysisTask
 	if err := db.First(&task, taskID).Error; err != nil {
-		config.Logger.Error("点赞失败，任务不存在", zap.Error(err), zap.Uint("task_id", taskID))
+		s.logger.Error("点赞失败，任务不存在", zap.Error(err), zap.Uint("task_id", taskID))
 		return err
 	}
-	task.LikeCount++
-	if err := db.Save(&task).Error; err != nil {
-		config.Logger.Error("点赞保存失败", zap.Error(err), zap.Uint("task_id", taskID))
+	// 在数据库中原子递增，并发点赞不会丢失，也不会覆盖工作池写入的其他字段
+	if err := db.Model(&task).UpdateColumn("like_count", gorm.Expr("like_count + 1")).Error; err != nil {
+		s.logger.Error("点赞保存失败", zap.Error(err), zap.Uint("task_id", taskID))
 		return err
 	}
-	config.Logger.Info("点赞成功", zap.Uint("task_id", taskID), zap.Int("like_count", task.LikeCount))
+	s.logger.Info("点赞成功", zap.Uint("task_id", taskID))
 	return nil
 }
 
 // UnlikeTask 取消点赞分析任务（-1，幂等，最小为0）
 func (s *AnalysisService) UnlikeTask(taskID uint) error {
-	config.Logger.Info("取消点赞分析任务", zap.Uint("task_id", taskID))
-	db := config.DB
+	s.logger.Info("取消点赞分析任务", zap.Uint("task_id", taskID))
+	db := s.db
 	var task model.AnalysisTask
 	if err := db.First(&task, taskID).Error; err != nil {
-		config.Logger.Error("取消点赞失败，任务不存在", zap.Error(err), zap.Uint("task_id", taskID))
+		s.logger.Error("取消点赞失败，任务不存在", zap.Error(err), zap.Uint("task_id", taskID))
 		return err
 	}
-	if task.LikeCount > 0 {
-		task.LikeCount--
-	}
-	if err := db.Save(&task).Error; err != nil {
-		config.Logger.Error("取消点赞保存失败", zap.Error(err), zap.Uint("task_id", taskID))
+	if err := db.Model(&task).Where("like_count > 0").UpdateColumn("like_count", gorm.Expr("like_count - 1")).Error; err != nil {
+		s.logger.Error("取消点赞保存失败", zap.Error(err), zap.Uint("task_id", taskID))
 		return err
 	}
-	config.Logger.Info("取消点赞成功", zap.Uint("task_id", taskID), zap.Int("like_count", task.LikeCount))
+	s.logger.Info("取消点赞成功", zap.Uint("task_id", taskID))
 	return nil
 }
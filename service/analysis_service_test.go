@@ -0,0 +1,130 @@
+package service
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"papergraph/model"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestCancelAndRetryKeepPaperStatus(t *testing.T) {
+	db := newTestDB(t)
+	user := createTestUser(t, db, "cancel@example.com")
+	papers := []model.Paper{
+		{UserID: user.ID, FileName: "a.pdf", Status: "已完成"},
+		{UserID: user.ID, FileName: "b.pdf", Status: "已完成"},
+	}
+	if err := db.Create(&papers).Error; err != nil {
+		t.Fatal(err)
+	}
+	analysis := &model.AnalysisTask{UserID: user.ID, PaperID: papers[0].ID, Status: model.TaskStatusQueued}
+	comparison := &model.AnalysisTask{UserID: user.ID, Type: model.TaskTypeComparison,
+		PaperIDs: []uint{papers[0].ID, papers[1].ID}, Status: model.TaskStatusQueued}
+	if err := db.Create(analysis).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(comparison).Error; err != nil {
+		t.Fatal(err)
+	}
+	service := NewAnalysisService(db, zap.NewNop(), nil, AnalysisModel{}, SystemClock{})
+
+	paperStatus := func(id uint) string {
+		var paper model.Paper
+		db.First(&paper, id)
+		return paper.Status
+	}
+	if err := service.CancelTask(user.ID, comparison.ID); err != nil {
+		t.Fatalf("取消对比任务失败: %v", err)
+	}
+	if err := service.RetryTask(user.ID, comparison.ID); err != nil {
+		t.Fatalf("重试对比任务失败: %v", err)
+	}
+	for _, paper := range papers {
+		if status := paperStatus(paper.ID); status != "已完成" {
+			t.Errorf("对比任务不应改变论文%d的状态，实际为%s", paper.ID, status)
+		}
+	}
+
+	if err := service.CancelTask(user.ID, analysis.ID); err != nil {
+		t.Fatalf("取消分析任务失败: %v", err)
+	}
+	if status := paperStatus(papers[0].ID); status != "已取消" {
+		t.Errorf("取消分析任务后论文状态为%s，期望已取消", status)
+	}
+}
+
+func TestLikeCountUpdatesAreAtomic(t *testing.T) {
+	db := newTestDB(t)
+	user := createTestUser(t, db, "like@example.com")
+	task := &model.AnalysisTask{UserID: user.ID, Status: model.TaskStatusCompleted}
+	if err := db.Create(task).Error; err != nil {
+		t.Fatal(err)
+	}
+	service := NewAnalysisService(db, zap.NewNop(), nil, AnalysisModel{}, SystemClock{})
+
+	const likes = 20
+	var wg sync.WaitGroup
+	for i := 0; i < likes; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := service.LikeTask(task.ID); err != nil {
+				t.Errorf("点赞失败: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	likeCount := func() int {
+		var saved model.AnalysisTask
+		db.First(&saved, task.ID)
+		return saved.LikeCount
+	}
+	if got := likeCount(); got != likes {
+		t.Fatalf("并发点赞后点赞数应为%d，实际为%d", likes, got)
+	}
+
+	for i := 0; i < likes+1; i++ {
+		if err := service.UnlikeTask(task.ID); err != nil {
+			t.Fatalf("取消点赞失败: %v", err)
+		}
+	}
+	if got := likeCount(); got != 0 {
+		t.Errorf("点赞数最小为0，实际为%d", got)
+	}
+}
+
+func TestSetPublicKeepsWorkerState(t *testing.T) {
+	db := newTestDB(t)
+	user := createTestUser(t, db, "public@example.com")
+	task := &model.AnalysisTask{UserID: user.ID, Status: model.TaskStatusRunning, Stage: model.TaskStageUploading, Progress: 10}
+	if err := db.Create(task).Error; err != nil {
+		t.Fatal(err)
+	}
+	service := NewAnalysisService(db, zap.NewNop(), nil, AnalysisModel{}, SystemClock{})
+
+	// 公开操作执行前工作池已更新了进度和心跳，公开操作不应写回旧值
+	db.Callback().Update().Before("gorm:update").Register("test:worker_progress", func(tx *gorm.DB) {
+		if tx.Statement.Table == "analysis_tasks" {
+			tx.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Exec(
+				"UPDATE analysis_tasks SET stage = ?, progress = ?, heartbeat_at = ? WHERE id = ?",
+				model.TaskStageAnalyzing, 50, time.Now(), task.ID)
+		}
+	})
+	if err := service.SetTaskPublicStatus(user.ID, task.ID, true); err != nil {
+		t.Fatalf("设置公开失败: %v", err)
+	}
+	db.Callback().Update().Remove("test:worker_progress")
+
+	var saved model.AnalysisTask
+	db.First(&saved, task.ID)
+	if !saved.IsPublic {
+		t.Error("任务应已公开")
+	}
+	if saved.Stage != model.TaskStageAnalyzing || saved.Progress != 50 || saved.HeartbeatAt == nil {
+		t.Errorf("公开操作覆盖了工作池写入的状态: stage=%s, progress=%d, heartbeat_at=%v", saved.Stage, saved.Progress, saved.HeartbeatAt)
+	}
+}
@@ -0,0 +1,536 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"papergraph/config"
+	"papergraph/model"
+	"papergraph/utils"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// TaskProgressFunc 任务进度回调，stage为当前阶段，progress为0-100的进度
+type TaskProgressFunc func(stage string, progress int)
+
+// errTaskNotRunning 任务已不处于当前worker的执行中状态（如已被用户取消）
+var errTaskNotRunning = errors.New("任务已不在执行中")
+
+// AnalysisRunner 执行单个分析任务，返回待保存的分析结果
+type AnalysisRunner interface {
+	RunAnalysisTask(ctx context.Context, task *model.AnalysisTask, report TaskProgressFunc) (*model.AnalysisResult, error)
+}
+
+// TaskDispatcher 任务入队、取消时通知分析工作池，由AnalysisWorker实现
+type TaskDispatcher interface {
+	Notify()                 // 有新任务入队，空闲的worker立即尝试领取
+	Cancel(taskID uint) bool // 中断本实例正在执行的任务
+	Publish(event TaskEvent) // 推送任务状态变化
+}
+
+// AnalysisWorker 分析任务后台工作池
+// 以analysis_tasks表作为持久化队列：worker轮询领取排队中的任务，执行期间定期写心跳，
+// 进程重启或实例退出后，心跳超时的任务会被重新排队，因此任务不会丢失
+type AnalysisWorker struct {
+	db       *gorm.DB
+	logger   *zap.Logger
+	runner   AnalysisRunner
+	conf     config.AnalysisWorkerConfig
+	events   *TaskEventBus
+	clock    Clock
+	workerID string
+	wake     chan struct{}
+	wg       sync.WaitGroup
+
+	ctx       context.Context    // 任务执行的上下文，优雅退出超时后取消
+	abort     context.CancelFunc // 中断所有执行中的任务
+	draining  chan struct{}      // 关闭后worker不再领取新任务
+	drainOnce sync.Once
+
+	mu      sync.Mutex
+	running map[uint]context.CancelFunc // 本实例正在执行的任务
+}
+
+// NewAnalysisWorker 创建分析任务工作池，执行任务的runner在Start时提供
+// events用于向SSE等订阅者推送任务状态变化
+func NewAnalysisWorker(db *gorm.DB, logger *zap.Logger, conf config.AnalysisWorkerConfig, events *TaskEventBus, clock Clock) *AnalysisWorker {
+	if conf.Concurrency <= 0 {
+		conf.Concurrency = 1
+	}
+	if conf.MaxAttempts <= 0 {
+		conf.MaxAttempts = 1
+	}
+	if conf.PollInterval <= 0 {
+		conf.PollInterval = 5 * time.Second
+	}
+	if conf.HeartbeatInterval <= 0 {
+		conf.HeartbeatInterval = 15 * time.Second
+	}
+	if conf.StaleAfter <= conf.HeartbeatInterval {
+		conf.StaleAfter = 4 * conf.HeartbeatInterval
+	}
+	hostname, _ := os.Hostname()
+	return &AnalysisWorker{
+		db:       db,
+		logger:   logger,
+		conf:     conf,
+		events:   events,
+		clock:    clock,
+		workerID: fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), utils.GenerateRandomHex(8)),
+		wake:     make(chan struct{}, 1),
+		draining: make(chan struct{}),
+		running:  make(map[uint]context.CancelFunc),
+	}
+}
+
+// Start 启动工作池，由runner执行领取的任务
+// ctx取消后所有worker退出，执行中的任务立即中断并重新排队；需要等待任务完成时使用Shutdown
+// runner依赖论文服务，而论文服务需要通过工作池通知新任务，因此runner在启动时提供
+func (w *AnalysisWorker) Start(ctx context.Context, runner AnalysisRunner) {
+	w.runner = runner
+	w.ctx, w.abort = context.WithCancel(ctx)
+	w.logger.Info("启动分析工作池", zap.String("worker_id", w.workerID), zap.Int("concurrency", w.conf.Concurrency))
+	w.recoverStaleTasks()
+	for i := 0; i < w.conf.Concurrency; i++ {
+		w.wg.Add(1)
+		go w.loop()
+	}
+}
+
+// Shutdown 优雅停止工作池：不再领取新任务，等待执行中的任务完成
+// ctx到期时中断仍在执行的任务并重新排队，由其他实例或下次启动后继续执行，此时返回ctx的错误
+// 停止期间通过接口创建的任务照常写入队列
+func (w *AnalysisWorker) Shutdown(ctx context.Context) error {
+	w.drainOnce.Do(func() { close(w.draining) })
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		w.logger.Info("分析工作池已停止", zap.String("worker_id", w.workerID))
+		return nil
+	case <-ctx.Done():
+	}
+	w.mu.Lock()
+	interrupted := len(w.running)
+	w.mu.Unlock()
+	w.logger.Warn("等待分析任务完成超时，中断并重新排队", zap.Int("tasks", interrupted))
+	if w.abort != nil {
+		w.abort()
+	}
+	<-done
+	return ctx.Err()
+}
+
+// Events 返回任务事件总线
+func (w *AnalysisWorker) Events() *TaskEventBus {
+	return w.events
+}
+
+// Cancel 中断本实例正在执行的任务，任务不在本实例执行时返回false
+// 其他实例上执行的任务会在下次心跳时发现状态变化并自行中断
+func (w *AnalysisWorker) Cancel(taskID uint) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	cancel, ok := w.running[taskID]
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// Wait 等待所有worker退出
+func (w *AnalysisWorker) Wait() {
+	w.wg.Wait()
+}
+
+// Publish 推送任务状态变化
+func (w *AnalysisWorker) Publish(event TaskEvent) {
+	w.events.Publish(event)
+}
+
+// Notify 通知工作池有新任务入队，空闲的worker会立即尝试领取
+func (w *AnalysisWorker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// loop 单个worker的主循环
+func (w *AnalysisWorker) loop() {
+	defer w.wg.Done()
+	ticker := time.NewTicker(w.conf.PollInterval)
+	defer ticker.Stop()
+	for {
+		if w.stopping() {
+			return
+		}
+		task, err := w.claimNext()
+		if err != nil {
+			w.logger.Error("领取分析任务失败", zap.Error(err))
+		}
+		if task != nil {
+			w.execute(w.ctx, task)
+			continue
+		}
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-w.draining:
+			return
+		case <-w.wake:
+		case <-ticker.C:
+			w.recoverStaleTasks()
+		}
+	}
+}
+
+// stopping 工作池是否正在停止，停止后不再领取新任务
+func (w *AnalysisWorker) stopping() bool {
+	select {
+	case <-w.ctx.Done():
+		return true
+	case <-w.draining:
+		return true
+	default:
+		return false
+	}
+}
+
+// claimNext 领取一个可执行的排队任务，没有可领取任务时返回nil
+// 通过带状态条件的UPDATE实现乐观抢占，多实例部署时同一任务只会被一个worker领取
+func (w *AnalysisWorker) claimNext() (*model.AnalysisTask, error) {
+	for {
+		now := w.clock.Now()
+		// 使用Find而非First，避免空队列轮询时输出记录不存在日志
+		var candidates []model.AnalysisTask
+		err := w.db.Where("status = ? AND (next_run_at IS NULL OR next_run_at <= ?)", model.TaskStatusQueued, now).
+			Order("id asc").Limit(1).Find(&candidates).Error
+		if err != nil {
+			return nil, err
+		}
+		if len(candidates) == 0 {
+			return nil, nil
+		}
+		task := candidates[0]
+		res := w.db.Model(&model.AnalysisTask{}).
+			Where("id = ? AND status = ?", task.ID, model.TaskStatusQueued).
+			Updates(map[string]interface{}{
+				"status":       model.TaskStatusRunning,
+				"stage":        model.TaskStageStarting,
+				"progress":     0,
+				"attempts":     gorm.Expr("attempts + 1"),
+				"worker_id":    w.workerID,
+				"started_at":   now,
+				"heartbeat_at": now,
+			})
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected == 0 {
+			// 已被其他worker抢先领取，继续尝试下一个
+			continue
+		}
+		if err := w.db.First(&task, task.ID).Error; err != nil {
+			return nil, err
+		}
+		w.events.Publish(TaskEventFromTask(&task, w.clock))
+		return &task, nil
+	}
+}
+
+// execute 执行已领取的任务并根据结果更新任务状态
+func (w *AnalysisWorker) execute(ctx context.Context, task *model.AnalysisTask) {
+	w.logger.Info("开始执行分析任务", zap.Uint("task_id", task.ID), zap.Int("attempt", task.Attempts))
+	runCtx := ctx
+	var cancel context.CancelFunc
+	if w.conf.TaskTimeout > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, w.conf.TaskTimeout)
+	} else {
+		runCtx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+	w.mu.Lock()
+	w.running[task.ID] = cancel
+	w.mu.Unlock()
+	defer func() {
+		w.mu.Lock()
+		delete(w.running, task.ID)
+		w.mu.Unlock()
+	}()
+
+	stopHeartbeat := w.startHeartbeat(runCtx, task.ID, cancel)
+	result, err := w.runner.RunAnalysisTask(runCtx, task, func(stage string, progress int) {
+		w.reportProgress(task.ID, stage, progress)
+	})
+	stopHeartbeat()
+
+	if err == nil {
+		err = w.complete(task, result)
+		if err == nil {
+			w.logger.Info("分析任务完成", zap.Uint("task_id", task.ID))
+			w.events.Publish(TaskEvent{TaskID: task.ID, Status: model.TaskStatusCompleted, Stage: model.TaskStageCompleted, Progress: 100})
+			return
+		}
+	}
+	if errors.Is(err, errTaskNotRunning) || w.isCanceled(task.ID) {
+		// 用户取消后结果不再保存，任务状态已由取消操作更新
+		w.logger.Info("分析任务已取消", zap.Uint("task_id", task.ID), zap.Error(err))
+		return
+	}
+	if ctx.Err() != nil {
+		// 工作池退出，任务立即重新排队；重新排队失败时由心跳超时机制恢复
+		w.logger.Warn("工作池退出，分析任务中断", zap.Uint("task_id", task.ID), zap.Error(err))
+		w.requeueInterrupted(task)
+		return
+	}
+	w.fail(task, err)
+}
+
+// requeueInterrupted 将因工作池退出而中断的任务重新排队，本次执行不计入执行次数
+func (w *AnalysisWorker) requeueInterrupted(task *model.AnalysisTask) {
+	res := w.db.Model(&model.AnalysisTask{}).
+		Where("id = ? AND worker_id = ? AND status = ?", task.ID, w.workerID, model.TaskStatusRunning).
+		Updates(map[string]interface{}{
+			"status":      model.TaskStatusQueued,
+			"stage":       model.TaskStageQueued,
+			"progress":    0,
+			"attempts":    gorm.Expr("attempts - 1"),
+			"worker_id":   "",
+			"next_run_at": nil,
+		})
+	if res.Error != nil {
+		w.logger.Error("中断的任务重新排队失败", zap.Error(res.Error), zap.Uint("task_id", task.ID))
+		return
+	}
+	if res.RowsAffected > 0 {
+		w.logger.Info("中断的任务已重新排队", zap.Uint("task_id", task.ID))
+		w.events.Publish(TaskEvent{TaskID: task.ID, Status: model.TaskStatusQueued, Stage: model.TaskStageQueued})
+	}
+}
+
+// isCanceled 任务是否已被取消
+func (w *AnalysisWorker) isCanceled(taskID uint) bool {
+	var task model.AnalysisTask
+	if err := w.db.Select("status").First(&task, taskID).Error; err != nil {
+		return false
+	}
+	return task.Status == model.TaskStatusCanceled
+}
+
+// startHeartbeat 定期刷新任务心跳，返回停止函数
+// 心跳发现任务已不在执行中（被取消或被其他实例接管）时调用cancel中断执行
+func (w *AnalysisWorker) startHeartbeat(ctx context.Context, taskID uint, cancel context.CancelFunc) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(w.conf.HeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				res := w.db.Model(&model.AnalysisTask{}).
+					Where("id = ? AND worker_id = ? AND status = ?", taskID, w.workerID, model.TaskStatusRunning).
+					Update("heartbeat_at", w.clock.Now())
+				if res.Error != nil {
+					w.logger.Warn("更新任务心跳失败", zap.Error(res.Error), zap.Uint("task_id", taskID))
+					continue
+				}
+				if res.RowsAffected == 0 {
+					w.logger.Info("任务已不在执行中，中断执行", zap.Uint("task_id", taskID))
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// reportProgress 更新任务阶段和进度
+func (w *AnalysisWorker) reportProgress(taskID uint, stage string, progress int) {
+	err := w.db.Model(&model.AnalysisTask{}).
+		Where("id = ? AND worker_id = ?", taskID, w.workerID).
+		Updates(map[string]interface{}{"stage": stage, "progress": progress}).Error
+	if err != nil {
+		w.logger.Warn("更新任务进度失败", zap.Error(err), zap.Uint("task_id", taskID))
+	}
+	w.events.Publish(TaskEvent{TaskID: taskID, Status: model.TaskStatusRunning, Stage: stage, Progress: progress})
+}
+
+// complete 在同一事务内保存分析结果并将任务标记为已完成
+// 同一任务的历史结果保留，新结果版本号递增
+func (w *AnalysisWorker) complete(task *model.AnalysisTask, result *model.AnalysisResult) error {
+	if result == nil {
+		return errors.New("分析结果为空")
+	}
+	now := w.clock.Now()
+	return w.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.AnalysisTask{}).
+			Where("id = ? AND worker_id = ? AND status = ?", task.ID, w.workerID, model.TaskStatusRunning).
+			Updates(map[string]interface{}{
+				"status":      model.TaskStatusCompleted,
+				"stage":       model.TaskStageCompleted,
+				"progress":    100,
+				"last_error":  "",
+				"finished_at": now,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errTaskNotRunning
+		}
+
+		var latest int
+		if err := tx.Model(&model.AnalysisResult{}).Where("task_id = ?", task.ID).
+			Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+			return err
+		}
+		result.TaskID = task.ID
+		result.Version = latest + 1
+		if result.CreatedAt.IsZero() {
+			result.CreatedAt = now
+		}
+		if err := tx.Create(result).Error; err != nil {
+			return err
+		}
+		if _, err := NewEvaluationService(tx).CreateAIEvaluation(task, result); err != nil {
+			return err
+		}
+		return updateTaskPaperStatus(tx, task, "已完成", now)
+	})
+}
+
+// fail 记录失败原因，未超过最大次数时退避后重新排队，否则标记为失败
+func (w *AnalysisWorker) fail(task *model.AnalysisTask, cause error) {
+	now := w.clock.Now()
+	updates := map[string]interface{}{"last_error": cause.Error()}
+	if task.Attempts < w.conf.MaxAttempts {
+		next := now.Add(time.Duration(task.Attempts) * w.conf.RetryBackoff)
+		updates["status"] = model.TaskStatusQueued
+		updates["stage"] = model.TaskStageQueued
+		updates["progress"] = 0
+		updates["next_run_at"] = next
+		w.logger.Warn("分析任务失败，稍后重试", zap.Error(cause), zap.Uint("task_id", task.ID),
+			zap.Int("attempt", task.Attempts), zap.Time("next_run_at", next))
+	} else {
+		updates["status"] = model.TaskStatusFailed
+		updates["stage"] = model.TaskStageFailed
+		updates["finished_at"] = now
+		w.logger.Error("分析任务失败，重试次数已用完", zap.Error(cause), zap.Uint("task_id", task.ID),
+			zap.Int("attempt", task.Attempts))
+	}
+	err := w.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.AnalysisTask{}).
+			Where("id = ? AND worker_id = ? AND status = ?", task.ID, w.workerID, model.TaskStatusRunning).
+			Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errTaskNotRunning
+		}
+		if updates["status"] != model.TaskStatusFailed {
+			return nil
+		}
+		return updateTaskPaperStatus(tx, task, "失败", now)
+	})
+	if errors.Is(err, errTaskNotRunning) {
+		w.logger.Info("任务已不在执行中，忽略失败结果", zap.Uint("task_id", task.ID))
+		return
+	}
+	if err != nil {
+		w.logger.Error("更新失败任务状态失败", zap.Error(err), zap.Uint("task_id", task.ID))
+		return
+	}
+	w.events.Publish(TaskEvent{
+		TaskID: task.ID,
+		Status: updates["status"].(string),
+		Stage:  updates["stage"].(string),
+		Error:  cause.Error(),
+	})
+}
+
+// recoverStaleTasks 将心跳超时的执行中任务重新排队
+// 覆盖进程崩溃、重启以及旧版本遗留的进行中任务
+func (w *AnalysisWorker) recoverStaleTasks() {
+	cutoff := w.clock.Now().Add(-w.conf.StaleAfter)
+	var tasks []model.AnalysisTask
+	err := w.db.Where("status = ? AND (heartbeat_at IS NULL OR heartbeat_at < ?)", model.TaskStatusRunning, cutoff).
+		Find(&tasks).Error
+	if err != nil {
+		w.logger.Error("查询超时任务失败", zap.Error(err))
+		return
+	}
+	for i := range tasks {
+		task := &tasks[i]
+		updates := map[string]interface{}{
+			"status":    model.TaskStatusQueued,
+			"stage":     model.TaskStageQueued,
+			"progress":  0,
+			"worker_id": "",
+		}
+		if task.Attempts >= w.conf.MaxAttempts {
+			// 反复中断的任务不再重试，避免异常任务拖垮实例
+			updates["status"] = model.TaskStatusFailed
+			updates["stage"] = model.TaskStageFailed
+			updates["last_error"] = "执行实例心跳超时"
+			updates["finished_at"] = w.clock.Now()
+		}
+		res := w.db.Model(&model.AnalysisTask{}).
+			Where("id = ? AND status = ? AND (heartbeat_at IS NULL OR heartbeat_at < ?)", task.ID, model.TaskStatusRunning, cutoff).
+			Updates(updates)
+		if res.Error != nil {
+			w.logger.Error("重新排队超时任务失败", zap.Error(res.Error), zap.Uint("task_id", task.ID))
+			continue
+		}
+		if res.RowsAffected > 0 {
+			w.logger.Warn("执行实例心跳超时，任务状态已恢复", zap.Uint("task_id", task.ID),
+				zap.String("worker_id", task.WorkerID), zap.Any("status", updates["status"]))
+			w.events.Publish(TaskEvent{TaskID: task.ID, Status: updates["status"].(string), Stage: updates["stage"].(string)})
+		}
+	}
+	if len(tasks) > 0 {
+		w.Notify()
+	}
+}
+
+// notifyTasks 唤醒分析工作池；tasks为nil时任务仍会在下次轮询时被领取
+func notifyTasks(tasks TaskDispatcher) {
+	if tasks != nil {
+		tasks.Notify()
+	}
+}
+
+// cancelRunningTask 中断本进程正在执行的任务并推送取消事件
+func cancelRunningTask(tasks TaskDispatcher, taskID uint) {
+	if tasks == nil {
+		return
+	}
+	tasks.Cancel(taskID)
+	tasks.Publish(TaskEvent{TaskID: taskID, Status: model.TaskStatusCanceled, Stage: model.TaskStageCanceled})
+}
+
+// publishTaskQueued 推送任务重新入队事件
+func publishTaskQueued(tasks TaskDispatcher, taskID uint) {
+	if tasks != nil {
+		tasks.Publish(TaskEvent{TaskID: taskID, Status: model.TaskStatusQueued, Stage: model.TaskStageQueued})
+	}
+}
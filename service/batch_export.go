@@ -0,0 +1,149 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"papergraph/model"
+)
+
+// 批次导出格式
+const (
+	BatchExportJSON     = "json"
+	BatchExportMarkdown = "markdown"
+)
+
+// BatchExport 批次合并导出内容
+type BatchExport struct {
+	Batch      *model.UploadBatch `json:"batch"`
+	ExportedAt time.Time          `json:"exported_at"`
+	Papers     []BatchExportPaper `json:"papers"`
+}
+
+// BatchExportPaper 批次中单篇论文的导出内容
+type BatchExportPaper struct {
+	FileName     string                `json:"file_name"`
+	PaperID      uint                  `json:"paper_id"`
+	TaskID       uint                  `json:"task_id"`
+	Status       string                `json:"status"`
+	Error        string                `json:"error,omitempty"`
+	SuggestScore int                   `json:"suggest_score"`
+	Result       *model.AnalysisResult `json:"result,omitempty"` // 最新版本的分析结果
+}
+
+// ExportBatch 导出批次中全部论文的最新分析结果，批次中所有任务结束后才能导出
+func (s *BatchService) ExportBatch(userID, batchID uint) (*BatchExport, error) {
+	progress, err := s.GetBatch(userID, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if !progress.Finished {
+		return nil, ErrBatchNotFinished
+	}
+
+	db := s.db
+	var taskIDs []uint
+	for _, item := range progress.Items {
+		if item.TaskID != 0 {
+			taskIDs = append(taskIDs, item.TaskID)
+		}
+	}
+	tasks := make(map[uint]model.AnalysisTask)
+	results := make(map[uint]*model.AnalysisResult)
+	if len(taskIDs) > 0 {
+		var list []model.AnalysisTask
+		if err := db.Where("id IN ?", taskIDs).Find(&list).Error; err != nil {
+			return nil, err
+		}
+		for _, task := range list {
+			tasks[task.ID] = task
+		}
+		var all []model.AnalysisResult
+		if err := db.Where("task_id IN ?", taskIDs).Order("version desc, id desc").Find(&all).Error; err != nil {
+			return nil, err
+		}
+		for i := range all {
+			if _, ok := results[all[i].TaskID]; !ok {
+				results[all[i].TaskID] = &all[i]
+			}
+		}
+	}
+
+	export := &BatchExport{Batch: progress.Batch, ExportedAt: s.clock.Now()}
+	for _, item := range progress.Items {
+		paper := BatchExportPaper{
+			FileName: item.FileName,
+			PaperID:  item.PaperID,
+			TaskID:   item.TaskID,
+			Status:   item.Status,
+			Error:    item.Error,
+			Result:   results[item.TaskID],
+		}
+		if task, ok := tasks[item.TaskID]; ok {
+			paper.SuggestScore = task.SuggestScore
+			if paper.Error == "" && task.Status == model.TaskStatusFailed {
+				paper.Error = task.LastError
+			}
+		}
+		export.Papers = append(export.Papers, paper)
+	}
+	return export, nil
+}
+
+// RenderMarkdown 将导出内容渲染为Markdown阅读清单
+func (e *BatchExport) RenderMarkdown() string {
+	var b strings.Builder
+	name := e.Batch.Name
+	if name == "" {
+		name = fmt.Sprintf("批次%d", e.Batch.ID)
+	}
+	fmt.Fprintf(&b, "# %s\n\n", name)
+	fmt.Fprintf(&b, "共%d个文件，导出时间：%s\n", len(e.Papers), e.ExportedAt.Format("2006-01-02 15:04"))
+
+	for i, p := range e.Papers {
+		result := p.Result
+		title := p.FileName
+		if result != nil && result.Analysis != nil && result.Analysis.BasicInfo.Title != "" {
+			title = result.Analysis.BasicInfo.Title
+		}
+		fmt.Fprintf(&b, "\n## %d. %s\n\n", i+1, title)
+		if result == nil || result.Analysis == nil {
+			status := p.Status
+			if status == "" {
+				status = "未创建任务"
+			}
+			fmt.Fprintf(&b, "- 文件：%s\n- 状态：%s\n", p.FileName, status)
+			if p.Error != "" {
+				fmt.Fprintf(&b, "- 原因：%s\n", p.Error)
+			}
+			continue
+		}
+
+		a := result.Analysis
+		fmt.Fprintf(&b, "- 文件：%s\n", p.FileName)
+		if len(a.BasicInfo.Authors) > 0 {
+			fmt.Fprintf(&b, "- 作者：%s\n", strings.Join(a.BasicInfo.Authors, ", "))
+		}
+		for _, f := range []struct{ label, value string }{
+			{"发表时间", a.BasicInfo.PublicationDate},
+			{"研究领域", a.BasicInfo.ResearchField},
+		} {
+			if f.value != "" {
+				fmt.Fprintf(&b, "- %s：%s\n", f.label, f.value)
+			}
+		}
+		fmt.Fprintf(&b, "- 阅读原文建议强度：%d/10\n", p.SuggestScore)
+		for _, f := range []struct{ label, value string }{
+			{"研究目的", a.Summary.Purpose},
+			{"研究方法", a.Summary.Methods},
+			{"主要发现", a.Summary.KeyFindings},
+			{"结论", a.Summary.Conclusion},
+		} {
+			if text := strings.TrimSpace(f.value); text != "" {
+				fmt.Fprintf(&b, "\n**%s**：%s\n", f.label, text)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"papergraph/config"
+	"papergraph/model"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 批量上传错误
+var (
+	ErrBatchNotFound    = errors.New("批次不存在")
+	ErrBatchNotFinished = errors.New("批次中还有未完成的任务")
+)
+
+// BatchService 批量上传相关业务逻辑
+type BatchService struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	papers *PaperService // 逐个文件按上传流程创建论文和分析任务
+	conf   config.BatchConfig
+	clock  Clock
+}
+
+// NewBatchService 创建BatchService实例
+func NewBatchService(db *gorm.DB, logger *zap.Logger, papers *PaperService, conf config.BatchConfig, clock Clock) *BatchService {
+	return &BatchService{db: db, logger: logger, papers: papers, conf: conf, clock: clock}
+}
+
+// BatchFile 批量上传中的一个文件，Err不为空时表示该文件校验失败
+type BatchFile struct {
+	Name   string         // 文件名，zip中的文件为zip内的路径
+	Upload *SpooledUpload // 校验通过的文件
+	Err    error
+}
+
+// CloseBatchFiles 删除批量上传文件的临时文件
+func CloseBatchFiles(files []BatchFile) {
+	for _, f := range files {
+		f.Upload.Close()
+	}
+}
+
+// ExpandUpload 展开上传的文件：zip解压出其中的文件，其他文件直接校验
+// zip中的目录、macOS元数据和隐藏文件会被忽略；每个文件流式写入临时文件，超过PDF大小上限时立即停止，防止压缩炸弹；
+// 返回的文件使用完后需调用CloseBatchFiles
+func (s *BatchService) ExpandUpload(name string, r io.ReaderAt, size int64) ([]BatchFile, error) {
+	if !strings.EqualFold(path.Ext(name), ".zip") {
+		upload, err := SpoolUpload(path.Base(name), io.NewSectionReader(r, 0, size))
+		return []BatchFile{{Name: name, Upload: upload, Err: err}}, nil
+	}
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%s不是有效的zip文件", name)
+	}
+	var files []BatchFile
+	for _, f := range reader.File {
+		base := path.Base(f.Name)
+		if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") || strings.HasPrefix(base, ".") {
+			continue
+		}
+		if len(files) >= s.conf.MaxFiles {
+			CloseBatchFiles(files)
+			return nil, fmt.Errorf("单个批次最多包含%d个文件", s.conf.MaxFiles)
+		}
+		file := BatchFile{Name: f.Name}
+		switch {
+		case !strings.EqualFold(path.Ext(base), ".pdf"):
+			file.Err = errors.New("不是PDF文件")
+		case f.UncompressedSize64 > MaxPDFSize:
+			file.Err = ErrFileTooLarge
+		default:
+			file.Upload, file.Err = spoolZipEntry(f)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// spoolZipEntry 解压zip条目到临时文件并校验
+func spoolZipEntry(f *zip.File) (*SpooledUpload, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, errors.New("解压失败")
+	}
+	defer rc.Close()
+	return SpoolUpload(path.Base(f.Name), rc)
+}
+
+// BatchItemProgress 批次中单个文件的任务进度
+type BatchItemProgress struct {
+	model.UploadBatchItem
+	Status   string `json:"status"`   // 任务状态，校验失败的文件为空
+	Stage    string `json:"stage"`    // 任务执行阶段
+	Progress int    `json:"progress"` // 任务进度百分比
+}
+
+// BatchProgress 批次整体进度
+type BatchProgress struct {
+	Batch     *model.UploadBatch  `json:"batch"`
+	Items     []BatchItemProgress `json:"items"`
+	Queued    int                 `json:"queued"`    // 排队中的任务数
+	Running   int                 `json:"running"`   // 进行中的任务数
+	Completed int                 `json:"completed"` // 已完成的任务数
+	Failed    int                 `json:"failed"`    // 失败的任务数
+	Canceled  int                 `json:"canceled"`  // 已取消的任务数
+	Progress  int                 `json:"progress"`  // 整体进度百分比，按任务平均
+	Finished  bool                `json:"finished"`  // 全部任务是否已结束
+}
+
+// CreateBatch 批量上传论文，逐个文件创建论文和分析任务
+// 文件已在ExpandUpload中校验；并按有效文件数一次性预扣分析额度，额度不足时整个批次不创建；
+// 只有新加入分析队列的任务扣除额度，创建任务失败、复用已有任务或缓存结果的文件退还对应额度
+func (s *BatchService) CreateBatch(userID uint, name string, files []BatchFile, forceFresh bool) (*BatchProgress, error) {
+	s.logger.Info("开始批量上传", zap.Uint("user_id", userID), zap.String("name", name), zap.Int("files", len(files)))
+	if len(files) == 0 {
+		return nil, errors.New("请上传PDF文件或zip压缩包")
+	}
+	if len(files) > s.conf.MaxFiles {
+		return nil, fmt.Errorf("单个批次最多包含%d个文件", s.conf.MaxFiles)
+	}
+
+	// 批次内内容相同的文件只保留第一个
+	valid := 0
+	seen := make(map[string]string)
+	for i := range files {
+		f := &files[i]
+		if f.Err != nil {
+			continue
+		}
+		if first, ok := seen[f.Upload.Hash]; ok {
+			f.Err = fmt.Errorf("与%s内容相同", first)
+			continue
+		}
+		seen[f.Upload.Hash] = f.Name
+		valid++
+	}
+	if valid == 0 {
+		return nil, errors.New("没有有效的PDF文件")
+	}
+
+	db := s.db
+	subscriptions := NewSubscriptionService(db)
+	charged, err := subscriptions.ReserveAnalysisQuota(userID, valid)
+	if err != nil {
+		s.logger.Warn("批量上传额度不足", zap.Error(err), zap.Uint("user_id", userID), zap.Int("files", valid))
+		return nil, err
+	}
+
+	batch := &model.UploadBatch{UserID: userID, Name: name, TotalFiles: len(files), ChargedTrials: charged, CreatedAt: s.clock.Now()}
+	if err := db.Create(batch).Error; err != nil {
+		s.logger.Error("创建批次失败", zap.Error(err))
+		if refundErr := subscriptions.RefundFreeTrial(userID, charged); refundErr != nil {
+			s.logger.Error("退还免费试用次数失败", zap.Error(refundErr), zap.Uint("user_id", userID))
+		}
+		return nil, err
+	}
+
+	queued := 0
+	items := make([]model.UploadBatchItem, 0, len(files))
+	for _, f := range files {
+		item := model.UploadBatchItem{BatchID: batch.ID, FileName: truncateRunes(f.Name, 256), CreatedAt: s.clock.Now()}
+		if f.Err == nil {
+			paper, task, isNew, err := s.papers.uploadAndQueue(userID, f.Upload, forceFresh)
+			switch {
+			case err != nil:
+				f.Err = err
+			case task == nil:
+				f.Err = errors.New("创建分析任务失败")
+			default:
+				item.PaperID, item.TaskID = paper.ID, task.ID
+				batch.AcceptedFiles++
+				if isNew {
+					queued++
+				}
+			}
+		}
+		if f.Err != nil {
+			item.Error = truncateRunes(f.Err.Error(), 512)
+		}
+		items = append(items, item)
+	}
+	// 退还未新建分析任务的文件预扣的额度；已排队的任务会照常分析，之后保存批次记录失败也不退还
+	if refund := charged - queued; refund > 0 {
+		if err := subscriptions.RefundFreeTrial(userID, refund); err != nil {
+			s.logger.Error("退还免费试用次数失败", zap.Error(err), zap.Uint("user_id", userID))
+		} else {
+			batch.ChargedTrials -= refund
+		}
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&items).Error; err != nil {
+			return err
+		}
+		return tx.Model(batch).Updates(map[string]interface{}{
+			"accepted_files": batch.AcceptedFiles,
+			"charged_trials": batch.ChargedTrials,
+		}).Error
+	})
+	if err != nil {
+		s.logger.Error("保存批次文件失败", zap.Error(err), zap.Uint("batch_id", batch.ID))
+		return nil, err
+	}
+	s.logger.Info("批量上传完成", zap.Uint("batch_id", batch.ID), zap.Int("total", batch.TotalFiles), zap.Int("accepted", batch.AcceptedFiles))
+	return s.GetBatch(userID, batch.ID)
+}
+
+// ListBatches 获取用户的批量上传记录，最近的在前
+func (s *BatchService) ListBatches(userID uint) ([]model.UploadBatch, error) {
+	var batches []model.UploadBatch
+	err := s.db.Where("user_id = ?", userID).Order("id desc").Find(&batches).Error
+	return batches, err
+}
+
+// GetBatch 获取批次及各文件任务的进度（仅本人可查看）
+func (s *BatchService) GetBatch(userID, batchID uint) (*BatchProgress, error) {
+	db := s.db
+	var batch model.UploadBatch
+	if err := db.Where("id = ? AND user_id = ?", batchID, userID).Limit(1).Find(&batch).Error; err != nil {
+		return nil, err
+	}
+	if batch.ID == 0 {
+		return nil, ErrBatchNotFound
+	}
+	var items []model.UploadBatchItem
+	if err := db.Where("batch_id = ?", batch.ID).Order("id asc").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	var taskIDs []uint
+	for _, item := range items {
+		if item.TaskID != 0 {
+			taskIDs = append(taskIDs, item.TaskID)
+		}
+	}
+	tasks := make(map[uint]model.AnalysisTask)
+	if len(taskIDs) > 0 {
+		var list []model.AnalysisTask
+		if err := db.Select("id", "status", "stage", "progress").Where("id IN ?", taskIDs).Find(&list).Error; err != nil {
+			return nil, err
+		}
+		for _, task := range list {
+			tasks[task.ID] = task
+		}
+	}
+
+	progress := &BatchProgress{Batch: &batch, Items: make([]BatchItemProgress, 0, len(items))}
+	total, sum := 0, 0
+	for _, item := range items {
+		p := BatchItemProgress{UploadBatchItem: item}
+		if task, ok := tasks[item.TaskID]; ok {
+			p.Status, p.Stage, p.Progress = task.Status, task.Stage, task.Progress
+			total++
+			switch task.Status {
+			case model.TaskStatusQueued:
+				progress.Queued++
+			case model.TaskStatusRunning:
+				progress.Running++
+			case model.TaskStatusCompleted:
+				progress.Completed++
+			case model.TaskStatusFailed:
+				progress.Failed++
+			case model.TaskStatusCanceled:
+				progress.Canceled++
+			}
+			if isFinishedStatus(task.Status) {
+				sum += 100
+			} else {
+				sum += task.Progress
+			}
+		}
+		progress.Items = append(progress.Items, p)
+	}
+	if total > 0 {
+		progress.Progress = sum / total
+	}
+	progress.Finished = progress.Completed+progress.Failed+progress.Canceled == total
+	return progress, nil
+}
+
+// isFinishedStatus 任务是否已结束
+func isFinishedStatus(status string) bool {
+	return slices.Contains([]string{model.TaskStatusCompleted, model.TaskStatusFailed, model.TaskStatusCanceled}, status)
+}
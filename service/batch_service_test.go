@@ -0,0 +1,39 @@
+package service
+
+import (
+	"testing"
+
+	"papergraph/config"
+	"papergraph/model"
+
+	"go.uber.org/zap"
+)
+
+func TestCreateBatchChargesOnlyNewTasks(t *testing.T) {
+	db := newTestDB(t)
+	user := createTestUser(t, db, "batch@example.com")
+	var before model.User
+	db.First(&before, user.ID)
+	papers := newTestPaperService(t, db, nil)
+	batches := NewBatchService(db, zap.NewNop(), papers, config.Default(config.ProfileTest).Batch, SystemClock{})
+
+	if _, _, err := papers.UploadAndCreateTask(user.ID, spoolTestPDF(t, "a.pdf", "paper a"), false); err != nil {
+		t.Fatal(err)
+	}
+	files := []BatchFile{
+		{Name: "a.pdf", Upload: spoolTestPDF(t, "a.pdf", "paper a")}, // 复用已有任务
+		{Name: "b.pdf", Upload: spoolTestPDF(t, "b.pdf", "paper b")},
+	}
+	progress, err := batches.CreateBatch(user.ID, "batch", files, false)
+	if err != nil {
+		t.Fatalf("批量上传失败: %v", err)
+	}
+	if progress.Batch.AcceptedFiles != 2 || progress.Batch.ChargedTrials != 1 {
+		t.Errorf("accepted=%d charged=%d，期望2个文件、只扣1次", progress.Batch.AcceptedFiles, progress.Batch.ChargedTrials)
+	}
+	var remaining model.User
+	db.First(&remaining, user.ID)
+	if remaining.FreeTrialCount != before.FreeTrialCount-1 {
+		t.Errorf("剩余免费次数%d，期望%d", remaining.FreeTrialCount, before.FreeTrialCount-1)
+	}
+}
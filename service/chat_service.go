@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"papergraph/aitools"
+	"papergraph/config"
+	"papergraph/metrics"
+	"papergraph/model"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 论文问答错误
+var (
+	ErrChatQuotaExceeded = errors.New("今日提问次数已用完")
+	ErrChatForbidden     = errors.New("无权访问该论文")
+	ErrChatNotReady      = errors.New("论文尚未完成分析，暂时无法问答")
+)
+
+// chatThreadTitleRunes 会话标题最大字符数
+const chatThreadTitleRunes = 40
+
+// ChatService 论文问答业务逻辑
+type ChatService struct {
+	db       *gorm.DB
+	logger   *zap.Logger
+	provider aitools.LLMProvider
+	papers   *PaperService // 读取论文文件和提取的文本
+	conf     config.ChatConfig
+	clock    Clock
+}
+
+// NewChatService 创建论文问答服务，provider为回答问题使用的模型服务
+func NewChatService(db *gorm.DB, logger *zap.Logger, provider aitools.LLMProvider, papers *PaperService, conf config.ChatConfig, clock Clock) *ChatService {
+	return &ChatService{db: db, logger: logger, provider: provider, papers: papers, conf: conf, clock: clock}
+}
+
+// ChatRequest 提问请求
+type ChatRequest struct {
+	UserID   uint
+	PaperID  uint
+	ThreadID uint // 为0时新建会话
+	Question string
+}
+
+// ChatReply 一轮问答结果
+type ChatReply struct {
+	Thread   *model.ChatThread  `json:"thread"`
+	Question *model.ChatMessage `json:"question"`
+	Answer   *model.ChatMessage `json:"answer"`
+}
+
+// ChatQuota 用户当日提问配额
+type ChatQuota struct {
+	Plan  string `json:"plan"` // free/subscriber
+	Limit int    `json:"limit"`
+	Used  int    `json:"used"`
+}
+
+// Ask 针对论文提问，回答以论文提取文本和分析报告为依据
+// onDelta不为空时流式回调回答内容；问题和回答在回答完成后一起保存
+func (s *ChatService) Ask(ctx context.Context, req ChatRequest, onDelta aitools.ChatDeltaFunc) (*ChatReply, error) {
+	question := strings.TrimSpace(req.Question)
+	if question == "" {
+		return nil, errors.New("问题不能为空")
+	}
+	if utf8.RuneCountInString(question) > s.conf.MaxQuestionRunes {
+		return nil, fmt.Errorf("问题不能超过%d个字符", s.conf.MaxQuestionRunes)
+	}
+	paper, err := s.accessiblePaper(req.UserID, req.PaperID)
+	if err != nil {
+		return nil, err
+	}
+	day, err := s.reserveQuota(req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	// 回答或保存失败时退还预占的额度
+	answered := false
+	defer func() {
+		if !answered {
+			s.releaseQuota(req.UserID, day)
+		}
+	}()
+
+	db := s.db
+	thread := &model.ChatThread{UserID: req.UserID, PaperID: paper.ID, Title: truncateRunes(question, chatThreadTitleRunes)}
+	var history []aitools.ChatMessage
+	if req.ThreadID != 0 {
+		if err := db.Where("id = ? AND user_id = ? AND paper_id = ?", req.ThreadID, req.UserID, paper.ID).First(thread).Error; err != nil {
+			return nil, errors.New("会话不存在")
+		}
+		if history, err = s.recentHistory(thread.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	source, err := s.chatSource(ctx, req.UserID, paper)
+	if err != nil {
+		return nil, err
+	}
+	contextTokens := aitools.DefaultContextTokens
+	if p, ok := s.provider.(aitools.ContextWindowProvider); ok && p.ContextTokens() > 0 {
+		contextTokens = p.ContextTokens()
+	}
+	messages := aitools.BuildPaperChatMessages(source, history, question, contextTokens)
+
+	s.logger.Info("论文问答", zap.Uint("user_id", req.UserID), zap.Uint("paper_id", paper.ID),
+		zap.Uint("thread_id", thread.ID), zap.Int("history", len(history)))
+	answer, usage, err := aitools.StreamChat(ctx, s.provider, messages, onDelta)
+	if err != nil {
+		s.logger.Error("论文问答失败", zap.Error(err), zap.Uint("paper_id", paper.ID))
+		return nil, fmt.Errorf("%s回答失败: %w", s.provider.Name(), err)
+	}
+	if !usage.Reported() {
+		// 提供方未报告用量时按字符数估算
+		for _, m := range messages {
+			usage.InputTokens += aitools.EstimateTokens(m.Content)
+		}
+		usage.OutputTokens = aitools.EstimateTokens(answer)
+	}
+	metrics.AddLLMTokens(s.provider.Name(), usage.InputTokens, usage.OutputTokens)
+
+	now := s.clock.Now()
+	reply := &ChatReply{
+		Thread:   thread,
+		Question: &model.ChatMessage{UserID: req.UserID, Role: model.ChatRoleUser, Content: question, CreatedAt: now},
+		Answer: &model.ChatMessage{
+			UserID:    req.UserID,
+			Role:      model.ChatRoleAssistant,
+			Content:   answer,
+			Citations: aitools.ParseCitations(answer, len(source.Pages)),
+			Provider:  s.provider.Name(),
+			Model:     s.provider.ModelName(),
+			CreatedAt: now,
+		},
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if thread.ID == 0 {
+			if err := tx.Create(thread).Error; err != nil {
+				return err
+			}
+		}
+		reply.Question.ThreadID = thread.ID
+		reply.Answer.ThreadID = thread.ID
+		if err := tx.Create(reply.Question).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(reply.Answer).Error; err != nil {
+			return err
+		}
+		thread.MessageCount += 2
+		thread.UpdatedAt = now
+		return tx.Model(thread).Updates(map[string]interface{}{
+			"message_count": gorm.Expr("message_count + ?", 2),
+			"updated_at":    now,
+		}).Error
+	})
+	if err != nil {
+		s.logger.Error("保存问答记录失败", zap.Error(err), zap.Uint("paper_id", paper.ID))
+		return nil, err
+	}
+	answered = true
+	return reply, nil
+}
+
+// ListThreads 获取用户在某篇论文下的问答会话，最近活跃的在前
+func (s *ChatService) ListThreads(userID, paperID uint) ([]model.ChatThread, error) {
+	var threads []model.ChatThread
+	err := s.db.Where("user_id = ? AND paper_id = ?", userID, paperID).Order("updated_at desc").Find(&threads).Error
+	return threads, err
+}
+
+// ListMessages 获取会话的全部消息（仅本人可查看），按时间顺序
+func (s *ChatService) ListMessages(userID, threadID uint) ([]model.ChatMessage, error) {
+	var thread model.ChatThread
+	if err := s.db.Where("id = ? AND user_id = ?", threadID, userID).First(&thread).Error; err != nil {
+		return nil, errors.New("会话不存在")
+	}
+	var messages []model.ChatMessage
+	err := s.db.Where("thread_id = ?", thread.ID).Order("id asc").Find(&messages).Error
+	return messages, err
+}
+
+// GetQuota 查询用户当日提问配额，有效订阅用户使用订阅额度
+func (s *ChatService) GetQuota(userID uint) (*ChatQuota, error) {
+	quota, err := s.quotaLimit(userID)
+	if err != nil {
+		return nil, err
+	}
+	var usage model.ChatUsage
+	if err := s.db.Where("user_id = ? AND day = ?", userID, s.today()).Limit(1).Find(&usage).Error; err != nil {
+		return nil, err
+	}
+	quota.Used = usage.Used
+	return quota, nil
+}
+
+// quotaLimit 按用户订阅状态确定当日提问次数上限
+func (s *ChatService) quotaLimit(userID uint) (*ChatQuota, error) {
+	subscribed, err := NewSubscriptionService(s.db).HasActiveSubscription(userID)
+	if err != nil {
+		return nil, err
+	}
+	quota := &ChatQuota{Plan: "free", Limit: s.conf.FreeDailyMessages}
+	if subscribed {
+		quota.Plan = "subscriber"
+		quota.Limit = s.conf.SubscriberDailyMessages
+	}
+	return quota, nil
+}
+
+// reserveQuota 预占一次当日提问额度，返回额度所在的日期
+// 以带上限条件的UPDATE原子递增计数，并发提问时最多只有Limit个请求成功
+func (s *ChatService) reserveQuota(userID uint) (string, error) {
+	quota, err := s.quotaLimit(userID)
+	if err != nil {
+		return "", err
+	}
+	db := s.db
+	day := s.today()
+	usage := model.ChatUsage{UserID: userID, Day: day, UpdatedAt: s.clock.Now()}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&usage).Error; err != nil {
+		return "", err
+	}
+	res := db.Model(&model.ChatUsage{}).Where("user_id = ? AND day = ? AND used < ?", userID, day, quota.Limit).
+		Updates(map[string]interface{}{"used": gorm.Expr("used + 1"), "updated_at": s.clock.Now()})
+	if res.Error != nil {
+		return "", res.Error
+	}
+	if res.RowsAffected == 0 {
+		s.logger.Warn("论文问答配额已用完", zap.Uint("user_id", userID), zap.String("plan", quota.Plan), zap.Int("limit", quota.Limit))
+		return "", ErrChatQuotaExceeded
+	}
+	return day, nil
+}
+
+// releaseQuota 退还预占的提问额度
+func (s *ChatService) releaseQuota(userID uint, day string) {
+	err := s.db.Model(&model.ChatUsage{}).Where("user_id = ? AND day = ? AND used > 0", userID, day).
+		Updates(map[string]interface{}{"used": gorm.Expr("used - 1"), "updated_at": s.clock.Now()}).Error
+	if err != nil {
+		s.logger.Error("退还提问额度失败", zap.Error(err), zap.Uint("user_id", userID))
+	}
+}
+
+// today 当前日期，作为每日配额的计数键
+func (s *ChatService) today() string {
+	return s.clock.Now().Format("2006-01-02")
+}
+
+// accessiblePaper 查询论文并校验访问权限：论文上传者或论文有公开的分析任务
+func (s *ChatService) accessiblePaper(userID, paperID uint) (*model.Paper, error) {
+	db := s.db
+	var paper model.Paper
+	if err := db.First(&paper, paperID).Error; err != nil {
+		return nil, errors.New("论文不存在")
+	}
+	ok, err := paperAccessible(s.db, userID, &paper)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrChatForbidden
+	}
+	return &paper, nil
+}
+
+// chatSource 组装问答依据：本地提取的逐页文本和最近一次可见的分析报告
+func (s *ChatService) chatSource(ctx context.Context, userID uint, paper *model.Paper) (*aitools.PaperChatSource, error) {
+	db := s.db
+	source := &aitools.PaperChatSource{}
+
+	var result model.AnalysisResult
+	err := db.Joins("JOIN analysis_tasks ON analysis_tasks.id = analysis_results.task_id").
+		Where("analysis_tasks.paper_id = ? AND (analysis_tasks.user_id = ? OR analysis_tasks.is_public = ?)", paper.ID, userID, true).
+		Order("analysis_results.id desc").Limit(1).Find(&result).Error
+	if err != nil {
+		return nil, err
+	}
+	if result.Analysis != nil {
+		source.Analysis = result.Analysis
+		source.Title = result.Analysis.BasicInfo.Title
+	}
+
+	content, err := s.paperContent(ctx, paper)
+	if err != nil {
+		// 扫描件等无法提取文本时仅依据分析报告回答
+		s.logger.Warn("获取论文文本失败", zap.Error(err), zap.Uint("paper_id", paper.ID))
+	} else {
+		source.Pages = content.Pages
+		source.Abstract = content.Abstract
+		if source.Title == "" {
+			source.Title = content.Title
+		}
+	}
+	if source.Analysis == nil && len(source.Pages) == 0 {
+		return nil, ErrChatNotReady
+	}
+	return source, nil
+}
+
+// paperContent 获取论文提取内容，尚未提取时下载PDF提取
+func (s *ChatService) paperContent(ctx context.Context, paper *model.Paper) (*model.PaperContent, error) {
+	var content model.PaperContent
+	if err := s.db.Where("paper_id = ?", paper.ID).Limit(1).Find(&content).Error; err != nil {
+		return nil, err
+	}
+	if content.ID != 0 {
+		return &content, nil
+	}
+	data, err := s.papers.readFile(ctx, paper.OSSPath)
+	if err != nil {
+		return nil, fmt.Errorf("下载论文失败: %w", err)
+	}
+	return s.papers.GetOrExtractContent(ctx, paper, data)
+}
+
+// recentHistory 读取会话最近的消息作为上下文，按时间顺序返回
+func (s *ChatService) recentHistory(threadID uint) ([]aitools.ChatMessage, error) {
+	var messages []model.ChatMessage
+	if err := s.db.Where("thread_id = ?", threadID).Order("id desc").
+		Limit(s.conf.HistoryMessages).Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	history := make([]aitools.ChatMessage, 0, len(messages))
+	for i := len(messages) - 1; i >= 0; i-- {
+		role := aitools.RoleUser
+		if messages[i].Role == model.ChatRoleAssistant {
+			role = aitools.RoleAssistant
+		}
+		history = append(history, aitools.ChatMessage{Role: role, Content: messages[i].Content})
+	}
+	return history, nil
+}
+
+// truncateRunes 按字符截断文本
+func truncateRunes(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n])
+}
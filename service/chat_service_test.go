@@ -0,0 +1,54 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"papergraph/aitools"
+	"papergraph/config"
+
+	"go.uber.org/zap"
+)
+
+func TestReserveQuotaConcurrent(t *testing.T) {
+	db := newTestDB(t)
+	user := createTestUser(t, db, "chat@example.com")
+	conf := config.Default(config.ProfileTest).Chat
+	conf.FreeDailyMessages = 3
+	chat := NewChatService(db, zap.NewNop(), aitools.NewFakeProvider(), newTestPaperService(t, db, nil), conf, SystemClock{})
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	reserved, exceeded := 0, 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := chat.reserveQuota(user.ID)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				reserved++
+			case errors.Is(err, ErrChatQuotaExceeded):
+				exceeded++
+			default:
+				t.Errorf("预占额度失败: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if reserved != 3 || exceeded != 7 {
+		t.Fatalf("成功%d次、超出%d次，期望3次和7次", reserved, exceeded)
+	}
+
+	chat.releaseQuota(user.ID, chat.today())
+	quota, err := chat.GetQuota(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quota.Used != 2 || quota.Limit != 3 {
+		t.Errorf("used=%d limit=%d，期望退还后已用2次", quota.Used, quota.Limit)
+	}
+}
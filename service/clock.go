@@ -0,0 +1,16 @@
+package service
+
+import "time"
+
+// Clock 提供当前时间，测试中可替换为固定或可调的时间
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock 使用系统时间
+type SystemClock struct{}
+
+// Now 当前系统时间
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
@@ -2,26 +2,29 @@ package service
 
 import (
 	"errors"
-	"papergraph/config"
 	"papergraph/model"
-	"time"
 
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // CommentService 评论相关业务逻辑
-type CommentService struct{}
+type CommentService struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	clock  Clock
+}
 
 // NewCommentService 创建CommentService实例
-func NewCommentService() *CommentService {
-	return &CommentService{}
+func NewCommentService(db *gorm.DB, logger *zap.Logger, clock Clock) *CommentService {
+	return &CommentService{db: db, logger: logger, clock: clock}
 }
 
 // AddComment 添加评论或回复
 func (s *CommentService) AddComment(userID, taskID uint, content string, parentID *uint) (*model.Comment, error) {
-	config.Logger.Info("添加评论", zap.Uint("user_id", userID), zap.Uint("task_id", taskID), zap.String("content", content))
+	s.logger.Info("添加评论", zap.Uint("user_id", userID), zap.Uint("task_id", taskID), zap.String("content", content))
 	if content == "" {
-		config.Logger.Warn("评论内容为空", zap.Uint("user_id", userID), zap.Uint("task_id", taskID))
+		s.logger.Warn("评论内容为空", zap.Uint("user_id", userID), zap.Uint("task_id", taskID))
 		return nil, errors.New("评论内容不能为空")
 	}
 	comment := model.Comment{
@@ -29,24 +32,24 @@ func (s *CommentService) AddComment(userID, taskID uint, content string, parentI
 		UserID:    userID,
 		Content:   content,
 		ParentID:  parentID,
-		CreatedAt: time.Now(),
+		CreatedAt: s.clock.Now(),
 	}
-	db := config.DB
+	db := s.db
 	if err := db.Create(&comment).Error; err != nil {
-		config.Logger.Error("保存评论失败", zap.Error(err), zap.Uint("user_id", userID), zap.Uint("task_id", taskID))
+		s.logger.Error("保存评论失败", zap.Error(err), zap.Uint("user_id", userID), zap.Uint("task_id", taskID))
 		return nil, err
 	}
-	config.Logger.Info("评论保存成功", zap.Uint("comment_id", comment.ID))
+	s.logger.Info("评论保存成功", zap.Uint("comment_id", comment.ID))
 	return &comment, nil
 }
 
 // GetComments 获取某分析任务下的评论列表，按时间正序
 func (s *CommentService) GetComments(taskID uint) ([]model.Comment, error) {
-	config.Logger.Info("获取评论列表", zap.Uint("task_id", taskID))
-	db := config.DB
+	s.logger.Info("获取评论列表", zap.Uint("task_id", taskID))
+	db := s.db
 	var comments []model.Comment
 	if err := db.Where("task_id = ?", taskID).Order("created_at asc").Find(&comments).Error; err != nil {
-		config.Logger.Error("查询评论失败", zap.Error(err), zap.Uint("task_id", taskID))
+		s.logger.Error("查询评论失败", zap.Error(err), zap.Uint("task_id", taskID))
 		return nil, err
 	}
 	return comments, nil
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // EvaluationService 评价服务
@@ -17,9 +18,9 @@ func NewEvaluationService(db *gorm.DB) *EvaluationService {
 	return &EvaluationService{db: db}
 }
 
-// CreateEvaluation 创建论文评价
+// CreateEvaluation 创建论文评价（不级联写入关联数据）
 func (s *EvaluationService) CreateEvaluation(evaluation *model.PaperEvaluation) error {
-	return s.db.Create(evaluation).Error
+	return s.db.Omit(clause.Associations).Create(evaluation).Error
 }
 
 // GetEvaluationByID 根据ID获取评价
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"papergraph/storage"
+)
+
+// putFile 保存论文PDF，返回对象路径
+func (s *PaperService) putFile(ctx context.Context, fileName string, r io.Reader, size int64) (string, error) {
+	if s.store == nil {
+		return "", errors.New("文件存储未初始化")
+	}
+	key := newPaperFileKey(fileName, s.clock.Now())
+	if err := s.store.Put(ctx, key, r, size, "application/pdf"); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// newPaperFileKey 生成论文PDF的对象路径
+func newPaperFileKey(fileName string, now time.Time) string {
+	name := sanitizeFileName(fileName)
+	if name == "" {
+		name = "paper.pdf"
+	}
+	return fmt.Sprintf("%s%d_%s", paperFilePrefix, now.UnixNano(), name)
+}
+
+// readFile 读取论文PDF
+func (s *PaperService) readFile(ctx context.Context, key string) ([]byte, error) {
+	if s.store == nil {
+		return nil, errors.New("文件存储未初始化")
+	}
+	return storage.ReadAll(ctx, s.store, key)
+}
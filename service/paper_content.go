@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"papergraph/aitools"
+	"papergraph/model"
+	"papergraph/utils"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 结构识别参数
+const (
+	maxAbstractRunes  = 3000 // 摘要最大字符数
+	maxReferenceCount = 500  // 最多保留的参考文献条目数
+)
+
+var (
+	// abstractHeadingPattern 摘要标题，标题后可能直接跟摘要正文
+	abstractHeadingPattern = regexp.MustCompile(`^(?i:abstract)\b[\s:：.—-]*|^摘\s*要[\s:：]*`)
+	// abstractEndPattern 摘要结束标志
+	abstractEndPattern = regexp.MustCompile(`^(?i:keywords|key words|index terms)\b|^关键词|^关键字`)
+	// referencesHeadingPattern 参考文献标题
+	referencesHeadingPattern = regexp.MustCompile(`^(?:\d{1,2}\.?\s+)?(?i:references|bibliography)$|^参考文献$`)
+	// referenceStartPattern 参考文献条目开头，如[12]、12.
+	referenceStartPattern = regexp.MustCompile(`^(?:\[\d{1,3}\]|\d{1,3}\.\s)`)
+	// sectionNumberPattern 章节编号，用于计算层级
+	sectionNumberPattern = regexp.MustCompile(`^\d{1,2}((?:\.\d{1,2})*)\.?\s`)
+	// titleNoisePattern 首页中不可能是标题的行
+	titleNoisePattern = regexp.MustCompile(`(?i)^(arxiv|preprint|proceedings|journal|vol\.|doi|http|©|copyright|\d)`)
+)
+
+// GetOrExtractContent 获取论文本地提取内容，不存在时从PDF提取并保存
+func (s *PaperService) GetOrExtractContent(ctx context.Context, paper *model.Paper, data []byte) (*model.PaperContent, error) {
+	db := s.db
+	var content model.PaperContent
+	err := db.Where("paper_id = ?", paper.ID).First(&content).Error
+	if err == nil {
+		return &content, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	pages, err := utils.ExtractPDFPages(ctx, data)
+	if err != nil {
+		s.logger.Warn("PDF文本提取失败", zap.Uint("paper_id", paper.ID), zap.Error(err))
+		return nil, err
+	}
+	extracted := BuildPaperContent(paper.ID, pages)
+	if err := db.Create(extracted).Error; err != nil {
+		s.logger.Error("保存论文提取内容失败", zap.Uint("paper_id", paper.ID), zap.Error(err))
+		return nil, err
+	}
+	if paper.PageCount != len(pages) {
+		paper.PageCount = len(pages)
+		db.Model(paper).Update("page_count", len(pages))
+	}
+	s.logger.Info("论文内容提取完成", zap.Uint("paper_id", paper.ID), zap.Int("pages", len(pages)),
+		zap.Int("sections", len(extracted.Sections)), zap.Int("references", len(extracted.References)))
+	return extracted, nil
+}
+
+// BuildPaperContent 根据逐页文本识别标题、摘要、章节和参考文献
+func BuildPaperContent(paperID uint, pages []string) *model.PaperContent {
+	content := &model.PaperContent{PaperID: paperID, Pages: pages}
+	for _, page := range pages {
+		content.CharCount += utf8.RuneCountInString(page)
+	}
+	if len(pages) == 0 {
+		return content
+	}
+	content.Title = detectTitle(pages[0])
+	content.Abstract = detectAbstract(pages[:min(2, len(pages))])
+
+	inReferences := false
+	var reference strings.Builder
+	flushReference := func() {
+		if text := strings.TrimSpace(reference.String()); text != "" && len(content.References) < maxReferenceCount {
+			content.References = append(content.References, text)
+		}
+		reference.Reset()
+	}
+	for i, page := range pages {
+		for _, line := range strings.Split(page, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			if referencesHeadingPattern.MatchString(line) {
+				content.Sections = append(content.Sections, model.PaperSection{Title: line, Level: 1, Page: i + 1})
+				inReferences = true
+				continue
+			}
+			if inReferences {
+				// 参考文献之后出现的附录等章节结束参考文献区
+				if aitools.IsSectionHeading(line) && !referenceStartPattern.MatchString(line) {
+					flushReference()
+					inReferences = false
+				} else {
+					if referenceStartPattern.MatchString(line) {
+						flushReference()
+					}
+					if reference.Len() > 0 {
+						reference.WriteByte(' ')
+					}
+					reference.WriteString(line)
+					continue
+				}
+			}
+			if aitools.IsSectionHeading(line) {
+				content.Sections = append(content.Sections, model.PaperSection{Title: line, Level: sectionLevel(line), Page: i + 1})
+			}
+		}
+	}
+	flushReference()
+	return content
+}
+
+// detectTitle 取首页开头第一行像标题的文本
+func detectTitle(firstPage string) string {
+	for _, line := range strings.Split(firstPage, "\n") {
+		line = strings.TrimSpace(line)
+		n := utf8.RuneCountInString(line)
+		if n < 4 || n > 250 || titleNoisePattern.MatchString(line) || abstractHeadingPattern.MatchString(line) {
+			continue
+		}
+		return line
+	}
+	return ""
+}
+
+// detectAbstract 在前两页中查找摘要段落
+func detectAbstract(pages []string) string {
+	lines := strings.Split(strings.Join(pages, "\n"), "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		loc := abstractHeadingPattern.FindStringIndex(line)
+		if loc == nil {
+			continue
+		}
+		var b strings.Builder
+		b.WriteString(line[loc[1]:])
+		for _, next := range lines[i+1:] {
+			next = strings.TrimSpace(next)
+			if abstractEndPattern.MatchString(next) || aitools.IsSectionHeading(next) {
+				break
+			}
+			if b.Len() > 0 && next != "" {
+				b.WriteByte(' ')
+			}
+			b.WriteString(next)
+			if utf8.RuneCountInString(b.String()) >= maxAbstractRunes {
+				break
+			}
+		}
+		abstract := []rune(strings.TrimSpace(b.String()))
+		if len(abstract) > maxAbstractRunes {
+			abstract = abstract[:maxAbstractRunes]
+		}
+		return string(abstract)
+	}
+	return ""
+}
+
+// sectionLevel 根据编号计算章节层级，如"2.1 方法"为2级
+func sectionLevel(title string) int {
+	m := sectionNumberPattern.FindStringSubmatch(title)
+	if m == nil {
+		return 1
+	}
+	return strings.Count(m[1], ".") + 1
+}
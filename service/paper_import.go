@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"papergraph/config"
+	"papergraph/model"
+	"papergraph/utils"
+
+	"go.uber.org/zap"
+)
+
+// 论文导入来源类型
+const (
+	ImportSourceURL   = "url"
+	ImportSourceArxiv = "arxiv"
+	ImportSourceDOI   = "doi"
+)
+
+// 论文导入错误
+var (
+	ErrImportUnsupported = errors.New("无法识别的论文链接、arXiv ID或DOI")
+	ErrImportNotPDF      = errors.New("链接内容不是PDF文件")
+	ErrImportNotFound    = errors.New("未找到该DOI对应的开放获取PDF")
+)
+
+// ImportSource 解析后的论文导入来源
+type ImportSource struct {
+	Type  string `json:"type"`  // url/arxiv/doi
+	Value string `json:"value"` // 链接、arXiv ID或DOI
+}
+
+var (
+	// arxivNewIDPattern 2007年4月之后的arXiv ID，如2301.00001v2
+	arxivNewIDPattern = regexp.MustCompile(`^\d{4}\.\d{4,5}(v\d+)?$`)
+	// arxivOldIDPattern 旧格式arXiv ID，如hep-th/9901001
+	arxivOldIDPattern = regexp.MustCompile(`^[a-z\-]+(\.[A-Z]{2})?/\d{7}(v\d+)?$`)
+	// doiPattern DOI，如10.1038/nature14539
+	doiPattern = regexp.MustCompile(`^10\.\d{4,9}/\S+$`)
+	// arxivDOIPrefix arXiv为论文分配的DOI前缀
+	arxivDOIPrefix = "10.48550/arxiv."
+)
+
+// ParseImportSource 识别用户输入的论文来源
+// 支持arXiv ID（可带arXiv:前缀）、arXiv摘要页或PDF链接、DOI（可带doi:前缀或doi.org链接）和PDF直链
+func ParseImportSource(input string) (*ImportSource, error) {
+	s := strings.TrimSpace(input)
+	lower := strings.ToLower(s)
+	switch {
+	case strings.HasPrefix(lower, "arxiv:"):
+		return arxivSource(strings.TrimSpace(s[len("arxiv:"):]))
+	case strings.HasPrefix(lower, "doi:"):
+		return doiSource(strings.TrimSpace(s[len("doi:"):]))
+	case arxivNewIDPattern.MatchString(s) || arxivOldIDPattern.MatchString(s):
+		return arxivSource(s)
+	case doiPattern.MatchString(s):
+		return doiSource(s)
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return nil, ErrImportUnsupported
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	switch host {
+	case "arxiv.org", "export.arxiv.org":
+		for _, prefix := range []string{"/abs/", "/pdf/"} {
+			if id, ok := strings.CutPrefix(u.Path, prefix); ok {
+				return arxivSource(strings.TrimSuffix(id, ".pdf"))
+			}
+		}
+	case "doi.org", "dx.doi.org":
+		if doi, err := url.PathUnescape(strings.TrimPrefix(u.Path, "/")); err == nil {
+			return doiSource(doi)
+		}
+	}
+	return &ImportSource{Type: ImportSourceURL, Value: u.String()}, nil
+}
+
+// arxivSource 校验arXiv ID
+func arxivSource(id string) (*ImportSource, error) {
+	if !arxivNewIDPattern.MatchString(id) && !arxivOldIDPattern.MatchString(id) {
+		return nil, ErrImportUnsupported
+	}
+	return &ImportSource{Type: ImportSourceArxiv, Value: id}, nil
+}
+
+// doiSource 校验DOI，arXiv分配的DOI直接转为arXiv ID
+func doiSource(doi string) (*ImportSource, error) {
+	if !doiPattern.MatchString(doi) {
+		return nil, ErrImportUnsupported
+	}
+	if id, ok := strings.CutPrefix(strings.ToLower(doi), arxivDOIPrefix); ok {
+		return arxivSource(doi[len(doi)-len(id):])
+	}
+	return &ImportSource{Type: ImportSourceDOI, Value: doi}, nil
+}
+
+// PaperImportService 按链接、arXiv ID或DOI导入论文
+type PaperImportService struct {
+	logger *zap.Logger
+	papers *PaperService // 下载完成后按上传流程创建论文和分析任务
+	conf   config.ImportConfig
+	client *http.Client
+}
+
+// NewPaperImportService 创建导入服务
+// 配置的解析服务地址为可信地址，其余地址（用户链接及其重定向目标）禁止访问内网
+func NewPaperImportService(logger *zap.Logger, papers *PaperService, conf config.ImportConfig) *PaperImportService {
+	var trusted []string
+	for _, endpoint := range []string{conf.ArxivPDFURL, conf.UnpaywallURL, conf.DOIResolverURL} {
+		// arXiv地址模板中的%s不是合法的URL转义，去掉后再解析
+		u, err := url.Parse(strings.ReplaceAll(endpoint, "%s", ""))
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+		port := u.Port()
+		if port == "" {
+			port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+		}
+		trusted = append(trusted, net.JoinHostPort(u.Hostname(), port))
+	}
+	return &PaperImportService{logger: logger, papers: papers, conf: conf, client: utils.NewSafeHTTPClient(conf.Timeout, conf.MaxRedirects, trusted...)}
+}
+
+// Import 下载论文PDF并创建分析任务，后续流程与上传PDF相同
+func (s *PaperImportService) Import(ctx context.Context, userID uint, input string, forceFresh bool) (*model.Paper, *model.AnalysisTask, *ImportSource, error) {
+	source, err := ParseImportSource(input)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	s.logger.Info("开始导入论文", zap.Uint("user_id", userID), zap.String("type", source.Type), zap.String("value", source.Value))
+
+	upload, err := s.fetchSource(ctx, source)
+	if err != nil {
+		s.logger.Warn("下载论文失败", zap.Error(err), zap.String("type", source.Type), zap.String("value", source.Value))
+		return nil, nil, source, err
+	}
+	defer upload.Close()
+	paper, task, err := s.papers.UploadAndCreateTask(userID, upload, forceFresh)
+	return paper, task, source, err
+}
+
+// fetchSource 按来源类型下载PDF
+func (s *PaperImportService) fetchSource(ctx context.Context, source *ImportSource) (*SpooledUpload, error) {
+	var upload *SpooledUpload
+	var err error
+	switch source.Type {
+	case ImportSourceArxiv:
+		if upload, err = s.download(ctx, fmt.Sprintf(s.conf.ArxivPDFURL, source.Value)); err == nil {
+			upload.Name = "arXiv-" + strings.ReplaceAll(source.Value, "/", "_") + ".pdf"
+		}
+	case ImportSourceDOI:
+		if upload, err = s.fetchDOI(ctx, source.Value); err == nil {
+			upload.Name = sanitizeFileName(source.Value) + ".pdf"
+		}
+	default:
+		upload, err = s.download(ctx, source.Value)
+	}
+	return upload, err
+}
+
+// unpaywallResponse Unpaywall API响应中用到的字段
+type unpaywallResponse struct {
+	BestOALocation *unpaywallLocation  `json:"best_oa_location"`
+	OALocations    []unpaywallLocation `json:"oa_locations"`
+}
+
+type unpaywallLocation struct {
+	URLForPDF string `json:"url_for_pdf"`
+}
+
+// fetchDOI 查找DOI对应的PDF：先通过Unpaywall查找开放获取地址，再尝试DOI解析地址直接返回PDF
+func (s *PaperImportService) fetchDOI(ctx context.Context, doi string) (*SpooledUpload, error) {
+	var candidates []string
+	if s.conf.UnpaywallEmail != "" {
+		urls, err := s.unpaywallPDFs(ctx, doi)
+		if err != nil {
+			s.logger.Warn("查询Unpaywall失败", zap.Error(err), zap.String("doi", doi))
+		}
+		candidates = append(candidates, urls...)
+	}
+	candidates = append(candidates, s.conf.DOIResolverURL+escapeDOI(doi))
+
+	var lastErr error
+	for _, candidate := range candidates {
+		upload, err := s.download(ctx, candidate)
+		if err == nil {
+			return upload, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		lastErr = err
+	}
+	if errors.Is(lastErr, ErrImportNotPDF) {
+		return nil, ErrImportNotFound
+	}
+	return nil, lastErr
+}
+
+// unpaywallPDFs 查询Unpaywall中DOI的开放获取PDF地址，最佳地址在前
+func (s *PaperImportService) unpaywallPDFs(ctx context.Context, doi string) ([]string, error) {
+	endpoint := s.conf.UnpaywallURL + escapeDOI(doi) + "?email=" + url.QueryEscape(s.conf.UnpaywallEmail)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unpaywall返回状态码%d", resp.StatusCode)
+	}
+	var result unpaywallResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析Unpaywall响应失败: %w", err)
+	}
+
+	var urls []string
+	seen := make(map[string]bool)
+	locations := result.OALocations
+	if result.BestOALocation != nil {
+		locations = append([]unpaywallLocation{*result.BestOALocation}, locations...)
+	}
+	for _, loc := range locations {
+		if loc.URLForPDF != "" && !seen[loc.URLForPDF] {
+			seen[loc.URLForPDF] = true
+			urls = append(urls, loc.URLForPDF)
+		}
+	}
+	return urls, nil
+}
+
+// escapeDOI 转义DOI中的特殊字符，保留路径分隔符
+func escapeDOI(doi string) string {
+	return (&url.URL{Path: doi}).EscapedPath()
+}
+
+// pdfContentTypes 允许的PDF响应类型，部分站点以通用二进制类型返回PDF
+var pdfContentTypes = map[string]bool{
+	"application/pdf":          true,
+	"application/x-pdf":        true,
+	"application/octet-stream": true,
+	"binary/octet-stream":      true,
+}
+
+// download 下载PDF，校验状态码和响应类型，内容边下载边写入临时文件并校验大小和PDF结构
+func (s *PaperImportService) download(ctx context.Context, rawURL string) (*SpooledUpload, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, ErrImportUnsupported
+	}
+	req.Header.Set("Accept", "application/pdf")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; PapergraphBot/1.0)")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		if errors.Is(err, utils.ErrPrivateAddress) {
+			return nil, utils.ErrPrivateAddress
+		}
+		return nil, fmt.Errorf("下载失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载失败: 状态码%d", resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if !pdfContentTypes[mediaType] {
+			return nil, ErrImportNotPDF
+		}
+	}
+	if resp.ContentLength > MaxPDFSize {
+		return nil, ErrFileTooLarge
+	}
+	upload, err := SpoolUpload(responseFileName(resp), resp.Body)
+	if errors.Is(err, utils.ErrNotPDF) || errors.Is(err, ErrFileEmpty) {
+		return nil, ErrImportNotPDF
+	}
+	return upload, err
+}
+
+// responseFileName 从Content-Disposition或最终地址中取文件名，统一以.pdf结尾
+func responseFileName(resp *http.Response) string {
+	var name string
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		name = params["filename"]
+	}
+	if name == "" && resp.Request != nil {
+		name = path.Base(resp.Request.URL.Path)
+	}
+	name = sanitizeFileName(strings.TrimSuffix(path.Base(name), ".pdf"))
+	if name == "" || name == "." || name == "_" {
+		name = "paper"
+	}
+	return name + ".pdf"
+}
+
+// unsafeFileNameChars 文件名中需要替换的字符
+var unsafeFileNameChars = regexp.MustCompile(`[^\p{L}\p{N}._\-]+`)
+
+// sanitizeFileName 替换文件名中的路径分隔符、空白和特殊字符，限制长度
+func sanitizeFileName(name string) string {
+	return truncateRunes(strings.Trim(unsafeFileNameChars.ReplaceAllString(name, "_"), "._"), 100)
+}
@@ -0,0 +1,365 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"papergraph/config"
+	"papergraph/model"
+	"papergraph/storage"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 论文删除错误
+var (
+	ErrPaperNotFound  = errors.New("论文不存在")
+	ErrPaperForbidden = errors.New("无权删除该论文")
+)
+
+// paperFilePrefix 论文PDF的对象路径前缀，见newPaperFileKey
+const paperFilePrefix = "papers/"
+
+// DeletePaper 删除论文（仅上传者可操作）
+// 排队中和分析中的任务先取消；论文及其任务、分析结果、评价、评论、点赞、问答记录和提取内容全部物理删除；
+// 存储的PDF没有被其他论文引用时一并删除，删除失败的对象由StorageGC稍后清理。
+// 引用该论文的对比分析任务保留，其结果中已包含论文摘要
+func (s *PaperService) DeletePaper(ctx context.Context, userID, paperID uint) error {
+	s.logger.Info("删除论文", zap.Uint("user_id", userID), zap.Uint("paper_id", paperID))
+	db := s.db
+	var paper model.Paper
+	if err := db.Where("id = ?", paperID).Limit(1).Find(&paper).Error; err != nil {
+		return err
+	}
+	if paper.ID == 0 {
+		return ErrPaperNotFound
+	}
+	if paper.UserID != userID {
+		return ErrPaperForbidden
+	}
+
+	var activeTaskIDs []uint
+	var unreferenced []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.AnalysisTask{}).
+			Where("paper_id = ? AND status IN ?", paper.ID, []string{model.TaskStatusQueued, model.TaskStatusRunning}).
+			Pluck("id", &activeTaskIDs).Error; err != nil {
+			return err
+		}
+		if len(activeTaskIDs) > 0 {
+			if err := tx.Model(&model.AnalysisTask{}).Where("id IN ?", activeTaskIDs).Updates(map[string]interface{}{
+				"status":      model.TaskStatusCanceled,
+				"stage":       model.TaskStageCanceled,
+				"finished_at": s.clock.Now(),
+			}).Error; err != nil {
+				return err
+			}
+		}
+		var err error
+		unreferenced, err = purgePapersAndFiles(tx, []model.Paper{paper})
+		return err
+	})
+	if err != nil {
+		s.logger.Error("删除论文失败", zap.Error(err), zap.Uint("paper_id", paper.ID))
+		return err
+	}
+	for _, taskID := range activeTaskIDs {
+		cancelRunningTask(s.tasks, taskID)
+	}
+	for _, key := range unreferenced {
+		if err := deleteFile(ctx, s.store, key); err != nil {
+			s.logger.Warn("删除论文文件失败，等待垃圾回收清理", zap.Error(err), zap.String("oss_path", key))
+		}
+	}
+	s.logger.Info("论文已删除", zap.Uint("paper_id", paper.ID), zap.Int("canceled_tasks", len(activeTaskIDs)))
+	return nil
+}
+
+// purgePapers 物理删除论文及其关联数据，需在事务中调用
+// 批量上传记录保留，只清除其中的论文和任务关联
+func purgePapers(tx *gorm.DB, paperIDs []uint) error {
+	if len(paperIDs) == 0 {
+		return nil
+	}
+	db := tx.Unscoped().Session(&gorm.Session{})
+	tasks := db.Model(&model.AnalysisTask{}).Select("id").Where("paper_id IN ?", paperIDs)
+	evaluations := db.Model(&model.PaperEvaluation{}).Select("id").Where("paper_id IN ?", paperIDs)
+	dimensions := db.Model(&model.EvaluationDimension{}).Select("id").Where("evaluation_id IN (?)", evaluations)
+	threads := db.Model(&model.ChatThread{}).Select("id").Where("paper_id IN ?", paperIDs)
+
+	steps := []func() *gorm.DB{
+		// 评价
+		func() *gorm.DB { return db.Where("dimension_id IN (?)", dimensions).Delete(&model.EvaluationMetric{}) },
+		func() *gorm.DB {
+			return db.Where("evaluation_id IN (?)", evaluations).Delete(&model.EvaluationDimension{})
+		},
+		func() *gorm.DB {
+			return db.Where("evaluation_id IN (?)", evaluations).Delete(&model.EvaluationComment{})
+		},
+		func() *gorm.DB { return db.Where("evaluation_id IN (?)", evaluations).Delete(&model.EvaluationLike{}) },
+		func() *gorm.DB { return db.Where("paper_id IN ?", paperIDs).Delete(&model.PaperEvaluation{}) },
+		// 任务
+		func() *gorm.DB { return db.Where("task_id IN (?)", tasks).Delete(&model.AnalysisResult{}) },
+		func() *gorm.DB { return db.Where("task_id IN (?)", tasks).Delete(&model.Comment{}) },
+		func() *gorm.DB { return db.Where("task_id IN (?)", tasks).Delete(&model.TaskReaction{}) },
+		func() *gorm.DB {
+			return db.Where("target_type = ? AND target_id IN (?)", model.TargetAnalysis, tasks).Delete(&model.UserActivity{})
+		},
+		func() *gorm.DB {
+			return db.Model(&model.UploadBatchItem{}).Where("paper_id IN ?", paperIDs).Updates(map[string]interface{}{"paper_id": 0, "task_id": 0, "error": "论文已删除"})
+		},
+		func() *gorm.DB { return db.Where("paper_id IN ?", paperIDs).Delete(&model.AnalysisTask{}) },
+		// 问答和提取内容
+		func() *gorm.DB { return db.Where("thread_id IN (?)", threads).Delete(&model.ChatMessage{}) },
+		func() *gorm.DB { return db.Where("paper_id IN ?", paperIDs).Delete(&model.ChatThread{}) },
+		func() *gorm.DB { return db.Where("paper_id IN ?", paperIDs).Delete(&model.PaperContent{}) },
+		func() *gorm.DB {
+			return db.Where("target_type = ? AND target_id IN ?", model.TargetPaper, paperIDs).Delete(&model.UserActivity{})
+		},
+		func() *gorm.DB { return db.Where("id IN ?", paperIDs).Delete(&model.Paper{}) },
+	}
+	for _, step := range steps {
+		if err := step().Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// purgePapersAndFiles 物理删除论文及其关联数据，返回不再被任何论文引用、可以删除的对象路径，需在事务中调用
+// 先锁定相同内容的论文记录（含软删除的），与上传时复用对象（见createPaperWithFile）串行执行；
+// 引用检查与删除论文记录在同一事务中完成，提交后上传方查不到这些论文，只会上传新对象，不会引用即将删除的对象。
+// 调用方在事务提交后删除返回的对象
+func purgePapersAndFiles(tx *gorm.DB, papers []model.Paper) ([]string, error) {
+	ids := make([]uint, 0, len(papers))
+	var hashes []string
+	for _, paper := range papers {
+		ids = append(ids, paper.ID)
+		if paper.ContentHash != "" {
+			hashes = append(hashes, paper.ContentHash)
+		}
+	}
+	if len(hashes) > 0 {
+		var locked []uint
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Model(&model.Paper{}).
+			Where("content_hash IN ?", hashes).Pluck("id", &locked).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := purgePapers(tx, ids); err != nil {
+		return nil, err
+	}
+
+	var unreferenced []string
+	seen := make(map[string]bool, len(papers))
+	for _, paper := range papers {
+		if paper.OSSPath == "" || seen[paper.OSSPath] {
+			continue
+		}
+		seen[paper.OSSPath] = true
+		referenced, err := fileReferenced(tx, paper.OSSPath)
+		if err != nil {
+			return nil, err
+		}
+		if !referenced {
+			unreferenced = append(unreferenced, paper.OSSPath)
+		}
+	}
+	return unreferenced, nil
+}
+
+// deleteFile 删除存储中的对象
+func deleteFile(ctx context.Context, store storage.Storage, key string) error {
+	if store == nil {
+		return errors.New("文件存储未初始化")
+	}
+	return store.Delete(ctx, key)
+}
+
+// fileReferenced 对象是否被论文或未确认的直传票据引用
+func fileReferenced(db *gorm.DB, key string) (bool, error) {
+	var papers int64
+	if err := db.Unscoped().Model(&model.Paper{}).Where("oss_path = ?", key).Count(&papers).Error; err != nil {
+		return false, err
+	}
+	if papers > 0 {
+		return true, nil
+	}
+	var tickets int64
+	if err := db.Model(&model.UploadTicket{}).
+		Where("object_key = ? AND status = ?", key, model.UploadTicketPending).Count(&tickets).Error; err != nil {
+		return false, err
+	}
+	return tickets > 0, nil
+}
+
+// StorageGC 存储垃圾回收
+// 清理软删除的论文、父记录已不存在的关联数据，以及papers/、uploads/下没有被任何论文或未确认票据引用的对象
+type StorageGC struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	store  storage.Storage
+	conf   config.StorageGCConfig
+	clock  Clock
+}
+
+// NewStorageGC 创建StorageGC实例
+func NewStorageGC(db *gorm.DB, logger *zap.Logger, store storage.Storage, conf config.StorageGCConfig, clock Clock) *StorageGC {
+	return &StorageGC{db: db, logger: logger, store: store, conf: conf, clock: clock}
+}
+
+// StorageGCReport 一次垃圾回收的清理结果
+type StorageGCReport struct {
+	PurgedPapers   int   `json:"purged_papers"`   // 物理删除的软删除论文数
+	OrphanRows     int64 `json:"orphan_rows"`     // 删除的无主记录数
+	OrphanObjects  int   `json:"orphan_objects"`  // 删除的无主对象数
+	ScannedObjects int   `json:"scanned_objects"` // 扫描的对象数
+}
+
+// Run 执行一次垃圾回收
+func (g *StorageGC) Run(ctx context.Context) (*StorageGCReport, error) {
+	report := &StorageGCReport{}
+	if err := g.purgeDeletedPapers(ctx, report); err != nil {
+		return report, err
+	}
+	if err := g.deleteOrphanRows(report); err != nil {
+		return report, err
+	}
+	if err := g.deleteOrphanObjects(ctx, report); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// purgeDeletedPapers 物理删除软删除的论文及其关联数据和文件
+func (g *StorageGC) purgeDeletedPapers(ctx context.Context, report *StorageGCReport) error {
+	var papers []model.Paper
+	if err := g.db.Unscoped().Where("deleted_at IS NOT NULL").Order("id asc").Limit(500).Find(&papers).Error; err != nil {
+		return err
+	}
+	if len(papers) == 0 {
+		return nil
+	}
+	var unreferenced []string
+	if err := g.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		unreferenced, err = purgePapersAndFiles(tx, papers)
+		return err
+	}); err != nil {
+		return err
+	}
+	report.PurgedPapers = len(papers)
+	for _, key := range unreferenced {
+		if err := deleteFile(ctx, g.store, key); err != nil {
+			g.logger.Warn("删除论文文件失败", zap.Error(err), zap.String("oss_path", key))
+		}
+	}
+	return nil
+}
+
+// deleteOrphanRows 删除父记录已不存在的关联数据，按先父后子的顺序逐级清理
+func (g *StorageGC) deleteOrphanRows(report *StorageGCReport) error {
+	db := g.db.Unscoped().Session(&gorm.Session{})
+	papers := db.Model(&model.Paper{}).Select("id")
+	tasks := db.Model(&model.AnalysisTask{}).Select("id")
+	threads := db.Model(&model.ChatThread{}).Select("id")
+	evaluations := db.Model(&model.PaperEvaluation{}).Select("id")
+	dimensions := db.Model(&model.EvaluationDimension{}).Select("id")
+
+	steps := []func() *gorm.DB{
+		// 对比分析任务的PaperID为0，不在此清理
+		func() *gorm.DB {
+			return db.Where("paper_id <> 0 AND paper_id NOT IN (?)", papers).Delete(&model.AnalysisTask{})
+		},
+		func() *gorm.DB { return db.Where("paper_id NOT IN (?)", papers).Delete(&model.PaperContent{}) },
+		func() *gorm.DB { return db.Where("paper_id NOT IN (?)", papers).Delete(&model.ChatThread{}) },
+		func() *gorm.DB { return db.Where("paper_id NOT IN (?)", papers).Delete(&model.PaperEvaluation{}) },
+		func() *gorm.DB { return db.Where("task_id NOT IN (?)", tasks).Delete(&model.AnalysisResult{}) },
+		func() *gorm.DB { return db.Where("task_id NOT IN (?)", tasks).Delete(&model.Comment{}) },
+		func() *gorm.DB { return db.Where("task_id NOT IN (?)", tasks).Delete(&model.TaskReaction{}) },
+		func() *gorm.DB { return db.Where("thread_id NOT IN (?)", threads).Delete(&model.ChatMessage{}) },
+		func() *gorm.DB {
+			return db.Where("evaluation_id NOT IN (?)", evaluations).Delete(&model.EvaluationDimension{})
+		},
+		func() *gorm.DB {
+			return db.Where("evaluation_id NOT IN (?)", evaluations).Delete(&model.EvaluationComment{})
+		},
+		func() *gorm.DB {
+			return db.Where("evaluation_id NOT IN (?)", evaluations).Delete(&model.EvaluationLike{})
+		},
+		func() *gorm.DB {
+			return db.Where("dimension_id NOT IN (?)", dimensions).Delete(&model.EvaluationMetric{})
+		},
+	}
+	for _, step := range steps {
+		res := step()
+		if res.Error != nil {
+			return res.Error
+		}
+		report.OrphanRows += res.RowsAffected
+	}
+	return nil
+}
+
+// deleteOrphanObjects 删除papers/和直传暂存路径uploads/下没有被引用的对象
+// 只处理创建超过OrphanAfter的对象，避免删除刚写入、论文记录尚未保存的文件
+func (g *StorageGC) deleteOrphanObjects(ctx context.Context, report *StorageGCReport) error {
+	if g.store == nil {
+		return nil
+	}
+	for _, prefix := range []string{paperFilePrefix, uploadStagingPrefix} {
+		if err := g.deleteOrphanObjectsUnder(ctx, prefix, report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteOrphanObjectsUnder 删除prefix下没有被引用的对象
+func (g *StorageGC) deleteOrphanObjectsUnder(ctx context.Context, prefix string, report *StorageGCReport) error {
+	cutoff := g.clock.Now().Add(-g.conf.OrphanAfter)
+	return g.store.List(ctx, prefix, func(obj storage.ObjectInfo) error {
+		report.ScannedObjects++
+		if obj.ModTime.After(cutoff) {
+			return nil
+		}
+		referenced, err := fileReferenced(g.db, obj.Key)
+		if err != nil || referenced {
+			return err
+		}
+		if err := g.store.Delete(ctx, obj.Key); err != nil {
+			g.logger.Warn("删除无主对象失败", zap.Error(err), zap.String("key", obj.Key))
+			return nil
+		}
+		report.OrphanObjects++
+		return nil
+	})
+}
+
+// Start 启动后台垃圾回收，按Interval定期执行，ctx取消后退出
+func (g *StorageGC) Start(ctx context.Context) {
+	if g.conf.Interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(g.conf.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			report, err := g.Run(ctx)
+			if err != nil {
+				g.logger.Error("存储垃圾回收失败", zap.Error(err))
+				continue
+			}
+			g.logger.Info("存储垃圾回收完成", zap.Int("purged_papers", report.PurgedPapers), zap.Int64("orphan_rows", report.OrphanRows),
+				zap.Int("orphan_objects", report.OrphanObjects), zap.Int("scanned_objects", report.ScannedObjects))
+		}
+	}()
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"papergraph/model"
+	"papergraph/storage"
+
+	"gorm.io/gorm"
+)
+
+func TestDeletePaperKeepsSharedFile(t *testing.T) {
+	db := newTestDB(t)
+	papers := newTestPaperService(t, db, nil)
+	alice, bob := createTestUser(t, db, "alice@example.com"), createTestUser(t, db, "bob@example.com")
+	first, _, err := papers.UploadAndCreateTask(alice.ID, spoolTestPDF(t, "shared.pdf", "Shared paper"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, err := papers.UploadAndCreateTask(bob.ID, spoolTestPDF(t, "shared.pdf", "Shared paper"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.OSSPath != second.OSSPath {
+		t.Fatalf("相同内容应复用存储对象: %s != %s", first.OSSPath, second.OSSPath)
+	}
+
+	ctx := context.Background()
+	if err := papers.DeletePaper(ctx, alice.ID, first.ID); err != nil {
+		t.Fatalf("删除论文失败: %v", err)
+	}
+	if _, err := papers.store.Stat(ctx, first.OSSPath); err != nil {
+		t.Fatalf("仍被引用的对象不应删除: %v", err)
+	}
+	if err := papers.DeletePaper(ctx, bob.ID, second.ID); err != nil {
+		t.Fatalf("删除论文失败: %v", err)
+	}
+	if _, err := papers.store.Stat(ctx, first.OSSPath); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("最后一个引用删除后应删除对象，实际为%v", err)
+	}
+}
+
+func TestDeletePaperDuringReuseKeepsFile(t *testing.T) {
+	db := newTestDB(t)
+	papers := newTestPaperService(t, db, nil)
+	alice, bob := createTestUser(t, db, "alice@example.com"), createTestUser(t, db, "bob@example.com")
+	original, _, err := papers.UploadAndCreateTask(alice.ID, spoolTestPDF(t, "shared.pdf", "Shared paper"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// bob查到可复用的对象后、写入论文记录前，alice删除了引用该对象的唯一一篇论文
+	var once sync.Once
+	deleted := make(chan error, 1)
+	db.Callback().Query().After("gorm:query").Register("test:delete_during_reuse", func(tx *gorm.DB) {
+		if tx.Statement.Table != "papers" || !strings.Contains(tx.Statement.SQL.String(), "oss_path <> ''") {
+			return
+		}
+		once.Do(func() {
+			go func() { deleted <- papers.DeletePaper(context.Background(), alice.ID, original.ID) }()
+			// 给删除足够的时间执行，引用检查应等待复用方的事务提交
+			select {
+			case err := <-deleted:
+				deleted <- err
+			case <-time.After(200 * time.Millisecond):
+			}
+		})
+	})
+	defer db.Callback().Query().Remove("test:delete_during_reuse")
+
+	reused, _, err := papers.UploadAndCreateTask(bob.ID, spoolTestPDF(t, "shared.pdf", "Shared paper"), false)
+	if err != nil {
+		t.Fatalf("上传论文失败: %v", err)
+	}
+	if err := <-deleted; err != nil {
+		t.Fatalf("删除论文失败: %v", err)
+	}
+	if _, err := papers.store.Stat(context.Background(), reused.OSSPath); err != nil {
+		t.Errorf("新论文引用的对象不应被删除: %v", err)
+	}
+	var count int64
+	db.Model(&model.Paper{}).Where("id = ?", original.ID).Count(&count)
+	if count != 0 {
+		t.Error("原论文应已删除")
+	}
+}
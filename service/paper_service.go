@@ -1,72 +1,203 @@
 package service
 
 import (
-	"errors"
+	"context"
 	"fmt"
-	"papergraph/config"
+	"papergraph/metrics"
 	"papergraph/model"
-	"papergraph/utils"
-	"time"
+	"papergraph/storage"
 
 	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 const MaxPDFSize = 20 * 1024 * 1024 // 20MB
 
 // PaperService 论文相关业务逻辑
-type PaperService struct{}
+type PaperService struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	store  storage.Storage // 论文文件使用的对象存储
+	tasks  TaskDispatcher  // 任务入队和取消时通知分析工作池，可为nil
+	model  AnalysisModel   // 默认模型，用于上传时查找可复用的分析结果
+	clock  Clock
+}
 
 // NewPaperService 创建PaperService实例
-func NewPaperService() *PaperService {
-	return &PaperService{}
+func NewPaperService(db *gorm.DB, logger *zap.Logger, store storage.Storage, tasks TaskDispatcher, model AnalysisModel, clock Clock) *PaperService {
+	return &PaperService{db: db, logger: logger, store: store, tasks: tasks, model: model, clock: clock}
 }
 
-// UploadAndCreateTask 上传PDF到OSS并创建分析任务
+// UploadAndCreateTask 上传PDF到对象存储并创建分析任务，任务创建后进入后台分析队列
+// 按文件SHA-256去重：本人重复上传时返回已有论文和任务；其他用户上传过的文件复用已存储的对象；
+// 相同文件已用默认模型和当前prompt版本分析过时，直接复用结果创建已完成的任务
 // userID: 当前用户ID
-// fileName: 文件名
-// fileData: 文件字节流
-// fileSize: 文件大小
-func (s *PaperService) UploadAndCreateTask(userID uint, fileName string, fileData []byte, fileSize int64) (*model.Paper, *model.AnalysisTask, error) {
-	config.Logger.Info("开始上传论文", zap.Uint("user_id", userID), zap.String("file_name", fileName), zap.Int64("file_size", fileSize))
-	if fileSize > MaxPDFSize {
-		config.Logger.Warn("文件过大", zap.Int64("file_size", fileSize))
-		return nil, nil, errors.New("文件大小不能超过20MB")
-	}
-	// 上传到OSS
-	ossPath, err := utils.UploadToOSS(fileName, fileData)
-	if err != nil {
-		config.Logger.Error("OSS上传失败", zap.Error(err))
-		return nil, nil, fmt.Errorf("OSS上传失败: %w", err)
+// upload: 已通过大小和结构校验的上传文件，见SpoolUpload
+// forceFresh: 是否跳过去重和缓存，强制重新分析
+func (s *PaperService) UploadAndCreateTask(userID uint, upload *SpooledUpload, forceFresh bool) (*model.Paper, *model.AnalysisTask, error) {
+	paper, task, _, err := s.uploadAndQueue(userID, upload, forceFresh)
+	return paper, task, err
+}
+
+// uploadAndQueue 同UploadAndCreateTask，queued表示是否新建了排队分析的任务，复用已有任务或缓存结果时为false
+func (s *PaperService) uploadAndQueue(userID uint, upload *SpooledUpload, forceFresh bool) (*model.Paper, *model.AnalysisTask, bool, error) {
+	fileName, fileSize, contentHash, pageCount := upload.Name, upload.Size, upload.Hash, upload.PageCount
+	s.logger.Info("开始上传论文", zap.Uint("user_id", userID), zap.String("file_name", fileName),
+		zap.Int64("file_size", fileSize), zap.Bool("force_fresh", forceFresh))
+	metrics.UploadBytes.Observe(float64(fileSize))
+	db := s.db
+
+	// 本人已上传过相同文件
+	var paper model.Paper
+	if err := db.Where("user_id = ? AND content_hash = ?", userID, contentHash).Order("id desc").Limit(1).Find(&paper).Error; err != nil {
+		s.logger.Error("查询重复论文失败", zap.Error(err))
+		return nil, nil, false, err
 	}
-	config.Logger.Info("OSS上传成功", zap.String("oss_path", ossPath))
-	// 保存论文记录
-	paper := model.Paper{
-		UserID:    userID,
-		FileName:  fileName,
-		OSSPath:   ossPath,
-		FileSize:  fileSize,
-		Status:    "分析中",
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+	if paper.ID != 0 && !forceFresh {
+		var task model.AnalysisTask
+		if err := db.Where("paper_id = ? AND user_id = ? AND status IN ?", paper.ID, userID,
+			[]string{model.TaskStatusQueued, model.TaskStatusRunning, model.TaskStatusCompleted}).
+			Order("id desc").Limit(1).Find(&task).Error; err != nil {
+			s.logger.Error("查询已有任务失败", zap.Error(err))
+			return nil, nil, false, err
+		}
+		if task.ID != 0 {
+			s.logger.Info("重复上传，复用已有论文和任务", zap.Uint("paper_id", paper.ID), zap.Uint("task_id", task.ID))
+			return &paper, &task, false, nil
+		}
 	}
-	db := config.DB
-	if err := db.Create(&paper).Error; err != nil {
-		config.Logger.Error("保存论文记录失败", zap.Error(err))
-		return nil, nil, err
+
+	if paper.ID == 0 {
+		// 保存论文记录
+		paper = model.Paper{
+			UserID:      userID,
+			FileName:    fileName,
+			FileSize:    fileSize,
+			PageCount:   pageCount,
+			ContentHash: contentHash,
+			Status:      "分析中",
+			CreatedAt:   s.clock.Now(),
+			UpdatedAt:   s.clock.Now(),
+		}
+		if err := s.createPaperWithFile(&paper, upload); err != nil {
+			return nil, nil, false, err
+		}
+		s.logger.Info("论文记录保存成功", zap.Uint("paper_id", paper.ID))
 	}
-	config.Logger.Info("论文记录保存成功", zap.Uint("paper_id", paper.ID))
+
 	// 创建分析任务
 	task := model.AnalysisTask{
-		UserID:    userID,
-		PaperID:   paper.ID,
-		Status:    "进行中",
-		IsPublic:  false,
-		CreatedAt: time.Now(),
+		UserID:     userID,
+		PaperID:    paper.ID,
+		Status:     model.TaskStatusQueued,
+		Stage:      model.TaskStageQueued,
+		ForceFresh: forceFresh,
+		IsPublic:   false,
+		CreatedAt:  s.clock.Now(),
+	}
+	if !forceFresh {
+		cached, err := findCachedResult(db, contentHash, s.model.Provider, s.model.Name, 0)
+		if err != nil {
+			s.logger.Warn("查询缓存结果失败，交由工作池分析", zap.Error(err))
+		} else if cached != nil {
+			if err := s.createCachedTask(&paper, &task, cached); err != nil {
+				s.logger.Error("复用缓存结果失败", zap.Error(err))
+				return &paper, nil, false, err
+			}
+			s.logger.Info("复用缓存分析结果，任务直接完成", zap.Uint("task_id", task.ID), zap.Uint("cached_result_id", cached.ID))
+			return &paper, &task, false, nil
+		}
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&task).Error; err != nil {
+			return err
+		}
+		return tx.Model(&paper).Updates(map[string]interface{}{"status": "分析中", "updated_at": s.clock.Now()}).Error
+	})
+	if err != nil {
+		s.logger.Error("创建分析任务失败", zap.Error(err))
+		return &paper, nil, false, err
+	}
+	notifyTasks(s.tasks)
+	s.logger.Info("分析任务创建成功，已加入分析队列", zap.Uint("task_id", task.ID))
+	return &paper, &task, true, nil
+}
+
+// createPaperWithFile 保存PDF文件并创建论文记录，已有相同内容的对象时直接复用其存储路径
+// 查找可复用的对象和创建论文记录在同一事务中完成，并锁定相同内容的论文记录，与删除论文时的引用检查串行执行，
+// 避免复用的对象在新论文记录写入前被删除，见purgePapersAndFiles
+func (s *PaperService) createPaperWithFile(paper *model.Paper, upload *SpooledUpload) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing model.Paper
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("content_hash = ? AND oss_path <> ''", paper.ContentHash).
+			Order("id desc").Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+		if existing.ID == 0 {
+			return nil
+		}
+		paper.OSSPath = existing.OSSPath
+		return tx.Create(paper).Error
+	})
+	if err != nil {
+		s.logger.Error("保存论文记录失败", zap.Error(err))
+		return err
+	}
+	if paper.ID != 0 {
+		s.logger.Info("复用已存储的相同文件", zap.String("oss_path", paper.OSSPath))
+		return nil
+	}
+
+	// 没有可复用的对象时上传到新路径，新路径不会被其他论文引用，不需要加锁
+	ossPath, err := s.putFile(context.Background(), upload.Name, upload.Reader(), upload.Size)
+	if err != nil {
+		s.logger.Error("文件上传失败", zap.Error(err))
+		return fmt.Errorf("文件上传失败: %w", err)
+	}
+	s.logger.Info("文件上传成功", zap.String("oss_path", ossPath))
+	paper.OSSPath = ossPath
+	if err := s.db.Create(paper).Error; err != nil {
+		s.logger.Error("保存论文记录失败", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// createCachedTask 用缓存的分析结果创建已完成的任务，结果、AI评价和论文状态在同一事务中写入
+func (s *PaperService) createCachedTask(paper *model.Paper, task *model.AnalysisTask, cached *model.AnalysisResult) error {
+	now := s.clock.Now()
+	task.Status = model.TaskStatusCompleted
+	task.Stage = model.TaskStageCompleted
+	task.Progress = 100
+	task.FinishedAt = &now
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(task).Error; err != nil {
+			return err
+		}
+		result := cachedResultCopy(cached, now)
+		result.TaskID = task.ID
+		result.Version = 1
+		if err := tx.Create(result).Error; err != nil {
+			return err
+		}
+		if _, err := NewEvaluationService(tx).CreateAIEvaluation(task, result); err != nil {
+			return err
+		}
+		paper.Status = "已完成"
+		paper.UpdatedAt = now
+		return tx.Model(paper).Updates(map[string]interface{}{"status": paper.Status, "updated_at": now}).Error
+	})
+}
+
+// paperAccessible 判断用户能否使用论文：论文上传者，或论文有公开的分析任务
+func paperAccessible(db *gorm.DB, userID uint, paper *model.Paper) (bool, error) {
+	if paper.UserID == userID {
+		return true, nil
 	}
-	if err := db.Create(&task).Error; err != nil {
-		config.Logger.Error("创建分析任务失败", zap.Error(err))
-		return &paper, nil, err
+	var public int64
+	if err := db.Model(&model.AnalysisTask{}).Where("paper_id = ? AND is_public = ?", paper.ID, true).Count(&public).Error; err != nil {
+		return false, err
 	}
-	config.Logger.Info("分析任务创建成功", zap.Uint("task_id", task.ID))
-	return &paper, &task, nil
+	return public > 0, nil
 }
@@ -91,15 +91,15 @@ func (s *SocialService) UnfollowUser(followerID, followingID uint) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
 		// 更新关注者的关注数量
 		if err := tx.Model(&model.UserStats{}).
-			Where("user_id = ?", followerID).
-			UpdateColumn("following_count", gorm.Expr("GREATEST(following_count - 1, 0)")).Error; err != nil {
+			Where("user_id = ? AND following_count > 0", followerID).
+			UpdateColumn("following_count", gorm.Expr("following_count - ?", 1)).Error; err != nil {
 			return err
 		}
 
 		// 更新被关注者的粉丝数量
 		if err := tx.Model(&model.UserStats{}).
-			Where("user_id = ?", followingID).
-			UpdateColumn("follower_count", gorm.Expr("GREATEST(follower_count - 1, 0)")).Error; err != nil {
+			Where("user_id = ? AND follower_count > 0", followingID).
+			UpdateColumn("follower_count", gorm.Expr("follower_count - ?", 1)).Error; err != nil {
 			return err
 		}
 
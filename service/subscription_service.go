@@ -1,6 +1,9 @@
 package service
 
 import (
+	"errors"
+	"fmt"
+	"papergraph/metrics"
 	"papergraph/model"
 	"time"
 
@@ -50,6 +53,7 @@ func (s *SubscriptionService) CreateUserSubscription(userID, productID uint, dur
 	if err := s.db.First(&product, productID).Error; err != nil {
 		return err
 	}
+	metrics.SubscriptionPurchases.WithLabelValues(product.Name).Inc()
 	
 	// 颁发订阅相关奖章
 	if err := s.badgeService.AwardSubscriptionBadge(userID, product.Name); err != nil {
@@ -72,6 +76,49 @@ func (s *SubscriptionService) GetFreeTrialCount(userID uint) (int, error) {
 	return user.FreeTrialCount, err
 }
 
+// ErrAnalysisQuotaExceeded 免费试用次数不足
+var ErrAnalysisQuotaExceeded = errors.New("免费试用次数不足，请订阅后继续使用")
+
+// 查询用户是否有有效订阅
+func (s *SubscriptionService) HasActiveSubscription(userID uint) (bool, error) {
+	var count int64
+	err := s.db.Model(&model.UserSubscription{}).
+		Where("user_id = ? AND status = ? AND end_time > ?", userID, "active", time.Now()).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// 预扣n次分析额度，返回实际扣减的免费试用次数
+// 有效订阅用户不限次数；免费用户剩余次数不足n次时整体拒绝，不做部分扣减
+func (s *SubscriptionService) ReserveAnalysisQuota(userID uint, n int) (int, error) {
+	subscribed, err := s.HasActiveSubscription(userID)
+	if err != nil {
+		return 0, err
+	}
+	if subscribed || n <= 0 {
+		return 0, nil
+	}
+	res := s.db.Model(&model.User{}).Where("id = ? AND free_trial_count >= ?", userID, n).
+		UpdateColumn("free_trial_count", gorm.Expr("free_trial_count - ?", n))
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	if res.RowsAffected == 0 {
+		remaining, _ := s.GetFreeTrialCount(userID)
+		return 0, fmt.Errorf("%w（本次需要%d次，剩余%d次）", ErrAnalysisQuotaExceeded, n, remaining)
+	}
+	return n, nil
+}
+
+// 退还预扣的免费试用次数
+func (s *SubscriptionService) RefundFreeTrial(userID uint, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	return s.db.Model(&model.User{}).Where("id = ?", userID).
+		UpdateColumn("free_trial_count", gorm.Expr("free_trial_count + ?", n)).Error
+}
+
 // 写入支付记录
 func (s *SubscriptionService) CreatePaymentRecord(rec *model.PaymentRecord) error {
 	return s.db.Create(rec).Error
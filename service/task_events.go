@@ -0,0 +1,136 @@
+package service
+
+import (
+	"papergraph/model"
+	"sync"
+	"time"
+)
+
+// taskEventBuffer 每个订阅者的事件缓冲数
+const taskEventBuffer = 32
+
+// TaskEvent 分析任务状态变化事件
+type TaskEvent struct {
+	TaskID   uint      `json:"task_id"`         // 任务ID
+	Status   string    `json:"status"`          // 任务状态
+	Stage    string    `json:"stage"`           // 当前阶段
+	Progress int       `json:"progress"`        // 进度百分比（0-100）
+	Error    string    `json:"error,omitempty"` // 失败原因
+	Time     time.Time `json:"time"`            // 事件时间
+}
+
+// IsTerminal 是否为终态事件，终态后不会再有新事件
+func (e TaskEvent) IsTerminal() bool {
+	return e.Status == model.TaskStatusCompleted || e.Status == model.TaskStatusFailed || e.Status == model.TaskStatusCanceled
+}
+
+// TaskEventFromTask 根据任务当前状态生成事件，事件时间取自clock
+func TaskEventFromTask(task *model.AnalysisTask, clock Clock) TaskEvent {
+	return TaskEvent{
+		TaskID:   task.ID,
+		Status:   task.Status,
+		Stage:    task.Stage,
+		Progress: task.Progress,
+		Error:    task.LastError,
+		Time:     clock.Now(),
+	}
+}
+
+// TaskEventBus 进程内任务事件发布订阅
+// 同一任务可以有多个订阅者；订阅者消费过慢时丢弃最旧的事件，不阻塞工作池
+type TaskEventBus struct {
+	clock  Clock
+	mu     sync.Mutex
+	subs   map[uint]map[chan TaskEvent]struct{}
+	closed bool
+}
+
+// NewTaskEventBus 创建任务事件总线，clock用于为发布时未指定时间的事件补充时间
+func NewTaskEventBus(clock Clock) *TaskEventBus {
+	return &TaskEventBus{clock: clock, subs: make(map[uint]map[chan TaskEvent]struct{})}
+}
+
+// Subscribe 订阅指定任务的事件，返回事件通道和取消订阅函数
+// 取消订阅后通道会被关闭，取消函数可重复调用
+func (b *TaskEventBus) Subscribe(taskID uint) (<-chan TaskEvent, func()) {
+	ch := make(chan TaskEvent, taskEventBuffer)
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+	if b.subs[taskID] == nil {
+		b.subs[taskID] = make(map[chan TaskEvent]struct{})
+	}
+	b.subs[taskID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if _, ok := b.subs[taskID][ch]; !ok {
+				// 已由Close关闭
+				return
+			}
+			delete(b.subs[taskID], ch)
+			if len(b.subs[taskID]) == 0 {
+				delete(b.subs, taskID)
+			}
+			close(ch)
+		})
+	}
+}
+
+// Publish 向任务的所有订阅者发布事件
+func (b *TaskEventBus) Publish(event TaskEvent) {
+	if b == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = b.clock.Now()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[event.TaskID] {
+		select {
+		case ch <- event:
+		default:
+			// 缓冲已满，丢弃最旧的事件后重试，保证最新状态能送达
+			select {
+			case <-ch:
+			default:
+			}
+			select {
+			case ch <- event:
+			default:
+			}
+		}
+	}
+}
+
+// Close 关闭所有订阅者的事件通道，之后的订阅会得到已关闭的通道
+// 服务退出时调用，使SSE连接结束，客户端重连到其他实例
+func (b *TaskEventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for _, subs := range b.subs {
+		for ch := range subs {
+			close(ch)
+		}
+	}
+	b.subs = make(map[uint]map[chan TaskEvent]struct{})
+}
+
+// SubscriberCount 返回指定任务当前的订阅者数量
+func (b *TaskEventBus) SubscriberCount(taskID uint) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs[taskID])
+}
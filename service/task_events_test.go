@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"papergraph/aitools"
+	"papergraph/config"
+	"papergraph/model"
+
+	"go.uber.org/zap"
+)
+
+func TestTaskEventsPublishAnalyzingWithoutUpload(t *testing.T) {
+	db := newTestDB(t)
+	user := createTestUser(t, db, "events@example.com")
+	events := NewTaskEventBus(SystemClock{})
+	worker := NewAnalysisWorker(db, zap.NewNop(), config.Default(config.ProfileTest).AnalysisWorker, events, SystemClock{})
+	papers := newTestPaperService(t, db, worker)
+	_, task, err := papers.UploadAndCreateTask(user.ID, spoolTestPDF(t, "events.pdf", usableTestText), false)
+	if err != nil {
+		t.Fatalf("上传论文失败: %v", err)
+	}
+
+	ch, unsubscribe := events.Subscribe(task.ID)
+	defer unsubscribe()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	worker.Start(ctx, NewAnalysisPipeline(textOnlyProvider{aitools.NewFakeProvider()}, nil, papers))
+	defer worker.Shutdown(context.Background())
+
+	var stages []string
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case event := <-ch:
+			stages = append(stages, event.Stage)
+			if !event.IsTerminal() {
+				continue
+			}
+			if event.Status != model.TaskStatusCompleted {
+				t.Fatalf("分析任务未完成: %+v", event)
+			}
+			for i, stage := range stages {
+				if stage == model.TaskStageUploading {
+					t.Errorf("模型未上传PDF时不应推送上传阶段，实际为%v", stages)
+				}
+				if stage == model.TaskStageAnalyzing {
+					if i+1 < len(stages) && stages[i+1] == model.TaskStageSaving {
+						return
+					}
+				}
+			}
+			t.Fatalf("保存结果前应推送分析阶段事件，实际为%v", stages)
+		case <-timeout:
+			t.Fatalf("等待分析任务完成超时，已收到%v", stages)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"papergraph/aitools"
+	"papergraph/config"
+	"papergraph/model"
+	"papergraph/storage"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// newTestDB 按test配置创建已迁移的内存数据库
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	cfg := config.Default(config.ProfileTest)
+	db, err := config.InitDatabase(&cfg, zap.NewNop())
+	if err != nil {
+		t.Fatalf("初始化测试数据库失败: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// createTestUser 创建测试用户
+func createTestUser(t *testing.T, db *gorm.DB, email string) *model.User {
+	t.Helper()
+	user := &model.User{Email: email, Gmail: email, Name: "tester"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	return user
+}
+
+// newTestPaperService 创建使用本地存储和fake模型的论文服务
+func newTestPaperService(t *testing.T, db *gorm.DB, tasks TaskDispatcher) *PaperService {
+	t.Helper()
+	store, err := storage.NewLocalStorage(t.TempDir(), "http://localhost", "test-signing-key")
+	if err != nil {
+		t.Fatalf("创建本地存储失败: %v", err)
+	}
+	defaultModel := AnalysisModel{Provider: aitools.ProviderFake, Name: aitools.FakeModel}
+	return NewPaperService(db, zap.NewNop(), store, tasks, defaultModel, SystemClock{})
+}
+
+// spoolTestPDF 把测试PDF写入临时文件，测试结束时删除
+func spoolTestPDF(t *testing.T, name, text string) *SpooledUpload {
+	t.Helper()
+	upload, err := SpoolUpload(name, bytes.NewReader(testPDF(text)))
+	if err != nil {
+		t.Fatalf("暂存测试PDF失败: %v", err)
+	}
+	t.Cleanup(func() { upload.Close() })
+	return upload
+}
+
+// testPDF 生成一页包含指定英文文本的最小PDF
+func testPDF(text string) []byte {
+	content := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+
+	"papergraph/utils"
+)
+
+// 上传文件错误
+var (
+	ErrFileTooLarge = errors.New("文件大小不能超过20MB")
+	ErrFileEmpty    = errors.New("文件为空")
+)
+
+// SpooledUpload 已写入临时文件并通过校验的上传文件
+// 上传内容边接收边写入临时文件并计算SHA-256，不在内存中保留整个文件；使用完后需调用Close删除临时文件
+type SpooledUpload struct {
+	Name      string // 原始文件名
+	Size      int64  // 文件大小（字节）
+	Hash      string // 文件内容SHA-256
+	PageCount int    // PDF页数
+	file      *os.File
+}
+
+// SpoolUpload 将上传内容流式写入临时文件并校验
+// 先检查PDF文件头，不是PDF时不再继续读取；超过MaxPDFSize时立即停止；写完后校验PDF结构，拒绝加密或损坏的文件
+func SpoolUpload(name string, r io.Reader) (*SpooledUpload, error) {
+	head := make([]byte, 1024)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	head = head[:n]
+	if n == 0 {
+		return nil, ErrFileEmpty
+	}
+	if !utils.HasPDFHeader(head) {
+		return nil, utils.ErrNotPDF
+	}
+
+	f, err := os.CreateTemp("", "papergraph-upload-*.pdf")
+	if err != nil {
+		return nil, err
+	}
+	upload := &SpooledUpload{Name: name, file: f}
+	hash := sha256.New()
+	w := io.MultiWriter(f, hash)
+	written, err := io.Copy(w, io.LimitReader(io.MultiReader(bytes.NewReader(head), r), MaxPDFSize+1))
+	if err != nil {
+		upload.Close()
+		return nil, err
+	}
+	if written > MaxPDFSize {
+		upload.Close()
+		return nil, ErrFileTooLarge
+	}
+	upload.Size = written
+	upload.Hash = hex.EncodeToString(hash.Sum(nil))
+
+	if upload.PageCount, err = utils.ValidatePDFReader(f, written); err != nil {
+		upload.Close()
+		return nil, err
+	}
+	return upload, nil
+}
+
+// Reader 从头读取文件内容，每次调用返回独立的读取位置
+func (u *SpooledUpload) Reader() io.Reader {
+	return io.NewSectionReader(u.file, 0, u.Size)
+}
+
+// Close 关闭并删除临时文件，可重复调用
+func (u *SpooledUpload) Close() error {
+	if u == nil || u.file == nil {
+		return nil
+	}
+	u.file.Close()
+	err := os.Remove(u.file.Name())
+	u.file = nil
+	return err
+}
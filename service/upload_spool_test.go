@@ -0,0 +1,97 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"papergraph/utils"
+)
+
+// countingReader 记录已读取的字节数
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// encryptedTestPDF 在测试PDF的trailer中加入标准加密字段
+func encryptedTestPDF() []byte {
+	entry := "<" + strings.Repeat("ab", 32) + ">"
+	encrypt := "/Root 1 0 R /Encrypt << /Filter /Standard /V 1 /R 2 /O " + entry + " /U " + entry + " /P -4 >> /ID [<01> <01>]"
+	return bytes.Replace(testPDF("secret"), []byte("/Root 1 0 R"), []byte(encrypt), 1)
+}
+
+func TestSpoolUploadValidation(t *testing.T) {
+	valid := testPDF("Spooled paper")
+	tests := []struct {
+		name     string
+		r        io.Reader
+		wantErr  error
+		maxRead  int64 // 出错前最多读取的字节数，0表示不检查
+		wantPage int
+	}{
+		{"有效PDF", bytes.NewReader(valid), nil, 0, 1},
+		{"空文件", bytes.NewReader(nil), ErrFileEmpty, 0, 0},
+		{"文件头错误", io.MultiReader(strings.NewReader("PK\x03\x04"), zeroReader{}), utils.ErrNotPDF, 1024, 0},
+		{"加密PDF", bytes.NewReader(encryptedTestPDF()), utils.ErrPDFEncrypted, 0, 0},
+		{"截断", bytes.NewReader(valid[:len(valid)-200]), utils.ErrPDFCorrupt, 0, 0},
+		{"结构损坏", bytes.NewReader(append([]byte("%PDF-1.4\n"), bytes.Repeat([]byte("garbage "), 100)...)), utils.ErrPDFCorrupt, 0, 0},
+		// 超过上限后立即停止读取，不会把无限长的请求体读完
+		{"超过大小上限", io.MultiReader(strings.NewReader("%PDF-1.4\n"), zeroReader{}), ErrFileTooLarge, MaxPDFSize + 64*1024, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 暂存文件写入独立的临时目录，便于检查校验失败后是否删除
+			t.Setenv("TMPDIR", t.TempDir())
+			r := &countingReader{r: tt.r}
+			upload, err := SpoolUpload("paper.pdf", r)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("暂存失败: %v", err)
+				}
+				defer upload.Close()
+				if upload.PageCount != tt.wantPage || upload.Size != int64(len(valid)) || upload.Hash != utils.ContentHash(valid) {
+					t.Errorf("暂存结果错误: pages=%d, size=%d", upload.PageCount, upload.Size)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("期望错误%v，实际为%v", tt.wantErr, err)
+			}
+			if tt.maxRead > 0 && r.n > tt.maxRead {
+				t.Errorf("出错前最多读取%d字节，实际读取%d字节", tt.maxRead, r.n)
+			}
+			if n := spooledTempFiles(t); n > 0 {
+				t.Errorf("校验失败后应删除临时文件，残留%d个", n)
+			}
+		})
+	}
+}
+
+// zeroReader 无限长的零字节流
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// spooledTempFiles 统计临时目录中的上传暂存文件数
+func spooledTempFiles(t *testing.T) int {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(os.TempDir(), "papergraph-upload-*.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(matches)
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"papergraph/config"
+	"papergraph/model"
+	"papergraph/storage"
+	"papergraph/utils"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 直传错误
+var (
+	ErrUploadTicketNotFound = errors.New("上传票据不存在")
+	ErrUploadTicketExpired  = errors.New("上传票据已过期，请重新申请")
+	ErrUploadObjectMissing  = errors.New("文件尚未上传完成")
+	ErrUploadSizeMismatch   = errors.New("上传的文件大小与申请时不一致")
+	ErrUploadHashMismatch   = errors.New("上传的文件内容与申请时的SHA-256不一致")
+)
+
+// uploadContentType 直传地址要求的Content-Type，客户端上传时必须使用相同的值
+const uploadContentType = "application/pdf"
+
+// uploadStagingPrefix 直传暂存对象的路径前缀
+// 上传地址在有效期内可以重复使用，因此只签发暂存路径；确认时把校验过的内容另存为论文文件，客户端之后再上传也不会影响论文文件
+const uploadStagingPrefix = "uploads/"
+
+var contentHashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// UploadTicketService 浏览器直传对象存储的上传票据
+// 流程：申请票据得到暂存路径的限时上传地址 → 客户端PUT文件到对象存储 → 确认票据，服务端校验对象，
+// 将校验过的内容写入新的论文文件路径并删除暂存对象，再创建论文和分析任务
+type UploadTicketService struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	store  storage.Storage // 论文文件使用的对象存储
+	papers *PaperService   // 确认票据后创建论文和分析任务
+	conf   config.UploadTicketConfig
+	clock  Clock
+}
+
+// NewUploadTicketService 创建UploadTicketService实例，store需与papers使用同一个对象存储
+func NewUploadTicketService(db *gorm.DB, logger *zap.Logger, store storage.Storage, papers *PaperService, conf config.UploadTicketConfig, clock Clock) *UploadTicketService {
+	return &UploadTicketService{db: db, logger: logger, store: store, papers: papers, conf: conf, clock: clock}
+}
+
+// UploadTicketResult 签发的上传票据和上传地址
+type UploadTicketResult struct {
+	Ticket    *model.UploadTicket `json:"ticket"`
+	UploadURL string              `json:"upload_url"` // 限时上传地址
+	Method    string              `json:"method"`     // 上传使用的HTTP方法
+	Headers   map[string]string   `json:"headers"`    // 上传时必须携带的请求头
+}
+
+// CreateTicket 申请上传票据
+// fileSize和contentHash为客户端声明的文件大小和SHA-256，确认时与实际上传的对象比对
+func (s *UploadTicketService) CreateTicket(ctx context.Context, userID uint, fileName string, fileSize int64, contentHash string) (*UploadTicketResult, error) {
+	if s.store == nil {
+		return nil, errors.New("文件存储未初始化")
+	}
+	if fileSize <= 0 {
+		return nil, ErrFileEmpty
+	}
+	if fileSize > MaxPDFSize {
+		return nil, ErrFileTooLarge
+	}
+	contentHash = strings.ToLower(strings.TrimSpace(contentHash))
+	if !contentHashPattern.MatchString(contentHash) {
+		return nil, errors.New("content_hash必须是文件内容的SHA-256（64位十六进制）")
+	}
+
+	ticket := &model.UploadTicket{
+		Token:       utils.GenerateRandomHex(32),
+		UserID:      userID,
+		FileName:    truncateRunes(fileName, 256),
+		ObjectKey:   uploadStagingPrefix + utils.GenerateRandomHex(16) + ".pdf",
+		FileSize:    fileSize,
+		ContentHash: contentHash,
+		Status:      model.UploadTicketPending,
+		ExpiresAt:   s.clock.Now().Add(s.conf.TTL),
+	}
+	uploadURL, err := s.store.PresignPut(ctx, ticket.ObjectKey, s.conf.TTL, uploadContentType, fileSize)
+	if err != nil {
+		s.logger.Error("生成上传地址失败", zap.Error(err), zap.String("object_key", ticket.ObjectKey))
+		return nil, fmt.Errorf("生成上传地址失败: %w", err)
+	}
+	if err := s.db.Create(ticket).Error; err != nil {
+		s.logger.Error("保存上传票据失败", zap.Error(err))
+		return nil, err
+	}
+	s.logger.Info("签发上传票据", zap.Uint("user_id", userID), zap.Uint("ticket_id", ticket.ID), zap.String("object_key", ticket.ObjectKey))
+	return &UploadTicketResult{
+		Ticket:    ticket,
+		UploadURL: uploadURL,
+		Method:    "PUT",
+		Headers:   map[string]string{"Content-Type": uploadContentType, "Content-Length": strconv.FormatInt(fileSize, 10)},
+	}, nil
+}
+
+// CompleteTicket 确认上传完成，校验对象的大小、SHA-256和PDF结构后创建论文和分析任务
+// 论文文件使用校验时读取到本地的内容写入新路径，不引用暂存对象，确认后暂存对象即被删除；
+// 对象尚未上传时返回ErrUploadObjectMissing，票据保持有效可重试；校验失败时删除对象并作废票据；
+// 已确认的票据重复确认时返回之前创建的论文和任务
+func (s *UploadTicketService) CompleteTicket(ctx context.Context, userID uint, token string, forceFresh bool) (*model.Paper, *model.AnalysisTask, error) {
+	db := s.db
+	var ticket model.UploadTicket
+	if err := db.Where("token = ? AND user_id = ?", token, userID).Limit(1).Find(&ticket).Error; err != nil {
+		return nil, nil, err
+	}
+	if ticket.ID == 0 {
+		return nil, nil, ErrUploadTicketNotFound
+	}
+	switch ticket.Status {
+	case model.UploadTicketCompleted:
+		return s.completedResult(&ticket)
+	case model.UploadTicketRejected:
+		return nil, nil, errors.New(ticket.Error)
+	case model.UploadTicketExpired:
+		return nil, nil, ErrUploadTicketExpired
+	}
+	if s.clock.Now().After(ticket.ExpiresAt) {
+		return nil, nil, ErrUploadTicketExpired
+	}
+
+	info, err := s.store.Stat(ctx, ticket.ObjectKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, ErrUploadObjectMissing
+	}
+	if err != nil {
+		s.logger.Error("查询上传对象失败", zap.Error(err), zap.String("object_key", ticket.ObjectKey))
+		return nil, nil, err
+	}
+	if info.Size != ticket.FileSize {
+		return nil, nil, s.reject(ctx, &ticket, ErrUploadSizeMismatch)
+	}
+
+	body, err := s.store.Get(ctx, ticket.ObjectKey)
+	if err != nil {
+		s.logger.Error("读取上传对象失败", zap.Error(err), zap.String("object_key", ticket.ObjectKey))
+		return nil, nil, err
+	}
+	upload, err := SpoolUpload(ticket.FileName, body)
+	body.Close()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		return nil, nil, s.reject(ctx, &ticket, err)
+	}
+	defer upload.Close()
+	if upload.Hash != ticket.ContentHash {
+		return nil, nil, s.reject(ctx, &ticket, ErrUploadHashMismatch)
+	}
+
+	paper, task, err := s.papers.UploadAndCreateTask(userID, upload, forceFresh)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.store.Delete(ctx, ticket.ObjectKey); err != nil {
+		// 未删除的暂存对象由存储垃圾回收清理
+		s.logger.Warn("删除暂存的上传对象失败", zap.Error(err), zap.String("object_key", ticket.ObjectKey))
+	}
+	updates := map[string]interface{}{"status": model.UploadTicketCompleted, "paper_id": paper.ID, "updated_at": s.clock.Now()}
+	if task != nil {
+		updates["task_id"] = task.ID
+	}
+	if err := db.Model(&ticket).Updates(updates).Error; err != nil {
+		s.logger.Error("更新上传票据失败", zap.Error(err), zap.Uint("ticket_id", ticket.ID))
+	}
+	s.logger.Info("直传确认完成", zap.Uint("ticket_id", ticket.ID), zap.Uint("paper_id", paper.ID))
+	return paper, task, nil
+}
+
+// completedResult 返回已确认票据创建的论文和任务
+func (s *UploadTicketService) completedResult(ticket *model.UploadTicket) (*model.Paper, *model.AnalysisTask, error) {
+	var paper model.Paper
+	if err := s.db.First(&paper, ticket.PaperID).Error; err != nil {
+		return nil, nil, err
+	}
+	var task model.AnalysisTask
+	if err := s.db.First(&task, ticket.TaskID).Error; err != nil {
+		return nil, nil, err
+	}
+	return &paper, &task, nil
+}
+
+// reject 作废票据并删除未通过校验的对象，返回原始错误
+func (s *UploadTicketService) reject(ctx context.Context, ticket *model.UploadTicket, cause error) error {
+	s.logger.Warn("直传文件校验失败", zap.Error(cause), zap.Uint("ticket_id", ticket.ID))
+	if err := s.store.Delete(ctx, ticket.ObjectKey); err != nil {
+		s.logger.Warn("删除未通过校验的对象失败", zap.Error(err), zap.String("object_key", ticket.ObjectKey))
+	}
+	if err := s.db.Model(ticket).Updates(map[string]interface{}{
+		"status":     model.UploadTicketRejected,
+		"error":      truncateRunes(cause.Error(), 512),
+		"updated_at": s.clock.Now(),
+	}).Error; err != nil {
+		s.logger.Error("更新上传票据失败", zap.Error(err), zap.Uint("ticket_id", ticket.ID))
+	}
+	return cause
+}
+
+// CleanupExpired 清理过期未确认的票据，删除客户端已上传但未确认的对象，返回清理的票据数
+// 票据过期超过CleanupGrace才清理，避免删除过期前开始、仍在校验中的对象；已被论文引用的对象不删除
+func (s *UploadTicketService) CleanupExpired(ctx context.Context) (int, error) {
+	db := s.db
+	var tickets []model.UploadTicket
+	if err := db.Where("status = ? AND expires_at < ?", model.UploadTicketPending, s.clock.Now().Add(-s.conf.CleanupGrace)).
+		Order("id asc").Limit(500).Find(&tickets).Error; err != nil {
+		return 0, err
+	}
+	cleaned := 0
+	for i := range tickets {
+		ticket := &tickets[i]
+		var referenced int64
+		if err := db.Unscoped().Model(&model.Paper{}).Where("oss_path = ?", ticket.ObjectKey).Count(&referenced).Error; err != nil {
+			return cleaned, err
+		}
+		if referenced == 0 {
+			if err := s.store.Delete(ctx, ticket.ObjectKey); err != nil {
+				s.logger.Warn("删除过期的上传对象失败", zap.Error(err), zap.String("object_key", ticket.ObjectKey))
+				continue
+			}
+		}
+		if err := db.Model(ticket).Where("status = ?", model.UploadTicketPending).
+			Updates(map[string]interface{}{"status": model.UploadTicketExpired, "updated_at": s.clock.Now()}).Error; err != nil {
+			return cleaned, err
+		}
+		cleaned++
+	}
+	return cleaned, nil
+}
+
+// StartJanitor 启动后台清理，按CleanupInterval定期清理过期票据，ctx取消后退出
+func (s *UploadTicketService) StartJanitor(ctx context.Context) {
+	if s.store == nil || s.conf.CleanupInterval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(s.conf.CleanupInterval)
+		defer ticker.Stop()
+		for {
+			if cleaned, err := s.CleanupExpired(ctx); err != nil {
+				s.logger.Error("清理过期上传票据失败", zap.Error(err))
+			} else if cleaned > 0 {
+				s.logger.Info("已清理过期上传票据", zap.Int("count", cleaned))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
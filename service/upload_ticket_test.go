@@ -0,0 +1,95 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"papergraph/config"
+	"papergraph/storage"
+
+	"go.uber.org/zap"
+)
+
+// putPresigned 按签发的上传地址把内容PUT到本地存储
+func putPresigned(t *testing.T, store *storage.LocalStorage, result *UploadTicketResult, body []byte, contentLength int64) int {
+	t.Helper()
+	u, err := url.Parse(result.UploadURL)
+	if err != nil {
+		t.Fatalf("解析上传地址失败: %v", err)
+	}
+	req := httptest.NewRequest(result.Method, u.RequestURI(), bytes.NewReader(body))
+	req.Header.Set("Content-Type", result.Headers["Content-Type"])
+	req.ContentLength = contentLength
+	rec := httptest.NewRecorder()
+	store.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestCompleteTicketStoresVerifiedCopyUnderFreshKey(t *testing.T) {
+	db := newTestDB(t)
+	user := createTestUser(t, db, "ticket@example.com")
+	papers := newTestPaperService(t, db, nil)
+	store := papers.store.(*storage.LocalStorage)
+	tickets := NewUploadTicketService(db, zap.NewNop(), store, papers, config.Default(config.ProfileTest).UploadTicket, SystemClock{})
+	ctx := context.Background()
+
+	pdf := testPDF("Presigned upload")
+	sum := sha256.Sum256(pdf)
+	result, err := tickets.CreateTicket(ctx, user.ID, "paper.pdf", int64(len(pdf)), hex.EncodeToString(sum[:]))
+	if err != nil {
+		t.Fatalf("申请上传票据失败: %v", err)
+	}
+	stagingKey := result.Ticket.ObjectKey
+	if !strings.HasPrefix(stagingKey, uploadStagingPrefix) {
+		t.Fatalf("上传地址应指向暂存路径，实际为%s", stagingKey)
+	}
+
+	// 签名限定了内容长度，长度不符的上传被拒绝
+	if code := putPresigned(t, store, result, append(pdf, ' '), int64(len(pdf)+1)); code != http.StatusBadRequest {
+		t.Fatalf("长度不符的上传应返回400，实际为%d", code)
+	}
+	if code := putPresigned(t, store, result, pdf, int64(len(pdf))); code != http.StatusOK {
+		t.Fatalf("上传失败，状态码%d", code)
+	}
+
+	paper, task, err := tickets.CompleteTicket(ctx, user.ID, result.Ticket.Token, false)
+	if err != nil {
+		t.Fatalf("确认上传失败: %v", err)
+	}
+	if task == nil {
+		t.Fatal("确认后应创建分析任务")
+	}
+	if paper.OSSPath == stagingKey || !strings.HasPrefix(paper.OSSPath, paperFilePrefix) {
+		t.Fatalf("论文文件应保存到新的论文路径，实际为%s", paper.OSSPath)
+	}
+	if _, err := store.Stat(ctx, stagingKey); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("确认后暂存对象应被删除，err=%v", err)
+	}
+
+	// 上传地址仍在有效期内，再次上传只会写入暂存路径，不影响已校验的论文文件
+	tampered := bytes.Repeat([]byte{'x'}, len(pdf))
+	if code := putPresigned(t, store, result, tampered, int64(len(tampered))); code != http.StatusOK {
+		t.Fatalf("重复上传失败，状态码%d", code)
+	}
+	body, err := store.Get(ctx, paper.OSSPath)
+	if err != nil {
+		t.Fatalf("读取论文文件失败: %v", err)
+	}
+	defer body.Close()
+	stored, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("读取论文文件失败: %v", err)
+	}
+	if !bytes.Equal(stored, pdf) {
+		t.Fatal("论文文件被再次上传的内容覆盖")
+	}
+}
@@ -0,0 +1,272 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalRoutePrefix 本地存储签名地址的路由前缀，需要在HTTP服务中挂载LocalStorage
+const LocalRoutePrefix = "/storage/"
+
+// LocalStorage 本地文件系统存储，用于开发和CI环境
+// 签名地址指向本服务的LocalRoutePrefix路由，由LocalStorage.ServeHTTP校验签名后读写文件
+type LocalStorage struct {
+	dir         string
+	baseURL     string
+	signingKey  []byte
+	MaxPutBytes int64 // 通过签名地址上传的最大字节数，0表示不限制
+}
+
+// NewLocalStorage 创建本地文件系统存储，dir不存在时自动创建
+func NewLocalStorage(dir, baseURL, signingKey string) (*LocalStorage, error) {
+	if dir == "" {
+		return nil, errors.New("本地存储目录未配置")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建本地存储目录失败: %w", err)
+	}
+	key := []byte(signingKey)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+	return &LocalStorage{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/"), signingKey: key}, nil
+}
+
+// filePath 对象路径对应的本地文件路径
+func (s *LocalStorage) filePath(key string) (string, error) {
+	cleaned, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(cleaned)), nil
+}
+
+// Put 写入对象，先写临时文件再重命名，读取方不会看到写了一半的文件
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	name, err := s.filePath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// Get 读取对象
+func (s *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	name, err := s.filePath(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete 删除对象
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	name, err := s.filePath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Stat 查询对象元信息，Content-Type按扩展名推断
+func (s *LocalStorage) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	name, err := s.filePath(key)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(name)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && fi.IsDir()) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectInfo{Key: key, Size: fi.Size(), ContentType: mime.TypeByExtension(path.Ext(key)), ModTime: fi.ModTime()}, nil
+}
+
+// List 遍历存储目录下的文件，跳过写入中的临时文件
+func (s *LocalStorage) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	err := filepath.WalkDir(s.dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		rel, err := filepath.Rel(s.dir, name)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if d.IsDir() {
+			// 跳过与前缀不相交的目录
+			if key != "." && !strings.HasPrefix(key+"/", prefix) && !strings.HasPrefix(prefix, key+"/") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasPrefix(key, prefix) || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(ObjectInfo{Key: key, Size: fi.Size(), ContentType: mime.TypeByExtension(path.Ext(key)), ModTime: fi.ModTime()})
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// PresignGet 生成限时下载地址
+func (s *LocalStorage) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return s.presign(http.MethodGet, key, expires, "", 0)
+}
+
+// PresignPut 生成限时上传地址，size大于0时上传内容的长度必须与之相同
+func (s *LocalStorage) PresignPut(ctx context.Context, key string, expires time.Duration, contentType string, size int64) (string, error) {
+	return s.presign(http.MethodPut, key, expires, contentType, size)
+}
+
+// presign 生成签名地址，签名覆盖请求方法、对象路径、过期时间、上传的Content-Type和内容长度
+func (s *LocalStorage) presign(method, key string, expires time.Duration, contentType string, size int64) (string, error) {
+	cleaned, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	expiresAt := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expiresAt)
+	sizeText := ""
+	if size > 0 {
+		sizeText = strconv.FormatInt(size, 10)
+		query.Set("size", sizeText)
+	}
+	query.Set("signature", s.sign(method, cleaned, expiresAt, contentType, sizeText))
+	return s.baseURL + LocalRoutePrefix + (&url.URL{Path: cleaned}).EscapedPath() + "?" + query.Encode(), nil
+}
+
+// sign 计算签名
+func (s *LocalStorage) sign(method, key, expires, contentType, size string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%s", method, key, expires, contentType, size)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ServeHTTP 处理签名地址的下载（GET）和上传（PUT）请求
+func (s *LocalStorage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key, err := CleanKey(strings.TrimPrefix(r.URL.Path, LocalRoutePrefix))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodPut {
+		http.Error(w, "不支持的请求方法", http.StatusMethodNotAllowed)
+		return
+	}
+	method, contentType := r.Method, ""
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	if method == http.MethodPut {
+		contentType = r.Header.Get("Content-Type")
+	}
+	expires, size := r.URL.Query().Get("expires"), r.URL.Query().Get("size")
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	signature := s.sign(method, key, expires, contentType, size)
+	if err != nil || time.Now().Unix() > expiresAt || !hmac.Equal([]byte(signature), []byte(r.URL.Query().Get("signature"))) {
+		http.Error(w, "签名无效或已过期", http.StatusForbidden)
+		return
+	}
+
+	if method == http.MethodPut {
+		limit := s.MaxPutBytes
+		if size != "" {
+			// 签名限定了内容长度，长度不符或未声明长度的请求直接拒绝
+			limit, _ = strconv.ParseInt(size, 10, 64)
+			if r.ContentLength != limit {
+				http.Error(w, "文件大小与签名不一致", http.StatusBadRequest)
+				return
+			}
+		}
+		if s.MaxPutBytes > 0 && (limit <= 0 || limit > s.MaxPutBytes) {
+			limit = s.MaxPutBytes
+		}
+		body := io.Reader(r.Body)
+		if limit > 0 {
+			if r.ContentLength > limit {
+				http.Error(w, "文件过大", http.StatusRequestEntityTooLarge)
+				return
+			}
+			body = http.MaxBytesReader(w, r.Body, limit)
+		}
+		if err := s.Put(r.Context(), key, body, r.ContentLength, contentType); err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				http.Error(w, "文件过大", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "保存文件失败", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	name, _ := s.filePath(key)
+	f, err := os.Open(name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil || fi.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeContent(w, r, path.Base(key), fi.ModTime(), f)
+}
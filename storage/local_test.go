@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLocalStorage(t *testing.T) *LocalStorage {
+	t.Helper()
+	s, err := NewLocalStorage(t.TempDir(), "http://localhost", "test-signing-key")
+	if err != nil {
+		t.Fatalf("创建本地存储失败: %v", err)
+	}
+	return s
+}
+
+func TestLocalStorageRoundTrip(t *testing.T) {
+	s := newTestLocalStorage(t)
+	ctx := context.Background()
+	key := "papers/ab/abcdef.pdf"
+
+	if err := s.Put(ctx, key, strings.NewReader("%PDF-1.4 content"), 16, "application/pdf"); err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+	rc, err := s.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("读取失败: %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "%PDF-1.4 content" {
+		t.Errorf("读取内容错误: %q", data)
+	}
+	info, err := s.Stat(ctx, key)
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	if info.Key != key || info.Size != 16 || info.ContentType != "application/pdf" {
+		t.Errorf("元信息错误: %+v", info)
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatalf("删除失败: %v", err)
+	}
+	if _, err := s.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("删除后读取应返回ErrNotFound，实际为%v", err)
+	}
+	if _, err := s.Stat(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("删除后查询应返回ErrNotFound，实际为%v", err)
+	}
+	if err := s.Delete(ctx, key); err != nil {
+		t.Errorf("删除不存在的对象不应报错: %v", err)
+	}
+	if _, err := s.Stat(ctx, "papers/ab"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("目录不是对象，应返回ErrNotFound，实际为%v", err)
+	}
+}
+
+func TestLocalStorageRejectsUnsafeKeys(t *testing.T) {
+	s := newTestLocalStorage(t)
+	ctx := context.Background()
+	for _, key := range []string{"../escape.pdf", "papers/../../escape.pdf", "/etc/passwd", ""} {
+		if err := s.Put(ctx, key, strings.NewReader("x"), 1, ""); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("%q: 写入应返回ErrInvalidKey，实际为%v", key, err)
+		}
+		if _, err := s.Get(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("%q: 读取应返回ErrInvalidKey，实际为%v", key, err)
+		}
+		if err := s.Delete(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("%q: 删除应返回ErrInvalidKey，实际为%v", key, err)
+		}
+		if _, err := s.PresignGet(ctx, key, time.Minute); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("%q: 签名应返回ErrInvalidKey，实际为%v", key, err)
+		}
+	}
+}
+
+// serveSigned 以签名地址的路径和参数请求LocalStorage.ServeHTTP
+func serveSigned(t *testing.T, s *LocalStorage, method, signedURL string, body io.Reader, contentType string) *httptest.ResponseRecorder {
+	t.Helper()
+	u, err := url.Parse(signedURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(method, u.RequestURI(), body)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestLocalStorageSignedURLs(t *testing.T) {
+	s := newTestLocalStorage(t)
+	ctx := context.Background()
+	key := "uploads/paper.pdf"
+
+	putURL, err := s.PresignPut(ctx, key, time.Minute, "application/pdf", 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec := serveSigned(t, s, http.MethodPut, putURL, strings.NewReader("%PDF-1.4"), "text/plain"); rec.Code != http.StatusForbidden {
+		t.Errorf("Content-Type与签名不符时应返回403，实际为%d", rec.Code)
+	}
+	if rec := serveSigned(t, s, http.MethodPut, putURL, strings.NewReader("%PDF-1.4 longer"), "application/pdf"); rec.Code != http.StatusBadRequest {
+		t.Errorf("长度与签名不符时应返回400，实际为%d", rec.Code)
+	}
+	if rec := serveSigned(t, s, http.MethodPut, putURL, strings.NewReader("%PDF-1.4"), "application/pdf"); rec.Code != http.StatusOK {
+		t.Fatalf("签名上传失败: %d %s", rec.Code, rec.Body.String())
+	}
+
+	getURL, err := s.PresignGet(ctx, key, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := serveSigned(t, s, http.MethodGet, getURL, nil, "")
+	if rec.Code != http.StatusOK || rec.Body.String() != "%PDF-1.4" {
+		t.Fatalf("签名下载失败: %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := serveSigned(t, s, http.MethodPut, getURL, strings.NewReader("%PDF-1.4"), ""); rec.Code != http.StatusForbidden {
+		t.Errorf("下载签名不能用于上传，实际为%d", rec.Code)
+	}
+
+	tampered := []func(url.Values, *url.URL){
+		func(q url.Values, u *url.URL) { q.Set("expires", "9999999999") },
+		func(q url.Values, u *url.URL) { q.Set("signature", strings.Repeat("0", 64)) },
+		func(q url.Values, u *url.URL) { q.Del("signature") },
+		func(q url.Values, u *url.URL) { u.Path = LocalRoutePrefix + "uploads/other.pdf" },
+	}
+	for i, tamper := range tampered {
+		u, _ := url.Parse(getURL)
+		q := u.Query()
+		tamper(q, u)
+		u.RawQuery = q.Encode()
+		if rec := serveSigned(t, s, http.MethodGet, u.String(), nil, ""); rec.Code != http.StatusForbidden {
+			t.Errorf("第%d种篡改应返回403，实际为%d", i+1, rec.Code)
+		}
+	}
+
+	expired, err := s.PresignGet(ctx, key, -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec := serveSigned(t, s, http.MethodGet, expired, nil, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("过期的签名应返回403，实际为%d", rec.Code)
+	}
+
+	other, err := NewLocalStorage(t.TempDir(), "http://localhost", "other-signing-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec := serveSigned(t, other, http.MethodGet, getURL, nil, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("其他密钥签名的地址应返回403，实际为%d", rec.Code)
+	}
+}
+
+func TestLocalStorageLimitsSignedUploads(t *testing.T) {
+	s := newTestLocalStorage(t)
+	s.MaxPutBytes = 4
+	putURL, err := s.PresignPut(context.Background(), "uploads/big.pdf", time.Minute, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec := serveSigned(t, s, http.MethodPut, putURL, strings.NewReader("%PDF-1.4"), ""); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("超过上传上限时应返回413，实际为%d", rec.Code)
+	}
+	if _, err := s.Stat(context.Background(), "uploads/big.pdf"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("超限的上传不应留下文件，实际为%v", err)
+	}
+}
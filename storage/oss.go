@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+// OSSStorage 阿里云OSS存储，客户端在创建时初始化并复用
+type OSSStorage struct {
+	bucket *oss.Bucket
+}
+
+// NewOSSStorage 创建阿里云OSS存储
+func NewOSSStorage(endpoint, accessKeyID, accessKeySecret, bucketName string) (*OSSStorage, error) {
+	if endpoint == "" || accessKeyID == "" || accessKeySecret == "" || bucketName == "" {
+		return nil, errors.New("OSS配置缺失")
+	}
+	client, err := oss.New(endpoint, accessKeyID, accessKeySecret)
+	if err != nil {
+		return nil, fmt.Errorf("OSS客户端初始化失败: %w", err)
+	}
+	bucket, err := client.Bucket(bucketName)
+	if err != nil {
+		return nil, fmt.Errorf("获取OSS bucket失败: %w", err)
+	}
+	return &OSSStorage{bucket: bucket}, nil
+}
+
+// Put 写入对象
+func (s *OSSStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	options := []oss.Option{oss.WithContext(ctx)}
+	if contentType != "" {
+		options = append(options, oss.ContentType(contentType))
+	}
+	if size >= 0 {
+		options = append(options, oss.ContentLength(size))
+	}
+	return s.bucket.PutObject(key, r, options...)
+}
+
+// Get 读取对象
+func (s *OSSStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	body, err := s.bucket.GetObject(key, oss.WithContext(ctx))
+	return body, ossError(err)
+}
+
+// Delete 删除对象
+func (s *OSSStorage) Delete(ctx context.Context, key string) error {
+	return s.bucket.DeleteObject(key, oss.WithContext(ctx))
+}
+
+// Stat 查询对象元信息
+func (s *OSSStorage) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	header, err := s.bucket.GetObjectDetailedMeta(key, oss.WithContext(ctx))
+	if err != nil {
+		return nil, ossError(err)
+	}
+	info := &ObjectInfo{Key: key, ContentType: header.Get("Content-Type")}
+	info.Size, _ = strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	info.ModTime, _ = http.ParseTime(header.Get("Last-Modified"))
+	return info, nil
+}
+
+// PresignGet 生成限时下载地址
+func (s *OSSStorage) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return s.bucket.SignURL(key, oss.HTTPGet, int64(expires.Seconds()))
+}
+
+// PresignPut 生成限时上传地址，OSS的签名不覆盖Content-Length，size不生效
+func (s *OSSStorage) PresignPut(ctx context.Context, key string, expires time.Duration, contentType string, size int64) (string, error) {
+	var options []oss.Option
+	if contentType != "" {
+		options = append(options, oss.ContentType(contentType))
+	}
+	return s.bucket.SignURL(key, oss.HTTPPut, int64(expires.Seconds()), options...)
+}
+
+// List 分页遍历对象
+func (s *OSSStorage) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	token := ""
+	for {
+		result, err := s.bucket.ListObjectsV2(oss.WithContext(ctx), oss.Prefix(prefix), oss.ContinuationToken(token), oss.MaxKeys(1000))
+		if err != nil {
+			return err
+		}
+		for _, obj := range result.Objects {
+			if err := fn(ObjectInfo{Key: obj.Key, Size: obj.Size, ModTime: obj.LastModified}); err != nil {
+				return err
+			}
+		}
+		if !result.IsTruncated {
+			return nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// ossError 将OSS的404错误转换为ErrNotFound
+func ossError(err error) error {
+	var serviceErr oss.ServiceError
+	if errors.As(err, &serviceErr) && serviceErr.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	return err
+}
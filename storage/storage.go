@@ -0,0 +1,113 @@
+// Package storage 对象存储抽象
+// 论文PDF等文件通过Storage接口读写，按配置使用阿里云OSS、本地文件系统或S3兼容存储
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// 存储驱动
+const (
+	DriverOSS   = "oss"
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+// 存储错误
+var (
+	ErrNotFound   = errors.New("对象不存在")
+	ErrInvalidKey = errors.New("对象路径不合法")
+)
+
+// ObjectInfo 对象元信息
+type ObjectInfo struct {
+	Key         string    `json:"key"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type"`
+	ModTime     time.Time `json:"mod_time"`
+}
+
+// Storage 对象存储
+// key为存储桶内的对象路径，如papers/1700000000_paper.pdf
+type Storage interface {
+	// Put 写入对象，size未知时传-1
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get 读取对象，调用方负责关闭；对象不存在时返回ErrNotFound
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除对象，对象不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+	// Stat 查询对象元信息，对象不存在时返回ErrNotFound
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	// PresignGet 生成限时下载地址
+	PresignGet(ctx context.Context, key string, expires time.Duration) (string, error)
+	// PresignPut 生成限时上传地址，客户端上传时需使用相同的Content-Type
+	// size大于0时要求上传内容的长度与之相同，OSS的签名地址不支持限制长度，需在上传后校验
+	PresignPut(ctx context.Context, key string, expires time.Duration, contentType string, size int64) (string, error)
+	// List 遍历路径以prefix开头的对象，fn返回错误时停止遍历并返回该错误
+	List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error
+}
+
+// Config 存储配置，Driver决定使用哪一组配置
+type Config struct {
+	Driver string
+
+	// 阿里云OSS
+	OSSEndpoint        string
+	OSSAccessKeyID     string
+	OSSAccessKeySecret string
+	OSSBucket          string
+
+	// 本地文件系统
+	LocalDir        string // 存储根目录
+	LocalBaseURL    string // 生成签名地址使用的服务地址，如http://localhost:8080
+	LocalSigningKey string // 签名密钥，为空时每次启动随机生成（重启后之前的签名地址失效）
+
+	// S3兼容存储（AWS S3、MinIO等）
+	S3Endpoint        string // 不含协议的服务地址，如s3.amazonaws.com、localhost:9000
+	S3Region          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3Bucket          string
+	S3UseSSL          bool
+}
+
+// New 按配置创建对象存储
+func New(cfg Config) (Storage, error) {
+	switch cfg.Driver {
+	case DriverOSS:
+		return NewOSSStorage(cfg.OSSEndpoint, cfg.OSSAccessKeyID, cfg.OSSAccessKeySecret, cfg.OSSBucket)
+	case DriverLocal:
+		return NewLocalStorage(cfg.LocalDir, cfg.LocalBaseURL, cfg.LocalSigningKey)
+	case DriverS3:
+		return NewS3Storage(cfg.S3Endpoint, cfg.S3Region, cfg.S3AccessKeyID, cfg.S3SecretAccessKey, cfg.S3Bucket, cfg.S3UseSSL)
+	}
+	return nil, fmt.Errorf("不支持的存储驱动: %s", cfg.Driver)
+}
+
+// ReadAll 读取整个对象
+func ReadAll(ctx context.Context, s Storage, key string) ([]byte, error) {
+	r, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// CleanKey 规范化对象路径，拒绝空路径、绝对路径和跳出根目录的路径
+func CleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") || strings.ContainsRune(key, 0) {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
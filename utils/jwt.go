@@ -6,8 +6,15 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
-// JWTSecret 建议用更安全的方式存储
-var JWTSecret = []byte("your_jwt_secret")
+// JWT 登录令牌和密码重置令牌的签发与校验
+type JWT struct {
+	secret []byte
+}
+
+// NewJWT 使用配置的签名密钥创建JWT
+func NewJWT(secret string) *JWT {
+	return &JWT{secret: []byte(secret)}
+}
 
 // Claims 自定义声明结构体
 type Claims struct {
@@ -26,7 +33,7 @@ type PasswordResetClaims struct {
 }
 
 // GenerateToken 生成JWT
-func GenerateToken(userID uint, email string) (string, error) {
+func (j *JWT) GenerateToken(userID uint, email string) (string, error) {
 	claims := Claims{
 		UserID: userID,
 		Email:  email,
@@ -36,11 +43,11 @@ func GenerateToken(userID uint, email string) (string, error) {
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(JWTSecret)
+	return token.SignedString(j.secret)
 }
 
 // GeneratePasswordResetToken 生成密码重置令牌
-func GeneratePasswordResetToken(userID uint, email string) (string, error) {
+func (j *JWT) GeneratePasswordResetToken(userID uint, email string) (string, error) {
 	claims := PasswordResetClaims{
 		UserID: userID,
 		Email:  email,
@@ -51,13 +58,13 @@ func GeneratePasswordResetToken(userID uint, email string) (string, error) {
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(JWTSecret)
+	return token.SignedString(j.secret)
 }
 
 // ParseToken 解析JWT
-func ParseToken(tokenString string) (*Claims, error) {
+func (j *JWT) ParseToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return JWTSecret, nil
+		return j.secret, nil
 	})
 	if err != nil {
 		return nil, err
@@ -69,9 +76,9 @@ func ParseToken(tokenString string) (*Claims, error) {
 }
 
 // ParsePasswordResetToken 解析密码重置令牌
-func ParsePasswordResetToken(tokenString string) (*PasswordResetClaims, error) {
+func (j *JWT) ParsePasswordResetToken(tokenString string) (*PasswordResetClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &PasswordResetClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return JWTSecret, nil
+		return j.secret, nil
 	})
 	if err != nil {
 		return nil, err
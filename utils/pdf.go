@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// PDF校验错误
+var (
+	ErrNotPDF       = errors.New("文件不是有效的PDF")
+	ErrPDFCorrupt   = errors.New("PDF文件已损坏")
+	ErrPDFEncrypted = errors.New("不支持加密的PDF")
+	ErrPDFNoPages   = errors.New("PDF没有页面")
+)
+
+// ContentHash 计算文件内容的SHA-256摘要（十六进制）
+func ContentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// HasPDFHeader 判断文件开头是否为PDF文件头，head为文件的前1024字节
+func HasPDFHeader(head []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(head[:min(len(head), 1024)], "\x00\t\r\n "), []byte("%PDF-"))
+}
+
+// openPDF 解析PDF结构，库内部解析异常时转换为错误返回
+func openPDF(data []byte) (*pdf.Reader, error) {
+	return openPDFReader(bytes.NewReader(data), int64(len(data)))
+}
+
+// openPDFReader 从随机读取的文件解析PDF结构，不需要把整个文件读入内存
+func openPDFReader(ra io.ReaderAt, size int64) (r *pdf.Reader, err error) {
+	head := make([]byte, min(size, 1024))
+	if n, err := ra.ReadAt(head, 0); err != nil && !(errors.Is(err, io.EOF) && n == len(head)) {
+		return nil, ErrNotPDF
+	}
+	if !HasPDFHeader(head) {
+		return nil, ErrNotPDF
+	}
+	defer func() {
+		if x := recover(); x != nil {
+			r, err = nil, fmt.Errorf("%w: %v", ErrPDFCorrupt, x)
+		}
+	}()
+	r, err = pdf.NewReader(ra, size)
+	if err != nil {
+		// 库只支持部分加密算法，不支持的算法（如AES-256）和参数错误同样视为加密文件
+		if errors.Is(err, pdf.ErrInvalidPassword) || strings.Contains(err.Error(), "encrypt") {
+			return nil, ErrPDFEncrypted
+		}
+		return nil, fmt.Errorf("%w: %v", ErrPDFCorrupt, err)
+	}
+	if !r.Trailer().Key("Encrypt").IsNull() {
+		return nil, ErrPDFEncrypted
+	}
+	return r, nil
+}
+
+// ValidatePDF 校验PDF结构是否完整，返回页数
+func ValidatePDF(data []byte) (pageCount int, err error) {
+	return ValidatePDFReader(bytes.NewReader(data), int64(len(data)))
+}
+
+// ValidatePDFReader 校验PDF结构是否完整，返回页数，文件按需随机读取
+func ValidatePDFReader(ra io.ReaderAt, size int64) (pageCount int, err error) {
+	r, err := openPDFReader(ra, size)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if x := recover(); x != nil {
+			pageCount, err = 0, fmt.Errorf("%w: %v", ErrPDFCorrupt, x)
+		}
+	}()
+	pageCount = r.NumPage()
+	if pageCount == 0 {
+		return 0, ErrPDFNoPages
+	}
+	return pageCount, nil
+}
+
+// ExtractPDFPages 按页提取PDF文本
+// 根据文字坐标还原空格和换行；单页解析失败时该页返回空字符串，不影响其他页
+// 逐字计算坐标较慢（每页约数百毫秒），应在后台任务中调用，ctx取消时停止提取
+func ExtractPDFPages(ctx context.Context, data []byte) ([]string, error) {
+	r, err := openPDF(data)
+	if err != nil {
+		return nil, err
+	}
+	n := r.NumPage()
+	if n == 0 {
+		return nil, ErrPDFNoPages
+	}
+	pages := make([]string, n)
+	for i := 1; i <= n; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		pages[i-1] = extractPageText(r.Page(i))
+	}
+	return pages, nil
+}
+
+// extractPageText 提取单页文本
+func extractPageText(p pdf.Page) (text string) {
+	defer func() {
+		if recover() != nil {
+			text = ""
+		}
+	}()
+	if p.V.IsNull() {
+		return ""
+	}
+
+	var b strings.Builder
+	var lastY, lastEnd float64
+	first := true
+	for _, t := range p.Content().Text {
+		if strings.TrimSpace(t.S) == "" {
+			continue
+		}
+		if !first {
+			// 纵向位移超过半个字号视为换行，横向间隔超过字号的15%视为空格
+			if math.Abs(t.Y-lastY) > t.FontSize*0.5 {
+				b.WriteByte('\n')
+			} else if t.X-lastEnd > t.FontSize*0.15 {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(strings.ToValidUTF8(t.S, ""))
+		lastY, lastEnd = t.Y, t.X+t.W
+		first = false
+	}
+	return strings.ReplaceAll(b.String(), "�", "")
+}
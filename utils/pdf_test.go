@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildTestPDF 生成最小PDF，pages为页面树对象，trailer为追加到trailer字典的内容
+func buildTestPDF(pages, trailer string) []byte {
+	content := "BT /F1 12 Tf 72 720 Td (Hello) Tj ET"
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		pages,
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+	}
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R %s>>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, trailer, xref)
+	return buf.Bytes()
+}
+
+// encryptTrailer 标准加密的trailer字段，密码不是空密码
+func encryptTrailer(v, r int) string {
+	entry := "<" + strings.Repeat("ab", 32) + ">"
+	return fmt.Sprintf("/Encrypt << /Filter /Standard /V %d /R %d /O %s /U %s /P -4 >> /ID [<0123456789abcdef> <0123456789abcdef>] ", v, r, entry, entry)
+}
+
+const onePage = "<< /Type /Pages /Kids [3 0 R] /Count 1 >>"
+
+func TestValidatePDF(t *testing.T) {
+	valid := buildTestPDF(onePage, "")
+	tests := []struct {
+		name    string
+		data    []byte
+		pages   int
+		wantErr error
+	}{
+		{"有效PDF", valid, 1, nil},
+		{"空文件", nil, 0, ErrNotPDF},
+		{"文件头错误", append([]byte("%PDX-1.4\n"), valid[9:]...), 0, ErrNotPDF},
+		{"HTML页面", []byte("<html><body>%PDF-1.4</body></html>"), 0, ErrNotPDF},
+		{"加密PDF", buildTestPDF(onePage, encryptTrailer(1, 2)), 0, ErrPDFEncrypted},
+		{"AES-256加密PDF", buildTestPDF(onePage, encryptTrailer(5, 6)), 0, ErrPDFEncrypted},
+		{"截断", valid[:len(valid)/2], 0, ErrPDFCorrupt},
+		{"缺少交叉引用表", bytes.Replace(valid, []byte("startxref"), []byte("startxrex"), 1), 0, ErrPDFCorrupt},
+		{"没有页面", buildTestPDF("<< /Type /Pages /Kids [] /Count 0 >>", ""), 0, ErrPDFNoPages},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages, err := ValidatePDF(tt.data)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("期望错误%v，实际为%v", tt.wantErr, err)
+			}
+			if pages != tt.pages {
+				t.Errorf("期望%d页，实际为%d", tt.pages, pages)
+			}
+		})
+	}
+}
+
+func TestHasPDFHeader(t *testing.T) {
+	tests := []struct {
+		head string
+		want bool
+	}{
+		{"%PDF-1.7", true},
+		{"\x00\x00\n%PDF-2.0", true},
+		{"PDF-1.4", false},
+		{"", false},
+		{string(bytes.Repeat([]byte(" "), 1024)) + "%PDF-1.4", false}, // 文件头只在前1024字节内查找
+	}
+	for _, tt := range tests {
+		if got := HasPDFHeader([]byte(tt.head)); got != tt.want {
+			t.Errorf("%q: 期望%v，实际为%v", tt.head, tt.want, got)
+		}
+	}
+}
@@ -34,6 +34,15 @@ func Error(c *gin.Context, msg string, code int) {
 	})
 }
 
+// ErrorWithData 返回带附加数据的错误响应
+func ErrorWithData(c *gin.Context, msg string, code int, data interface{}) {
+	c.JSON(http.StatusOK, Response{
+		Code:    code,
+		Message: msg,
+		Data:    data,
+	})
+}
+
 // FailWithMsg 失败响应，code=1
 func FailWithMsg(c *gin.Context, msg string) {
 	Error(c, msg, 1)
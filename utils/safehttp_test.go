@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr   string
+		public bool
+	}{
+		{"8.8.8.8", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.0.0.1", false},
+		{"172.16.5.4", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false}, // 云厂商元数据服务
+		{"fe80::1", false},
+		{"fd00::1", false}, // IPv6唯一本地地址
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"::ffff:8.8.8.8", true},
+		{"0.0.0.0", false},
+		{"100.64.0.1", false},
+		{"64:ff9b::a00:1", false}, // NAT64映射的10.0.0.1
+		{"224.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := IsPublicAddr(netip.MustParseAddr(tt.addr)); got != tt.public {
+			t.Errorf("%s: 期望%v，实际为%v", tt.addr, tt.public, got)
+		}
+	}
+}
+
+func TestSafeHTTPClientRefusesPrivateAddress(t *testing.T) {
+	var requested bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+	}))
+	defer srv.Close()
+
+	_, err := NewSafeHTTPClient(5*time.Second, 3).Get(srv.URL)
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("访问127.0.0.1应返回ErrPrivateAddress，实际为%v", err)
+	}
+	localhost := "http://localhost:" + mustURL(t, srv.URL).Port()
+	if _, err := NewSafeHTTPClient(5*time.Second, 3).Get(localhost); !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("解析到内网的域名应返回ErrPrivateAddress，实际为%v", err)
+	}
+	if requested {
+		t.Error("不应向内网地址发送请求")
+	}
+}
+
+func TestSafeHTTPClientChecksRedirectTarget(t *testing.T) {
+	var requested bool
+	private := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+	}))
+	defer private.Close()
+	// 可信地址代替公网站点，重定向到未受信任的127.0.0.1
+	public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, private.URL+"/secret", http.StatusFound)
+	}))
+	defer public.Close()
+
+	client := NewSafeHTTPClient(5*time.Second, 3, mustURL(t, public.URL).Host)
+	_, err := client.Get(public.URL)
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("重定向到内网地址应返回ErrPrivateAddress，实际为%v", err)
+	}
+	if requested {
+		t.Error("不应跟随重定向访问内网地址")
+	}
+}
+
+func TestSafeHTTPClientLimitsRedirects(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/loop":
+			http.Redirect(w, r, srv.URL+"/loop", http.StatusFound)
+		case "/file":
+			http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	client := NewSafeHTTPClient(5*time.Second, 3, mustURL(t, srv.URL).Host)
+	if _, err := client.Get(srv.URL + "/loop"); err == nil {
+		t.Error("重定向次数超限时应报错")
+	}
+	if _, err := client.Get(srv.URL + "/file"); err == nil {
+		t.Error("重定向到非http协议时应报错")
+	}
+}
+
+func mustURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}